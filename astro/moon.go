@@ -0,0 +1,58 @@
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// SynodicMonth is the mean time from one new moon to the next, 29.530589 days
+const SynodicMonth = 2551442877 * time.Millisecond
+
+// MoonPhase names the phases, each an eighth of the way round the moon's orbit
+type MoonPhase string
+
+const (
+	NewMoon        MoonPhase = "new_moon"
+	WaxingCrescent MoonPhase = "waxing_crescent"
+	FirstQuarter   MoonPhase = "first_quarter"
+	WaxingGibbous  MoonPhase = "waxing_gibbous"
+	FullMoon       MoonPhase = "full_moon"
+	WaningGibbous  MoonPhase = "waning_gibbous"
+	LastQuarter    MoonPhase = "last_quarter"
+	WaningCrescent MoonPhase = "waning_crescent"
+)
+
+var phases = []MoonPhase{NewMoon, WaxingCrescent, FirstQuarter, WaxingGibbous, FullMoon, WaningGibbous, LastQuarter, WaningCrescent}
+
+// Moon is the moon's phase at a time
+type Moon struct {
+	Phase MoonPhase
+	// Illumination is the fraction of the disc lit, 0 to 1
+	Illumination float64
+	// Elongation is how far the moon is east of the sun along the ecliptic in degrees, 0 new to 180 full
+	Elongation float64
+	// Age is the time since the last new moon, at the mean rate
+	Age time.Duration
+}
+
+// MoonAt works out the moon's phase at a time, from the main terms of Meeus' lunar longitude.
+// It's good to a few hours around each quarter.
+func MoonAt(t time.Time) Moon {
+	jc := (julianDay(t) - 2451545) / 36525
+	// mean longitude, elongation, the sun's and moon's anomalies, and argument of latitude
+	l := 218.3164477 + 481267.88123421*jc
+	d := rad(297.8501921 + 445267.1114034*jc)
+	m := rad(357.5291092 + 35999.0502909*jc)
+	mp := rad(134.9633964 + 477198.8675055*jc)
+	f := rad(93.2720950 + 483202.0175233*jc)
+	longitude := l + 6.288774*math.Sin(mp) + 1.274027*math.Sin(2*d-mp) + 0.658314*math.Sin(2*d) +
+		0.213618*math.Sin(2*mp) - 0.185116*math.Sin(m) - 0.114332*math.Sin(2*f)
+	elongation := math.Mod(longitude-sunAt(t).longitude+720, 360)
+	index := int(math.Floor((elongation+22.5)/45)) % len(phases)
+	return Moon{
+		Phase:        phases[index],
+		Illumination: (1 - math.Cos(rad(elongation))) / 2,
+		Elongation:   elongation,
+		Age:          time.Duration(elongation / 360 * float64(SynodicMonth)),
+	}
+}
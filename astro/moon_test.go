@@ -0,0 +1,41 @@
+package astro_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/astro"
+)
+
+func TestMoonAt(t *testing.T) {
+	// 2024's April lunation, from the almanac
+	tests := []struct {
+		name         string
+		at           time.Time
+		phase        astro.MoonPhase
+		illumination float64
+	}{
+		{"new", time.Date(2024, 4, 8, 18, 21, 0, 0, time.UTC), astro.NewMoon, 0},
+		{"waxing-crescent", time.Date(2024, 4, 12, 0, 0, 0, 0, time.UTC), astro.WaxingCrescent, 0.15},
+		{"first-quarter", time.Date(2024, 4, 15, 19, 13, 0, 0, time.UTC), astro.FirstQuarter, 0.5},
+		{"full", time.Date(2024, 4, 23, 23, 49, 0, 0, time.UTC), astro.FullMoon, 1},
+		{"last-quarter", time.Date(2024, 5, 1, 11, 27, 0, 0, time.UTC), astro.LastQuarter, 0.5},
+		{"waning-crescent", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC), astro.WaningCrescent, 0.12},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := astro.MoonAt(test.at)
+			if got.Phase != test.phase {
+				t.Errorf("expected '%v' got '%v'", test.phase, got.Phase)
+			}
+			if math.Abs(got.Illumination-test.illumination) > 0.05 {
+				t.Errorf("expected illumination '%v' got '%v'", test.illumination, got.Illumination)
+			}
+		})
+	}
+	full := astro.MoonAt(time.Date(2024, 4, 23, 23, 49, 0, 0, time.UTC))
+	if half := astro.SynodicMonth / 2; (full.Age - half).Abs() > 6*time.Hour {
+		t.Errorf("expected the full moon half a month old got '%v'", full.Age)
+	}
+}
@@ -0,0 +1,197 @@
+// Package astro works out the positions of the sun and moon, with the low precision formulas from
+// NOAA's solar calculator and Meeus' Astronomical Algorithms.  Times are good to about a minute,
+// away from the poles, and everything is computed locally.
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Altitudes of the sun's centre, in degrees, that events happen at.
+// Sunrise allows for refraction and the sun's radius.
+const (
+	AltitudeSunrise      = -0.833
+	AltitudeCivil        = -6
+	AltitudeNautical     = -12
+	AltitudeAstronomical = -18
+)
+
+// Twilight is when the sun passes an altitude in the morning and evening.
+// Either is zero when the sun doesn't cross that altitude on the day.
+type Twilight struct {
+	Dawn time.Time
+	Dusk time.Time
+}
+
+// Polar describes a day the sun doesn't rise or set
+type Polar string
+
+const (
+	PolarNone  Polar = ""
+	PolarDay   Polar = "day"
+	PolarNight Polar = "night"
+)
+
+// Day is the sun's events over one day at a place, in UTC
+type Day struct {
+	SolarNoon time.Time
+	// Sunrise and Sunset are zero during polar day and night
+	Sunrise      time.Time
+	Sunset       time.Time
+	Civil        Twilight
+	Nautical     Twilight
+	Astronomical Twilight
+	// Polar is set when the sun is up, or down, all day
+	Polar     Polar
+	DayLength time.Duration
+	// NoonElevation is the sun's elevation in degrees at solar noon
+	NoonElevation float64
+}
+
+// SunDay works out the sun's events for the solar day around t at a place.
+// The day is centred on the solar noon nearest t, rather than any time zone's midnight.
+func SunDay(lat float64, lon float64, t time.Time) Day {
+	mean := meanNoon(lon, t)
+	noon := mean
+	for i := 0; i < 2; i++ {
+		noon = mean.Add(-minutes(sunAt(noon).eqTime))
+	}
+	d := Day{
+		SolarNoon:     noon.Truncate(time.Second),
+		NoonElevation: SolarElevation(lat, lon, noon),
+	}
+	var up, down bool
+	d.Sunrise, d.Sunset, up, down = crossings(lat, mean, AltitudeSunrise)
+	switch {
+	case up:
+		d.Polar = PolarDay
+		d.DayLength = 24 * time.Hour
+	case down:
+		d.Polar = PolarNight
+	default:
+		d.DayLength = d.Sunset.Sub(d.Sunrise)
+	}
+	d.Civil.Dawn, d.Civil.Dusk, _, _ = crossings(lat, mean, AltitudeCivil)
+	d.Nautical.Dawn, d.Nautical.Dusk, _, _ = crossings(lat, mean, AltitudeNautical)
+	d.Astronomical.Dawn, d.Astronomical.Dusk, _, _ = crossings(lat, mean, AltitudeAstronomical)
+	return d
+}
+
+// SolarElevation is the angle of the sun's centre above the horizon in degrees, without refraction
+func SolarElevation(lat float64, lon float64, t time.Time) float64 {
+	s := sunAt(t)
+	utcMinutes := float64(t.UTC().Hour()*60+t.UTC().Minute()) + float64(t.UTC().Second())/60
+	trueSolar := math.Mod(utcMinutes+s.eqTime+4*lon, 1440)
+	hourAngle := trueSolar/4 - 180
+	phi := rad(lat)
+	cosZenith := math.Sin(phi)*math.Sin(s.declination) + math.Cos(phi)*math.Cos(s.declination)*math.Cos(rad(hourAngle))
+	return 90 - deg(math.Acos(clamp(cosZenith)))
+}
+
+// IsDay reports whether the sun is up, by the same altitude as sunrise and sunset
+func IsDay(lat float64, lon float64, t time.Time) bool {
+	return SolarElevation(lat, lon, t) > AltitudeSunrise
+}
+
+// meanNoon finds noon by local mean time nearest t, which differs from noon by the sun by the equation of time
+func meanNoon(lon float64, t time.Time) time.Time {
+	t = t.UTC()
+	offset := time.Duration(-lon * 4 * float64(time.Minute))
+	noon := time.Date(t.Year(), t.Month(), t.Day(), 12, 0, 0, 0, time.UTC).Add(offset)
+	if diff := t.Sub(noon); diff > 12*time.Hour {
+		noon = noon.Add(24 * time.Hour)
+	} else if diff <= -12*time.Hour {
+		noon = noon.Add(-24 * time.Hour)
+	}
+	return noon
+}
+
+// crossings finds when the sun passes altitude before and after noon.
+// up or down is set, and the times left zero, when it's above or below the altitude all day.
+func crossings(lat float64, mean time.Time, altitude float64) (rising time.Time, setting time.Time, up bool, down bool) {
+	rising, up, down = crossing(lat, mean, altitude, -1)
+	if up || down {
+		return time.Time{}, time.Time{}, up, down
+	}
+	setting, up, down = crossing(lat, mean, altitude, 1)
+	if up || down {
+		return time.Time{}, time.Time{}, up, down
+	}
+	return rising, setting, false, false
+}
+
+// crossing finds when the sun passes altitude on one side of noon, -1 for the morning and 1 the evening.
+// Each degree of hour angle is 4 minutes from noon; the sun's position is worked out again at the estimate,
+// as it moves over the day.
+func crossing(lat float64, mean time.Time, altitude float64, side float64) (time.Time, bool, bool) {
+	phi := rad(lat)
+	at := mean
+	for i := 0; i < 3; i++ {
+		s := sunAt(at)
+		cosH := (math.Sin(rad(altitude)) - math.Sin(phi)*math.Sin(s.declination)) / (math.Cos(phi) * math.Cos(s.declination))
+		switch {
+		case cosH < -1:
+			return time.Time{}, true, false
+		case cosH > 1:
+			return time.Time{}, false, true
+		}
+		at = mean.Add(minutes(side*4*deg(math.Acos(cosH)) - s.eqTime))
+	}
+	return at.Truncate(time.Second), false, false
+}
+
+// sun is the sun's position at a time
+type sun struct {
+	// declination in radians
+	declination float64
+	// eqTime is the equation of time in minutes, apparent less mean solar time
+	eqTime float64
+	// longitude is the apparent ecliptic longitude in degrees
+	longitude float64
+}
+
+// sunAt works out the sun's position, following NOAA's solar calculator
+func sunAt(t time.Time) sun {
+	jc := (julianDay(t) - 2451545) / 36525
+	meanLong := math.Mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+	meanAnom := 357.52911 + jc*(35999.05029-0.0001537*jc)
+	eccent := 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+	m := rad(meanAnom)
+	center := math.Sin(m)*(1.914602-jc*(0.004817+0.000014*jc)) + math.Sin(2*m)*(0.019993-0.000101*jc) + math.Sin(3*m)*0.000289
+	trueLong := meanLong + center
+	omega := rad(125.04 - 1934.136*jc)
+	appLong := trueLong - 0.00569 - 0.00478*math.Sin(omega)
+	meanObliq := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60
+	obliq := rad(meanObliq + 0.00256*math.Cos(omega))
+	y := math.Pow(math.Tan(obliq/2), 2)
+	l0 := rad(meanLong)
+	eqTime := 4 * deg(y*math.Sin(2*l0)-2*eccent*math.Sin(m)+4*eccent*y*math.Sin(m)*math.Cos(2*l0)-
+		0.5*y*y*math.Sin(4*l0)-1.25*eccent*eccent*math.Sin(2*m))
+	return sun{
+		declination: math.Asin(math.Sin(obliq) * math.Sin(rad(appLong))),
+		eqTime:      eqTime,
+		longitude:   math.Mod(appLong+360, 360),
+	}
+}
+
+func julianDay(t time.Time) float64 {
+	return float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5
+}
+
+func minutes(m float64) time.Duration {
+	return time.Duration(m * float64(time.Minute))
+}
+
+func rad(d float64) float64 {
+	return d * math.Pi / 180
+}
+
+func deg(r float64) float64 {
+	return r * 180 / math.Pi
+}
+
+// clamp keeps rounding from taking a cosine out of range
+func clamp(f float64) float64 {
+	return math.Max(-1, math.Min(1, f))
+}
@@ -0,0 +1,103 @@
+package astro_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/astro"
+)
+
+// within checks a time against an almanac's, which are given to the minute
+func within(t *testing.T, name string, expected time.Time, got time.Time) {
+	t.Helper()
+	if diff := got.Sub(expected).Abs(); diff > 2*time.Minute {
+		t.Errorf("expected %s '%v' got '%v'", name, expected, got)
+	}
+}
+
+func TestSunDay(t *testing.T) {
+	bst := time.FixedZone("BST", 60*60)
+	est := time.FixedZone("EST", -5*60*60)
+	aedt := time.FixedZone("AEDT", 11*60*60)
+	tests := []struct {
+		name      string
+		lat, lon  float64
+		at        time.Time
+		sunrise   time.Time
+		sunset    time.Time
+		civilDusk time.Time
+	}{
+		{
+			"greenwich-summer-solstice", 51.4769, -0.0005, time.Date(2024, 6, 21, 12, 0, 0, 0, bst),
+			time.Date(2024, 6, 21, 4, 43, 0, 0, bst), time.Date(2024, 6, 21, 21, 21, 0, 0, bst), time.Date(2024, 6, 21, 22, 8, 0, 0, bst),
+		},
+		{
+			"new-york-winter-solstice", 40.7128, -74.0060, time.Date(2024, 12, 21, 12, 0, 0, 0, est),
+			time.Date(2024, 12, 21, 7, 16, 0, 0, est), time.Date(2024, 12, 21, 16, 32, 0, 0, est), time.Date(2024, 12, 21, 17, 3, 0, 0, est),
+		},
+		{
+			"sydney-equinox", -33.8688, 151.2093, time.Date(2024, 3, 20, 12, 0, 0, 0, aedt),
+			time.Date(2024, 3, 20, 6, 58, 0, 0, aedt), time.Date(2024, 3, 20, 19, 6, 0, 0, aedt), time.Date(2024, 3, 20, 19, 31, 0, 0, aedt),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := astro.SunDay(test.lat, test.lon, test.at)
+			if got.Polar != astro.PolarNone {
+				t.Fatalf("expected the sun to rise and set got '%v'", got.Polar)
+			}
+			within(t, "sunrise", test.sunrise, got.Sunrise)
+			within(t, "sunset", test.sunset, got.Sunset)
+			within(t, "civil dusk", test.civilDusk, got.Civil.Dusk)
+			if got.DayLength != got.Sunset.Sub(got.Sunrise) {
+				t.Errorf("expected day length '%v' got '%v'", got.Sunset.Sub(got.Sunrise), got.DayLength)
+			}
+			if !(got.Nautical.Dawn.Before(got.Civil.Dawn) && got.Civil.Dawn.Before(got.Sunrise) && got.Sunrise.Before(got.SolarNoon)) {
+				t.Errorf("expected dawn to come in order got '%v'", got)
+			}
+		})
+	}
+}
+
+func TestSunDay_Polar(t *testing.T) {
+	// Tromsø
+	lat, lon := 69.6492, 18.9553
+	winter := astro.SunDay(lat, lon, time.Date(2024, 12, 21, 12, 0, 0, 0, time.UTC))
+	if winter.Polar != astro.PolarNight || !winter.Sunrise.IsZero() || winter.DayLength != 0 {
+		t.Errorf("expected polar night got '%v'", winter)
+	}
+	// there's still twilight at noon
+	if winter.Civil.Dawn.IsZero() || !winter.Civil.Dawn.Before(winter.SolarNoon) {
+		t.Errorf("expected civil twilight got '%v'", winter.Civil)
+	}
+	summer := astro.SunDay(lat, lon, time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC))
+	if summer.Polar != astro.PolarDay || !summer.Sunset.IsZero() || summer.DayLength != 24*time.Hour {
+		t.Errorf("expected the midnight sun got '%v'", summer)
+	}
+}
+
+func TestSolarElevation(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		at       time.Time
+		expected float64
+	}{
+		// 90 less the latitude, plus the declination at the solstice
+		{"greenwich-noon", 51.4769, 0, time.Date(2024, 6, 21, 12, 2, 0, 0, time.UTC), 61.96},
+		{"tropic-of-cancer-noon", 23.44, 0, time.Date(2024, 6, 21, 12, 2, 0, 0, time.UTC), 90},
+		{"greenwich-midnight", 51.4769, 0, time.Date(2024, 6, 21, 0, 2, 0, 0, time.UTC), -15.07},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := astro.SolarElevation(test.lat, test.lon, test.at)
+			if math.Abs(got-test.expected) > 0.2 {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+	if !astro.IsDay(51.4769, 0, time.Date(2024, 6, 21, 4, 0, 0, 0, time.UTC)) || astro.IsDay(51.4769, 0, time.Date(2024, 6, 21, 3, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected day to start at sunrise")
+	}
+}
@@ -0,0 +1,335 @@
+// Package capxml reads and writes Common Alerting Protocol 1.2 messages
+// (https://docs.oasis-open.org/emergency/cap/v1.2/CAP-v1.2.html).
+//
+// Only the XML form is supported.  Messages are validated against the required elements and value lists of the
+// specification, but not the full schema.
+package capxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/broganross/weather-exercise/geo"
+)
+
+const (
+	Namespace = "urn:oasis:names:tc:emergency:cap:1.2"
+	MediaType = "application/cap+xml"
+)
+
+// Values used in alerts
+const (
+	StatusActual  = "Actual"
+	MsgTypeAlert  = "Alert"
+	MsgTypeUpdate = "Update"
+	MsgTypeCancel = "Cancel"
+	ScopePublic   = "Public"
+	CategoryMet   = "Met"
+)
+
+var ErrInvalidAlert = errors.New("invalid cap alert")
+
+// Alert is a CAP message
+type Alert struct {
+	XMLName     xml.Name `xml:"urn:oasis:names:tc:emergency:cap:1.2 alert"`
+	Identifier  string   `xml:"identifier"`
+	Sender      string   `xml:"sender"`
+	Sent        Time     `xml:"sent"`
+	Status      string   `xml:"status"`
+	MsgType     string   `xml:"msgType"`
+	Source      string   `xml:"source,omitempty"`
+	Scope       string   `xml:"scope"`
+	Restriction string   `xml:"restriction,omitempty"`
+	Addresses   string   `xml:"addresses,omitempty"`
+	Codes       []string `xml:"code,omitempty"`
+	Note        string   `xml:"note,omitempty"`
+	// References are space separated "sender,identifier,sent" triples of earlier messages
+	References string `xml:"references,omitempty"`
+	Incidents  string `xml:"incidents,omitempty"`
+	Infos      []Info `xml:"info"`
+}
+
+// Info describes the event, there's one per language, or for different severities of it
+type Info struct {
+	Language      string       `xml:"language,omitempty"`
+	Categories    []string     `xml:"category"`
+	Event         string       `xml:"event"`
+	ResponseTypes []string     `xml:"responseType,omitempty"`
+	Urgency       string       `xml:"urgency"`
+	Severity      string       `xml:"severity"`
+	Certainty     string       `xml:"certainty"`
+	Audience      string       `xml:"audience,omitempty"`
+	EventCodes    []NamedValue `xml:"eventCode,omitempty"`
+	Effective     Time         `xml:"effective"`
+	Onset         Time         `xml:"onset"`
+	Expires       Time         `xml:"expires"`
+	SenderName    string       `xml:"senderName,omitempty"`
+	Headline      string       `xml:"headline,omitempty"`
+	Description   string       `xml:"description,omitempty"`
+	Instruction   string       `xml:"instruction,omitempty"`
+	Web           string       `xml:"web,omitempty"`
+	Contact       string       `xml:"contact,omitempty"`
+	Parameters    []NamedValue `xml:"parameter,omitempty"`
+	Resources     []Resource   `xml:"resource,omitempty"`
+	Areas         []Area       `xml:"area,omitempty"`
+}
+
+type NamedValue struct {
+	ValueName string `xml:"valueName"`
+	Value     string `xml:"value"`
+}
+
+// Resource is a file with more information, like an image or audio message
+type Resource struct {
+	ResourceDesc string `xml:"resourceDesc"`
+	MimeType     string `xml:"mimeType"`
+	Size         int64  `xml:"size,omitempty"`
+	URI          string `xml:"uri,omitempty"`
+	DerefURI     string `xml:"derefUri,omitempty"`
+	Digest       string `xml:"digest,omitempty"`
+}
+
+// Area is where the event is.  Polygons and circles use "latitude,longitude" pairs, latitude first.
+type Area struct {
+	AreaDesc string       `xml:"areaDesc"`
+	Polygons []string     `xml:"polygon,omitempty"`
+	Circles  []string     `xml:"circle,omitempty"`
+	Geocodes []NamedValue `xml:"geocode,omitempty"`
+	Altitude string       `xml:"altitude,omitempty"`
+	Ceiling  string       `xml:"ceiling,omitempty"`
+}
+
+// Time is a CAP date time, which has no fractional seconds and writes UTC as -00:00.
+// The zero time is left out.
+type Time struct {
+	time.Time
+}
+
+func (t Time) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if t.IsZero() {
+		return nil
+	}
+	s := t.Truncate(time.Second).Format("2006-01-02T15:04:05-07:00")
+	s = strings.TrimSuffix(s, "+00:00")
+	if len(s) == len("2006-01-02T15:04:05") {
+		s += "-00:00"
+	}
+	return e.EncodeElement(s, start)
+}
+
+func (t *Time) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var s string
+	if err := d.DecodeElement(&s, &start); err != nil {
+		return err
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("%w: %s time %q", ErrInvalidAlert, start.Name.Local, s)
+	}
+	t.Time = parsed
+	return nil
+}
+
+var (
+	statuses     = []string{StatusActual, "Exercise", "System", "Test", "Draft"}
+	msgTypes     = []string{MsgTypeAlert, MsgTypeUpdate, MsgTypeCancel, "Ack", "Error"}
+	scopes       = []string{ScopePublic, "Restricted", "Private"}
+	categories   = []string{"Geo", CategoryMet, "Safety", "Security", "Rescue", "Fire", "Health", "Env", "Transport", "Infra", "CBRNE", "Other"}
+	urgencies    = []string{"Immediate", "Expected", "Future", "Past", "Unknown"}
+	severities   = []string{"Extreme", "Severe", "Moderate", "Minor", "Unknown"}
+	certainties  = []string{"Observed", "Likely", "Possible", "Unlikely", "Unknown"}
+	responseList = []string{"Shelter", "Evacuate", "Prepare", "Execute", "Avoid", "Monitor", "Assess", "AllClear", "None"}
+)
+
+// Validate checks the required elements are there, and the values that come from lists are in them
+func (a *Alert) Validate() error {
+	errs := []error{}
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidAlert}, args...)...))
+		}
+	}
+	check(validID(a.Identifier), "identifier %q", a.Identifier)
+	check(validID(a.Sender), "sender %q", a.Sender)
+	check(!a.Sent.IsZero(), "missing sent")
+	check(oneOf(a.Status, statuses), "status %q", a.Status)
+	check(oneOf(a.MsgType, msgTypes), "msgType %q", a.MsgType)
+	check(oneOf(a.Scope, scopes), "scope %q", a.Scope)
+	check(a.Scope != "Restricted" || a.Restriction != "", "restricted scope without a restriction")
+	check(a.Scope != "Private" || a.Addresses != "", "private scope without addresses")
+	for i, info := range a.Infos {
+		check(len(info.Categories) > 0, "info %d missing category", i)
+		for _, c := range info.Categories {
+			check(oneOf(c, categories), "info %d category %q", i, c)
+		}
+		for _, r := range info.ResponseTypes {
+			check(oneOf(r, responseList), "info %d responseType %q", i, r)
+		}
+		check(info.Event != "", "info %d missing event", i)
+		check(oneOf(info.Urgency, urgencies), "info %d urgency %q", i, info.Urgency)
+		check(oneOf(info.Severity, severities), "info %d severity %q", i, info.Severity)
+		check(oneOf(info.Certainty, certainties), "info %d certainty %q", i, info.Certainty)
+		for j, area := range info.Areas {
+			check(area.AreaDesc != "", "info %d area %d missing areaDesc", i, j)
+			for _, p := range area.Polygons {
+				_, err := ParsePolygon(p)
+				check(err == nil, "info %d area %d polygon: %v", i, j, err)
+			}
+			for _, c := range area.Circles {
+				_, err := ParseCircle(c)
+				check(err == nil, "info %d area %d circle: %v", i, j, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validID is for identifiers and senders, which can't have spaces, commas or restricted characters
+func validID(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " ,<&\t\n")
+}
+
+func oneOf(s string, values []string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePolygon reads a CAP polygon, four or more space separated "latitude,longitude" pairs with the first and last the same
+func ParsePolygon(s string) (geo.Polygon, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("%w: polygon needs at least 4 points", ErrInvalidAlert)
+	}
+	ring := make([]geo.Point, len(fields))
+	for i, f := range fields {
+		p, err := parsePair(f)
+		if err != nil {
+			return nil, err
+		}
+		ring[i] = p
+	}
+	if ring[0] != ring[len(ring)-1] {
+		return nil, fmt.Errorf("%w: polygon isn't closed", ErrInvalidAlert)
+	}
+	return geo.Polygon{ring}, nil
+}
+
+// ParseCircle reads a CAP circle, a "latitude,longitude" center then a space and the radius in kilometres
+func ParseCircle(s string) (geo.Circle, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return geo.Circle{}, fmt.Errorf("%w: circle %q", ErrInvalidAlert, s)
+	}
+	center, err := parsePair(fields[0])
+	if err != nil {
+		return geo.Circle{}, err
+	}
+	radius, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || radius < 0 {
+		return geo.Circle{}, fmt.Errorf("%w: circle radius %q", ErrInvalidAlert, fields[1])
+	}
+	return geo.Circle{Center: center, RadiusKM: radius}, nil
+}
+
+func parsePair(s string) (geo.Point, error) {
+	lat, lon, ok := strings.Cut(s, ",")
+	if !ok {
+		return geo.Point{}, fmt.Errorf("%w: coordinate pair %q", ErrInvalidAlert, s)
+	}
+	p := geo.Point{}
+	var err1, err2 error
+	p.Lat, err1 = strconv.ParseFloat(lat, 64)
+	p.Lon, err2 = strconv.ParseFloat(lon, 64)
+	if err1 != nil || err2 != nil || !p.Valid() {
+		return geo.Point{}, fmt.Errorf("%w: coordinate pair %q", ErrInvalidAlert, s)
+	}
+	return p, nil
+}
+
+// FormatPolygon writes the outer ring of a polygon as a CAP polygon
+func FormatPolygon(pg geo.Polygon) string {
+	if len(pg) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(pg[0]))
+	for i, p := range pg[0] {
+		pairs[i] = formatPair(p)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// FormatCircle writes a circle as a CAP circle
+func FormatCircle(c geo.Circle) string {
+	return formatPair(c.Center) + " " + strconv.FormatFloat(c.RadiusKM, 'f', -1, 64)
+}
+
+func formatPair(p geo.Point) string {
+	return strconv.FormatFloat(p.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lon, 'f', -1, 64)
+}
+
+// Parse reads a single CAP alert, and validates it
+func Parse(r io.Reader) (*Alert, error) {
+	a := &Alert{}
+	if err := xml.NewDecoder(r).Decode(a); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAlert, err)
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ParseFeed reads every CAP alert in a document, which can be a lone alert or a feed, like Atom, with alerts embedded in it.
+// Alerts that don't validate are left out, and their errors returned along with the valid ones.
+func ParseFeed(b []byte) ([]*Alert, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	alerts := []*Alert{}
+	errs := []error{}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidAlert, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Space != Namespace || start.Name.Local != "alert" {
+			continue
+		}
+		a := &Alert{}
+		if err := dec.DecodeElement(a, &start); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidAlert, err))
+			continue
+		}
+		if err := a.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("alert %q: %w", a.Identifier, err))
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, errors.Join(errs...)
+}
+
+// Marshal writes an alert as an XML document
+func Marshal(a *Alert) ([]byte, error) {
+	b, err := xml.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding cap alert: %w", err)
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
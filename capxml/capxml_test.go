@@ -0,0 +1,203 @@
+package capxml_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/capxml"
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/thunderstorm.xml")
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	defer f.Close()
+	a, err := capxml.Parse(f)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if a.Identifier != "KSTO1055887203" || a.MsgType != capxml.MsgTypeAlert || len(a.Infos) != 1 {
+		t.Fatalf("expected the thunderstorm alert got '%v'", a)
+	}
+	pdt := time.FixedZone("PDT", -7*60*60)
+	if want := time.Date(2003, 6, 17, 14, 57, 0, 0, pdt); !a.Sent.Equal(want) {
+		t.Errorf("expected '%v' got '%v'", want, a.Sent)
+	}
+	info := a.Infos[0]
+	if info.Severity != "Severe" || info.Areas[0].Geocodes[0].Value != "006109" || info.ResponseTypes[0] != "Shelter" {
+		t.Errorf("expected the info to be read got '%v'", info)
+	}
+
+	// and back again
+	b, err := capxml.Marshal(a)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	again, err := capxml.Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v': %s", err, b)
+	}
+	if !again.Sent.Equal(a.Sent.Time) || again.Infos[0].Areas[0].Polygons[0] != info.Areas[0].Polygons[0] {
+		t.Errorf("expected the alert to survive a round trip got '%s'", b)
+	}
+	if bytes.Contains(b, []byte("<onset>")) {
+		t.Errorf("expected missing times to be left out got '%s'", b)
+	}
+}
+
+func TestAlert_Validate(t *testing.T) {
+	valid := func() *capxml.Alert {
+		return &capxml.Alert{
+			Identifier: "id-1",
+			Sender:     "sender@example.com",
+			Sent:       capxml.Time{Time: time.Now()},
+			Status:     "Actual",
+			MsgType:    "Alert",
+			Scope:      "Public",
+			Infos: []capxml.Info{{
+				Categories: []string{"Met"},
+				Event:      "Flood",
+				Urgency:    "Expected",
+				Severity:   "Moderate",
+				Certainty:  "Likely",
+				Areas:      []capxml.Area{{AreaDesc: "Somewhere", Circles: []string{"32.9525,-115.5527 10"}}},
+			}},
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(a *capxml.Alert)
+		valid  bool
+	}{
+		{"valid", func(a *capxml.Alert) {}, true},
+		{"no-infos", func(a *capxml.Alert) { a.Infos = nil }, true},
+		{"identifier-with-space", func(a *capxml.Alert) { a.Identifier = "id 1" }, false},
+		{"missing-sent", func(a *capxml.Alert) { a.Sent = capxml.Time{} }, false},
+		{"unknown-status", func(a *capxml.Alert) { a.Status = "actual" }, false},
+		{"restricted-without-restriction", func(a *capxml.Alert) { a.Scope = "Restricted" }, false},
+		{"missing-category", func(a *capxml.Alert) { a.Infos[0].Categories = nil }, false},
+		{"unknown-severity", func(a *capxml.Alert) { a.Infos[0].Severity = "Catastrophic" }, false},
+		{"missing-area-desc", func(a *capxml.Alert) { a.Infos[0].Areas[0].AreaDesc = "" }, false},
+		{"open-polygon", func(a *capxml.Alert) { a.Infos[0].Areas[0].Polygons = []string{"1,1 1,2 2,2 2,1"} }, false},
+		{"bad-circle", func(a *capxml.Alert) { a.Infos[0].Areas[0].Circles = []string{"32.9,-115.5"} }, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := valid()
+			test.modify(a)
+			err := a.Validate()
+			if test.valid && err != nil {
+				t.Errorf("got unexpected error: '%v'", err)
+			} else if !test.valid && !errors.Is(err, capxml.ErrInvalidAlert) {
+				t.Errorf("expected '%v' got '%v'", capxml.ErrInvalidAlert, err)
+			}
+		})
+	}
+}
+
+func TestParseFeed(t *testing.T) {
+	alert, _ := os.ReadFile("testdata/thunderstorm.xml")
+	body := strings.TrimPrefix(string(alert), `<?xml version="1.0" encoding="UTF-8"?>`)
+	invalid := strings.Replace(body, "<status>Actual</status>", "<status>Real</status>", 1)
+	feed := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Alerts</title>
+  <entry><title>one</title><content type="application/cap+xml">` + body + `</content></entry>
+  <entry><title>two</title><content type="application/cap+xml">` + invalid + `</content></entry>
+</feed>`
+	alerts, err := capxml.ParseFeed([]byte(feed))
+	if len(alerts) != 1 || alerts[0].Identifier != "KSTO1055887203" {
+		t.Errorf("expected the valid alert got '%v'", alerts)
+	}
+	if !errors.Is(err, capxml.ErrInvalidAlert) {
+		t.Errorf("expected '%v' got '%v'", capxml.ErrInvalidAlert, err)
+	}
+	if _, err := capxml.ParseFeed([]byte("<feed><entry>")); !errors.Is(err, capxml.ErrInvalidAlert) {
+		t.Errorf("expected '%v' got '%v'", capxml.ErrInvalidAlert, err)
+	}
+}
+
+func TestToDomain(t *testing.T) {
+	f, _ := os.Open("testdata/thunderstorm.xml")
+	defer f.Close()
+	a, err := capxml.Parse(f)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	translated := a.Infos[0]
+	translated.Language = "es-US"
+	translated.Event = "TORMENTA ELECTRICA SEVERA"
+	a.Infos = append(a.Infos, translated)
+
+	got := capxml.ToDomain(a, time.Now())
+	if len(got) != 1 {
+		t.Fatalf("expected translations to be skipped got '%v'", got)
+	}
+	alert := got[0]
+	if alert.ID != "KSTO1055887203" || alert.Severity != domain.SeveritySevere || alert.Certainty != domain.CertaintyObserved {
+		t.Errorf("expected the converted alert got '%v'", alert)
+	}
+	if !alert.Onset.Equal(a.Sent.Time) {
+		t.Errorf("expected onset to fall back to sent '%v' got '%v'", a.Sent, alert.Onset)
+	}
+	inside := geo.Point{Lon: -119.9, Lat: 38.45}
+	if len(alert.Areas) != 1 || !alert.Areas[0].Contains(inside) {
+		t.Errorf("expected the polygon to hold '%v' got '%v'", inside, alert.Areas)
+	}
+
+	a.MsgType = capxml.MsgTypeCancel
+	if got := capxml.ToDomain(a, time.Now()); len(got) != 0 {
+		t.Errorf("expected cancellations to convert to nothing got '%v'", got)
+	}
+}
+
+func TestFromDomain(t *testing.T) {
+	alerts := []domain.Alert{
+		{
+			ID:        "urn:oid:1",
+			Event:     "Tornado Warning",
+			Severity:  domain.SeverityExtreme,
+			Urgency:   domain.UrgencyImmediate,
+			Certainty: domain.CertaintyObserved,
+			Onset:     time.Date(2024, 5, 7, 3, 1, 0, 0, time.UTC),
+			AreaDesc:  "Oklahoma County",
+			Areas:     []geo.Area{geo.Circle{Center: geo.Point{Lon: -97.5, Lat: 35.5}, RadiusKM: 20}},
+			Source:    domain.Source{Provider: "nws"},
+		},
+		{ID: "abc", Event: "Heat Advisory", Severity: domain.SeverityMinor, Source: domain.Source{Provider: "openweather"}},
+	}
+	msg := capxml.FromDomain(alerts, geo.Point{Lon: -97.5, Lat: 35.5}, "weather-exercise", time.Now())
+	if err := msg.Validate(); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	b, err := capxml.Marshal(msg)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	for _, want := range []string{
+		`<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2">`,
+		"<severity>Extreme</severity>",
+		"<certainty>Unknown</certainty>",
+		"<onset>2024-05-07T03:01:00-00:00</onset>",
+		"<circle>35.5,-97.5 20</circle>",
+		"<value>urn:oid:1</value>",
+	} {
+		if !bytes.Contains(b, []byte(want)) {
+			t.Errorf("expected '%v' in '%s'", want, b)
+		}
+	}
+	again, err := capxml.Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if !again.Infos[0].Onset.Equal(alerts[0].Onset) {
+		t.Errorf("expected '%v' got '%v'", alerts[0].Onset, again.Infos[0].Onset)
+	}
+}
@@ -0,0 +1,171 @@
+package capxml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+)
+
+// Provider is the name ingested CAP alerts are attributed to
+const Provider = "cap"
+
+// Parameters added to the info of messages written by FromDomain
+const (
+	ParamProvider = "provider"
+	ParamAlertID  = "alert_id"
+)
+
+// ToDomain converts an actual Alert or Update message to domain alerts, one per info in its first language.
+// A message with more than one gets IDs like identifier#1 for the rest.  Anything else converts to nothing.
+func ToDomain(a *Alert, fetched time.Time) []domain.Alert {
+	if a.Status != StatusActual || (a.MsgType != MsgTypeAlert && a.MsgType != MsgTypeUpdate) {
+		return nil
+	}
+	alerts := []domain.Alert{}
+	for i, info := range a.Infos {
+		// other languages are translations of the first
+		if i > 0 && !strings.EqualFold(info.Language, a.Infos[0].Language) {
+			continue
+		}
+		id := a.Identifier
+		if n := len(alerts); n > 0 {
+			id = fmt.Sprintf("%s#%d", a.Identifier, n)
+		}
+		sender := info.SenderName
+		if sender == "" {
+			sender = a.Sender
+		}
+		onset := info.Onset.Time
+		if onset.IsZero() {
+			onset = info.Effective.Time
+		}
+		if onset.IsZero() {
+			onset = a.Sent.Time
+		}
+		descs := []string{}
+		areas := []geo.Area{}
+		for _, area := range info.Areas {
+			descs = append(descs, area.AreaDesc)
+			// the message was validated, so these parse
+			for _, p := range area.Polygons {
+				if pg, err := ParsePolygon(p); err == nil {
+					areas = append(areas, pg)
+				}
+			}
+			for _, c := range area.Circles {
+				if circle, err := ParseCircle(c); err == nil {
+					areas = append(areas, circle)
+				}
+			}
+		}
+		alerts = append(alerts, domain.Alert{
+			ID:          id,
+			Event:       info.Event,
+			Headline:    info.Headline,
+			Description: strings.TrimSpace(info.Description),
+			Instruction: strings.TrimSpace(info.Instruction),
+			Sender:      sender,
+			Severity:    domain.ParseSeverity(info.Severity),
+			Urgency:     domain.ParseUrgency(info.Urgency),
+			Certainty:   domain.ParseCertainty(info.Certainty),
+			Onset:       onset.UTC(),
+			Expires:     utcOrZero(info.Expires.Time),
+			AreaDesc:    strings.Join(descs, "; "),
+			Areas:       areas,
+			Source: domain.Source{
+				Provider:  Provider,
+				FetchedAt: fetched,
+			},
+		})
+	}
+	return alerts
+}
+
+// Referenced returns the identifiers of the earlier messages an alert references
+func Referenced(a *Alert) []string {
+	ids := []string{}
+	for _, ref := range strings.Fields(a.References) {
+		parts := strings.Split(ref, ",")
+		if len(parts) == 3 {
+			ids = append(ids, parts[1])
+		}
+	}
+	return ids
+}
+
+// FromDomain writes the alerts at a point as one message from sender, with an info per alert.
+// CAP has no collections, but infos can differ in everything but language, so this stays a valid single message.
+// Each info has provider and alert_id parameters saying where it came from.
+func FromDomain(alerts []domain.Alert, at geo.Point, sender string, sent time.Time) *Alert {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%d", sender, formatPair(at), sent.Unix())
+	for _, a := range alerts {
+		fmt.Fprintf(h, "|%s:%s", a.Source.Provider, a.ID)
+	}
+	msg := &Alert{
+		Identifier: "alerts-" + hex.EncodeToString(h.Sum(nil)[:12]),
+		Sender:     sender,
+		Sent:       Time{sent.UTC()},
+		Status:     StatusActual,
+		MsgType:    MsgTypeAlert,
+		Scope:      ScopePublic,
+		Note:       fmt.Sprintf("Active alerts at %s", formatPair(at)),
+		Infos:      make([]Info, len(alerts)),
+	}
+	for i, a := range alerts {
+		info := Info{
+			Categories:  []string{CategoryMet},
+			Event:       a.Event,
+			Urgency:     capValue(string(a.Urgency)),
+			Severity:    capValue(string(a.Severity)),
+			Certainty:   capValue(string(a.Certainty)),
+			Onset:       Time{a.Onset},
+			Expires:     Time{a.Expires},
+			SenderName:  a.Sender,
+			Headline:    a.Headline,
+			Description: a.Description,
+			Instruction: a.Instruction,
+			Parameters: []NamedValue{
+				{ValueName: ParamProvider, Value: a.Source.Provider},
+				{ValueName: ParamAlertID, Value: a.ID},
+			},
+		}
+		if a.AreaDesc != "" || len(a.Areas) > 0 {
+			area := Area{AreaDesc: a.AreaDesc}
+			if area.AreaDesc == "" {
+				area.AreaDesc = "Unspecified"
+			}
+			for _, shape := range a.Areas {
+				switch s := shape.(type) {
+				case geo.Polygon:
+					area.Polygons = append(area.Polygons, FormatPolygon(s))
+				case geo.Circle:
+					area.Circles = append(area.Circles, FormatCircle(s))
+				}
+			}
+			info.Areas = []Area{area}
+		}
+		msg.Infos[i] = info
+	}
+	return msg
+}
+
+// capValue capitalizes a domain value for CAP, empty values are Unknown
+func capValue(s string) string {
+	if s == "" {
+		return "Unknown"
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func utcOrZero(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.UTC()
+}
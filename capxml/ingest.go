@@ -0,0 +1,151 @@
+package capxml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/rs/zerolog/log"
+)
+
+// maxFeedBytes limits how much of a feed is read
+const maxFeedBytes = 10 << 20
+
+// Ingester loads CAP alerts from feeds into an alert store.
+// Each load replaces what the feed contributed last time, so alerts that drop out of a feed are removed,
+// and Cancel and Update messages remove the alerts they reference.
+type Ingester struct {
+	// Sources are http(s) URLs or local file paths, of lone alerts or feeds with alerts embedded
+	Sources  []string
+	Client   *http.Client
+	Store    domain.AlertStore
+	Interval time.Duration
+
+	mu   sync.Mutex
+	seen map[string][]string
+}
+
+// Run loads the sources every Interval until the context is cancelled
+func (in *Ingester) Run(ctx context.Context) {
+	ticker := time.NewTicker(in.Interval)
+	defer ticker.Stop()
+	for {
+		if err := in.Load(ctx); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("ingesting cap alerts")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Load reads every source once.  A source that fails keeps its alerts from the last load.
+func (in *Ingester) Load(ctx context.Context) error {
+	errs := []error{}
+	for _, source := range in.Sources {
+		if err := in.load(ctx, source); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (in *Ingester) load(ctx context.Context, source string) error {
+	b, err := in.read(ctx, source)
+	if err != nil {
+		return err
+	}
+	// invalid alerts are skipped, the rest of the feed is still good
+	messages, parseErr := ParseFeed(b)
+	if messages == nil {
+		return parseErr
+	}
+	if parseErr != nil {
+		log.Ctx(ctx).Warn().Err(parseErr).Str("source", source).Msg("skipping invalid cap alerts")
+	}
+	fetched := time.Now().UTC()
+	alerts := []domain.Alert{}
+	removed := []string{}
+	for _, m := range messages {
+		if m.Status != StatusActual {
+			continue
+		}
+		switch m.MsgType {
+		case MsgTypeUpdate, MsgTypeCancel:
+			removed = append(removed, Referenced(m)...)
+		}
+		alerts = append(alerts, ToDomain(m, fetched)...)
+	}
+	// feeds can carry an alert along with the update or cancel that replaces it, which mustn't bring it back
+	referenced := map[string]bool{}
+	for _, id := range removed {
+		referenced[id] = true
+	}
+	alerts = slices.DeleteFunc(alerts, func(a domain.Alert) bool {
+		id, _, _ := strings.Cut(a.ID, "#")
+		return referenced[id]
+	})
+	ids := make([]string, len(alerts))
+	current := map[string]bool{}
+	for i, a := range alerts {
+		ids[i] = a.ID
+		current[a.ID] = true
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.seen == nil {
+		in.seen = map[string][]string{}
+	}
+	for _, id := range in.seen[source] {
+		if !current[id] {
+			removed = append(removed, id)
+		}
+	}
+	if len(removed) > 0 {
+		if err := in.Store.Remove(ctx, removed...); err != nil {
+			return fmt.Errorf("removing alerts: %w", err)
+		}
+	}
+	if err := in.Store.Put(ctx, alerts...); err != nil {
+		return fmt.Errorf("storing alerts: %w", err)
+	}
+	in.seen[source] = ids
+	return nil
+}
+
+// read gets a source over http(s), or from the file system
+func (in *Ingester) read(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(strings.TrimPrefix(source, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("opening cap feed: %w", err)
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxFeedBytes))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating cap feed request: %w", err)
+	}
+	req.Header.Set("Accept", MediaType+", application/atom+xml, application/xml;q=0.9")
+	resp, err := in.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting cap feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("requesting cap feed: unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+}
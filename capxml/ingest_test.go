@@ -0,0 +1,129 @@
+package capxml_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/capxml"
+	"github.com/broganross/weather-exercise/store"
+)
+
+// capMessage builds a message covering a circle around 35.5,-97.5, expiring in an hour
+func capMessage(id string, msgType string, references string) string {
+	sent := time.Now().UTC().Format(time.RFC3339)
+	expires := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	return fmt.Sprintf(`<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2">
+  <identifier>%s</identifier>
+  <sender>w-nws.webmaster@noaa.gov</sender>
+  <sent>%s</sent>
+  <status>Actual</status>
+  <msgType>%s</msgType>
+  <scope>Public</scope>
+  <references>%s</references>
+  <info>
+    <category>Met</category>
+    <event>Tornado Warning</event>
+    <urgency>Immediate</urgency>
+    <severity>Extreme</severity>
+    <certainty>Observed</certainty>
+    <expires>%s</expires>
+    <area>
+      <areaDesc>Oklahoma County</areaDesc>
+      <circle>35.5,-97.5 20</circle>
+    </area>
+  </info>
+</alert>`, id, sent, msgType, references, expires)
+}
+
+func feed(messages ...string) string {
+	return `<feed xmlns="http://www.w3.org/2005/Atom"><entry><content>` +
+		strings.Join(messages, "</content></entry><entry><content>") +
+		`</content></entry></feed>`
+}
+
+func TestIngester_Load(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "alerts.xml")
+	served := feed(capMessage("remote-1", "Alert", ""))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write([]byte(served))
+	}))
+	defer srv.Close()
+	alerts := store.NewAlerts()
+	in := &capxml.Ingester{
+		Sources: []string{path, srv.URL},
+		Client:  srv.Client(),
+		Store:   alerts,
+	}
+	ids := func() []string {
+		found, _ := alerts.GetAlertsByCoords(ctx, 35.5, -97.5)
+		ids := []string{}
+		for _, a := range found {
+			ids = append(ids, a.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"first", feed(capMessage("a", "Alert", ""), capMessage("b", "Alert", "")), "[a b remote-1]"},
+		{"dropped-out", feed(capMessage("a", "Alert", "")), "[a remote-1]"},
+		{"updated", capMessage("a2", "Update", "w-nws.webmaster@noaa.gov,a,2024-05-07T03:01:00-00:00"), "[a2 remote-1]"},
+		{
+			"cancelled",
+			feed(capMessage("c", "Cancel", "w-nws.webmaster@noaa.gov,a2,2024-05-07T03:01:00-00:00")),
+			"[remote-1]",
+		},
+		{
+			"original-and-cancel",
+			feed(capMessage("d", "Alert", ""), capMessage("d-cancel", "Cancel", "w-nws.webmaster@noaa.gov,d,2024-05-07T03:01:00-00:00")),
+			"[remote-1]",
+		},
+		{
+			"original-and-update",
+			feed(capMessage("e", "Alert", ""), capMessage("e2", "Update", "w-nws.webmaster@noaa.gov,e,2024-05-07T03:01:00-00:00")),
+			"[e2 remote-1]",
+		},
+		{
+			"reloaded",
+			feed(capMessage("e", "Alert", ""), capMessage("e2", "Update", "w-nws.webmaster@noaa.gov,e,2024-05-07T03:01:00-00:00")),
+			"[e2 remote-1]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := os.WriteFile(path, []byte(test.file), 0o600); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if err := in.Load(ctx); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if got := fmt.Sprint(ids()); got != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+
+	// a source that fails keeps its alerts
+	os.Remove(path)
+	if err := in.Load(ctx); err == nil {
+		t.Errorf("expected an error for the missing file")
+	}
+	if got := fmt.Sprint(ids()); got != "[e2 remote-1]" {
+		t.Errorf("expected '[e2 remote-1]' got '%v'", got)
+	}
+	if found, _ := alerts.GetAlertsByCoords(ctx, 40, -97.5); len(found) != 0 {
+		t.Errorf("expected nothing outside the area got '%v'", found)
+	}
+}
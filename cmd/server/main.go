@@ -6,11 +6,19 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"time"
 
+	"github.com/broganross/weather-exercise/capxml"
 	"github.com/broganross/weather-exercise/config"
 	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/metrics"
 	"github.com/broganross/weather-exercise/repo"
 	"github.com/broganross/weather-exercise/server"
+	"github.com/broganross/weather-exercise/store"
+	"github.com/broganross/weather-exercise/tracing"
+	"github.com/broganross/weather-exercise/webhook"
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/rs/zerolog"
@@ -26,20 +34,214 @@ func main() {
 		log.Err(err).Msg("parsing environment variables")
 		os.Exit(1)
 	}
+	if err := conf.Validate(); err != nil {
+		log.Err(err).Msg("validating configuration")
+		os.Exit(1)
+	}
 	zerolog.SetGlobalLevel(conf.LogLevel)
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing, Version)
+	if err != nil {
+		log.Err(err).Msg("setting up tracing")
+		os.Exit(1)
+	}
 
 	// construct services
 	openWeather := &repo.OpenWeather{
-		BaseURL: conf.OpenWeather.BaseURL,
-		Client:  &http.Client{},
+		BaseURL:    conf.OpenWeather.BaseURL,
+		OneCallURL: conf.OpenWeather.OneCallURL,
+		Client: &http.Client{
+			Transport: &repo.RequestIDTransport{
+				Next: &repo.LoggingTransport{
+					SampleRate: conf.AccessLog.SampleRate,
+				},
+			},
+		},
 		APIid:   conf.OpenWeather.APIID,
 		Timeout: conf.OpenWeather.Timeout,
 	}
+	var source domain.Repo = &metrics.Repo{
+		Next:     openWeather,
+		Provider: repo.Provider,
+	}
+	var history *domain.HistoryService
+	closeArchive := func() error { return nil }
+	if conf.Archive.Enabled || conf.OpenWeather.History {
+		history = &domain.HistoryService{
+			GridSize: conf.Batch.GridSize,
+			Window:   conf.Archive.Window,
+		}
+	}
+	if conf.Archive.Enabled {
+		archive, err := store.OpenArchive(context.Background(), conf.Archive.SQLitePath)
+		if err != nil {
+			log.Err(err).Msg("opening observation archive")
+			os.Exit(1)
+		}
+		closeArchive = archive.Close
+		history.Archive = archive
+		source = &domain.ArchivingRepo{
+			Next:    source,
+			Archive: archive,
+		}
+	}
+	if conf.OpenWeather.History {
+		history.Source = &metrics.HistorySource{
+			Next:     openWeather,
+			Provider: repo.Provider,
+		}
+	}
 	domainService := &domain.WeatherService{
-		Source: openWeather,
+		Source:       source,
+		SourceAlerts: conf.OpenWeather.Alerts,
+	}
+	if conf.OpenWeather.UV {
+		domainService.UV = &metrics.UVSource{
+			Next:     openWeather,
+			Provider: repo.Provider,
+		}
+	}
+	airQuality := &domain.AirQualityService{
+		Source: &metrics.AirQualitySource{
+			Next:     openWeather,
+			Provider: repo.Provider,
+		},
+	}
+	if conf.NWS.Enabled {
+		domainService.AlertSources = append(domainService.AlertSources, &metrics.AlertSource{
+			Next: &repo.NWS{
+				BaseURL: conf.NWS.BaseURL,
+				Client: &http.Client{
+					Transport: &repo.RequestIDTransport{
+						Next: &repo.LoggingTransport{
+							SampleRate: conf.AccessLog.SampleRate,
+						},
+					},
+				},
+				UserAgent: conf.NWS.UserAgent,
+				Timeout:   conf.NWS.Timeout,
+			},
+			Provider: repo.NWSProvider,
+		})
+	}
+	var ingester *capxml.Ingester
+	if len(conf.CAP.Feeds) > 0 {
+		alertStore := store.NewAlerts()
+		domainService.AlertSources = append(domainService.AlertSources, alertStore)
+		ingester = &capxml.Ingester{
+			Sources:  conf.CAP.Feeds,
+			Client:   &http.Client{Timeout: conf.CAP.Timeout},
+			Store:    alertStore,
+			Interval: conf.CAP.Interval,
+		}
+	}
+	health := &server.Health{
+		Timeout: conf.Health.CheckTimeout,
+		Checks: map[string]server.Checker{
+			"openweather": &server.URLCheck{
+				URL:    conf.OpenWeather.BaseURL,
+				Client: openWeather.Client,
+			},
+			"auth": &server.URLCheck{
+				URL:    conf.AuthService.URL,
+				Client: &http.Client{},
+			},
+		},
+	}
+	instrumented := &metrics.Service{
+		Next: domainService,
+	}
+	batch := &domain.Batch{
+		Service:     instrumented,
+		MaxItems:    conf.Batch.MaxItems,
+		Concurrency: conf.Batch.Concurrency,
+		GridSize:    conf.Batch.GridSize,
+	}
+	poller := &domain.Poller{
+		Service:  instrumented,
+		Interval: conf.Stream.PollInterval,
+		GridSize: conf.Batch.GridSize,
+		Buffer:   conf.Stream.Buffer,
+		History:  conf.Stream.History,
+	}
+	var webhooks domain.WebhookStore
+	closeWebhooks := func() error { return nil }
+	switch conf.Webhook.Store {
+	case "memory":
+		webhooks = store.NewMemory()
+	case "sqlite":
+		db, err := store.OpenSQLite(context.Background(), conf.Webhook.SQLitePath)
+		if err != nil {
+			log.Err(err).Msg("opening webhook store")
+			os.Exit(1)
+		}
+		webhooks = db
+		closeWebhooks = db.Close
+	default:
+		log.Error().Str("store", conf.Webhook.Store).Msg("unknown webhook store")
+		os.Exit(1)
+	}
+	evaluator := &domain.WebhookEvaluator{
+		Service: instrumented,
+		Store:   webhooks,
+		Sender: &webhook.Sender{
+			Client: webhook.NewClient(conf.Webhook.Timeout),
+		},
+		Interval:    conf.Webhook.Interval,
+		GridSize:    conf.Batch.GridSize,
+		MaxAttempts: conf.Webhook.MaxAttempts,
+		Backoff:     conf.Webhook.Backoff,
+		NewID:       uuid.NewString,
+	}
+	// background work logs with the global logger, and stops on shutdown
+	workCtx, stopWork := context.WithCancel(log.Logger.WithContext(context.Background()))
+	workers := sync.WaitGroup{}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		evaluator.Run(workCtx)
+	}()
+	if ingester != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			ingester.Run(workCtx)
+		}()
 	}
 	handlers := server.Handlers{
-		Domain: domainService,
+		Domain:     instrumented,
+		AirQuality: airQuality,
+		CAPSender:  conf.CAP.Sender,
+		Batch:      batch,
+		Area: &domain.AreaService{
+			Batch:      batch,
+			MaxSamples: conf.Area.MaxSamples,
+		},
+		Route: &domain.RouteService{
+			Current:       instrumented,
+			Forecast:      &metrics.Forecaster{Next: domainService},
+			Spacing:       conf.Route.Spacing,
+			MaxSamples:    conf.Route.MaxSamples,
+			Concurrency:   conf.Batch.Concurrency,
+			GridSize:      conf.Batch.GridSize,
+			CurrentWindow: conf.Route.CurrentWindow,
+		},
+		Streams: &server.Streams{
+			Poller:           poller,
+			Heartbeat:        conf.Stream.Heartbeat,
+			MaxSubscriptions: conf.Stream.MaxSubscriptions,
+			SendBuffer:       conf.Stream.SendBuffer,
+			WriteTimeout:     conf.Stream.WriteTimeout,
+		},
+		Webhooks: webhooks,
+		Health:   health,
+	}
+	// alerts are only served when there's somewhere to get them from
+	if domainService.SourceAlerts || len(domainService.AlertSources) > 0 {
+		handlers.Alerts = &metrics.Alerter{Next: domainService}
+	}
+	// a nil *domain.HistoryService would still be a non-nil Historian
+	if history != nil {
+		handlers.History = history
 	}
 	router := mux.NewRouter()
 	server.SetupRoutes(&handlers, router, &conf)
@@ -51,6 +253,8 @@ func main() {
 		IdleTimeout:  conf.IdleTimeout,
 		Handler:      router,
 	}
+	// streams only end when their subscriptions do
+	srv.RegisterOnShutdown(poller.Close)
 	go func() {
 		if err := srv.ListenAndServe(); err != nil {
 			log.Err(err).Msg("serving")
@@ -62,9 +266,26 @@ func main() {
 	signal.Notify(channel, os.Interrupt)
 	<-channel
 
+	// fail readiness first, so load balancers stop sending traffic before we stop accepting it
+	health.Drain()
+	log.Info().Dur("delay", conf.Health.DrainDelay).Msg("draining")
+	time.Sleep(conf.Health.DrainDelay)
+
 	ctx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTime)
 	defer cancel()
 	srv.Shutdown(ctx)
+	// deliveries in flight are recorded as dead letters
+	stopWork()
+	workers.Wait()
+	if err := closeWebhooks(); err != nil {
+		log.Err(err).Msg("closing webhook store")
+	}
+	if err := closeArchive(); err != nil {
+		log.Err(err).Msg("closing observation archive")
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Err(err).Msg("flushing traces")
+	}
 	log.Info().Msg("shutting down")
 	os.Exit(0)
 }
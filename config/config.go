@@ -1,21 +1,118 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/rs/zerolog"
 )
 
+var ErrInvalidInterval = errors.New("interval must be positive")
+
 type OpenWeather struct {
-	APIID   string        `required:"true"`
-	BaseURL string        `required:"true"`
+	APIID   string `required:"true"`
+	BaseURL string `required:"true"`
+	// One Call is versioned separately from the other endpoints
+	OneCallURL string `default:"https://api.openweathermap.org/data/3.0/onecall"`
+	// Alerts come from One Call, which is a separate subscription, so they're only looked up if enabled
+	Alerts bool `default:"false"`
+	// UV indexes come from One Call too, so they're only looked up if enabled
+	UV bool `default:"false"`
+	// Past weather comes from One Call's time machine, so it's only looked up if enabled
+	History bool          `default:"false"`
 	Timeout time.Duration `default:"5s"`
 }
 
+type NWS struct {
+	// Alerts from the US National Weather Service are only looked up if enabled
+	Enabled bool   `default:"false"`
+	BaseURL string `default:"https://api.weather.gov"`
+	// The NWS asks for a user agent with contact details
+	UserAgent string        `default:"weather-exercise"`
+	Timeout   time.Duration `default:"5s"`
+}
+
 type AuthService struct {
 	URL string `default:"http://some.auth.com"`
 }
 
+type AccessLog struct {
+	SampleRate float64 `default:"1"`
+}
+
+type Tracing struct {
+	Enabled     bool    `default:"false"`
+	Endpoint    string  `default:"localhost:4318"`
+	Insecure    bool    `default:"false"`
+	SampleRatio float64 `default:"1"`
+	ServiceName string  `default:"weather-exercise"`
+}
+
+type Health struct {
+	CheckTimeout time.Duration `default:"2s"`
+	DrainDelay   time.Duration `default:"5s"`
+}
+
+type Batch struct {
+	MaxItems    int     `default:"500"`
+	Concurrency int     `default:"8"`
+	GridSize    float32 `default:"0.01"`
+}
+
+type Area struct {
+	MaxSamples int `default:"25"`
+}
+
+type Route struct {
+	// Distance between samples in kilometres
+	Spacing       float64       `default:"25"`
+	MaxSamples    int           `default:"40"`
+	CurrentWindow time.Duration `default:"1h"`
+}
+
+type Stream struct {
+	PollInterval time.Duration `default:"30s"`
+	Heartbeat    time.Duration `default:"15s"`
+	// Updates buffered for each subscriber
+	Buffer int `default:"8"`
+	// Updates kept per location for Last-Event-ID resumption
+	History int `default:"16"`
+	// Maximum locations one WebSocket can subscribe to
+	MaxSubscriptions int `default:"10"`
+	// Messages queued for each WebSocket before it's disconnected as too slow
+	SendBuffer   int           `default:"32"`
+	WriteTimeout time.Duration `default:"10s"`
+}
+
+type Webhook struct {
+	// Where subscriptions are kept, memory or sqlite
+	Store      string        `default:"memory"`
+	SQLitePath string        `default:"weather.db"`
+	Interval   time.Duration `default:"1m"`
+	// Attempts made to deliver each event before it's dead lettered
+	MaxAttempts int           `default:"5"`
+	Backoff     time.Duration `default:"2s"`
+	Timeout     time.Duration `default:"10s"`
+}
+
+type Archive struct {
+	// Every current observation is recorded when enabled, so history can be served without the provider
+	Enabled    bool   `default:"false"`
+	SQLitePath string `default:"archive.db"`
+	// How far from the time asked for an archived observation can be
+	Window time.Duration `default:"1h"`
+}
+
+type CAP struct {
+	// Files or URLs of CAP alerts, or feeds of them, to ingest
+	Feeds    []string
+	Interval time.Duration `default:"5m"`
+	Timeout  time.Duration `default:"10s"`
+	// Sender of the CAP messages the alerts endpoint writes
+	Sender string `default:"weather-exercise"`
+}
+
 type Config struct {
 	Address          string        `default:"0.0.0.0"`
 	Port             int           `default:"80"`
@@ -23,6 +120,38 @@ type Config struct {
 	ReadWriteTimeout time.Duration `default:"20s"`
 	IdleTimeout      time.Duration `default:"75s"`
 	ShutdownTime     time.Duration `default:"20s"`
+	RootSunset       time.Time     `default:"2027-04-19T00:00:00Z"`
 	OpenWeather      OpenWeather
+	NWS              NWS
+	CAP              CAP
 	AuthService      AuthService
+	AccessLog        AccessLog
+	Tracing          Tracing
+	Health           Health
+	Batch            Batch
+	Area             Area
+	Route            Route
+	Stream           Stream
+	Webhook          Webhook
+	Archive          Archive
+}
+
+// Validate checks the settings envconfig can't, the intervals that background work ticks at must be positive
+func (c *Config) Validate() error {
+	intervals := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"WEATHER_STREAM_POLLINTERVAL", c.Stream.PollInterval},
+		{"WEATHER_STREAM_HEARTBEAT", c.Stream.Heartbeat},
+		{"WEATHER_WEBHOOK_INTERVAL", c.Webhook.Interval},
+		{"WEATHER_CAP_INTERVAL", c.CAP.Interval},
+	}
+	errs := []error{}
+	for _, i := range intervals {
+		if i.value <= 0 {
+			errs = append(errs, fmt.Errorf("%w: %s is %v", ErrInvalidInterval, i.name, i.value))
+		}
+	}
+	return errors.Join(errs...)
 }
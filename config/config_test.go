@@ -0,0 +1,40 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/config"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() config.Config {
+		c := config.Config{}
+		c.Stream.PollInterval = 30 * time.Second
+		c.Stream.Heartbeat = 15 * time.Second
+		c.Webhook.Interval = time.Minute
+		c.CAP.Interval = 5 * time.Minute
+		return c
+	}
+	tests := []struct {
+		name   string
+		modify func(c *config.Config)
+		err    error
+	}{
+		{"valid", func(c *config.Config) {}, nil},
+		{"zero-poll-interval", func(c *config.Config) { c.Stream.PollInterval = 0 }, config.ErrInvalidInterval},
+		{"negative-heartbeat", func(c *config.Config) { c.Stream.Heartbeat = -time.Second }, config.ErrInvalidInterval},
+		{"zero-webhook-interval", func(c *config.Config) { c.Webhook.Interval = 0 }, config.ErrInvalidInterval},
+		{"zero-cap-interval", func(c *config.Config) { c.CAP.Interval = 0 }, config.ErrInvalidInterval},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := valid()
+			test.modify(&c)
+			if err := c.Validate(); !errors.Is(err, test.err) {
+				t.Errorf("expected '%v' got '%v'", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Pollutant names, as used for the dominant pollutant of an index
+const (
+	PollutantCO   = "co"
+	PollutantNO2  = "no2"
+	PollutantO3   = "o3"
+	PollutantSO2  = "so2"
+	PollutantPM25 = "pm2_5"
+	PollutantPM10 = "pm10"
+)
+
+// Pollutants are concentrations in μg/m³
+type Pollutants struct {
+	CO   float64
+	NO   float64
+	NO2  float64
+	O3   float64
+	SO2  float64
+	PM25 float64
+	PM10 float64
+	NH3  float64
+}
+
+// AirQualityIndex is the value of an index, the band it falls in, and the pollutant responsible for it
+type AirQualityIndex struct {
+	Value    int
+	Category string
+	Dominant string
+}
+
+type AirQuality struct {
+	Coords     Coords
+	Pollutants Pollutants
+	// AQI is the US EPA Air Quality Index, 0 to 500
+	AQI AirQualityIndex
+	// CAQI is the European Common Air Quality Index, 0 to 100, and above for very high pollution
+	CAQI AirQualityIndex
+	// ProviderIndex is the provider's own index, for Open Weather 1 (good) to 5 (very poor)
+	ProviderIndex int
+	ObservedAt    time.Time
+	Source        Source
+}
+
+// RepoAirQuality is the provider's air pollution, before the indexes are worked out
+type RepoAirQuality struct {
+	Coords        Coords
+	Pollutants    Pollutants
+	ProviderIndex int
+	ObservedAt    time.Time
+	Source        Source
+}
+
+// AirQualitySource is where air pollution data comes from
+type AirQualitySource interface {
+	GetAirQualityByCoords(ctx context.Context, latitude float32, longitude float32) (*RepoAirQuality, error)
+	// GetAirQualityForecastByCoords returns the forecast steps in time order
+	GetAirQualityForecastByCoords(ctx context.Context, latitude float32, longitude float32) ([]RepoAirQuality, error)
+}
+
+// AirQualityReporter is the business logic for air quality
+type AirQualityReporter interface {
+	AirQualityIn(ctx context.Context, lat float32, lon float32) (*AirQuality, error)
+	AirQualityForecastIn(ctx context.Context, lat float32, lon float32) ([]AirQuality, error)
+}
+
+// AirQualityService works out air quality indexes from the pollutant concentrations
+type AirQualityService struct {
+	Source AirQualitySource
+}
+
+// AirQualityIn finds the current air quality at a latitude and longitude
+func (s *AirQualityService) AirQualityIn(ctx context.Context, lat float32, lon float32) (*AirQuality, error) {
+	ctx, span := tracer.Start(ctx, "domain.AirQualityIn", trace.WithAttributes(
+		attribute.Float64("geo.latitude", float64(lat)),
+		attribute.Float64("geo.longitude", float64(lon)),
+	))
+	defer span.End()
+	raq, err := s.Source.GetAirQualityByCoords(ctx, lat, lon)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting air quality")
+		return nil, fmt.Errorf("getting air quality by coordinates: %w", err)
+	}
+	aq := airQualityFromRepo(raq)
+	span.SetAttributes(attribute.Int("air_quality.aqi", aq.AQI.Value))
+	return aq, nil
+}
+
+// AirQualityForecastIn finds the forecast air quality at a latitude and longitude, in time order
+func (s *AirQualityService) AirQualityForecastIn(ctx context.Context, lat float32, lon float32) ([]AirQuality, error) {
+	ctx, span := tracer.Start(ctx, "domain.AirQualityForecastIn", trace.WithAttributes(
+		attribute.Float64("geo.latitude", float64(lat)),
+		attribute.Float64("geo.longitude", float64(lon)),
+	))
+	defer span.End()
+	steps, err := s.Source.GetAirQualityForecastByCoords(ctx, lat, lon)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting air quality forecast")
+		return nil, fmt.Errorf("getting air quality forecast by coordinates: %w", err)
+	}
+	forecast := make([]AirQuality, len(steps))
+	for i := range steps {
+		forecast[i] = *airQualityFromRepo(&steps[i])
+	}
+	return forecast, nil
+}
+
+func airQualityFromRepo(raq *RepoAirQuality) *AirQuality {
+	return &AirQuality{
+		Coords:        raq.Coords,
+		Pollutants:    raq.Pollutants,
+		AQI:           EPAAQI(raq.Pollutants),
+		CAQI:          CAQI(raq.Pollutants),
+		ProviderIndex: raq.ProviderIndex,
+		ObservedAt:    raq.ObservedAt,
+		Source:        raq.Source,
+	}
+}
+
+// breakpoint maps a range of concentrations linearly onto a range of the index
+type breakpoint struct {
+	cLow, cHigh float64
+	iLow, iHigh float64
+}
+
+// subIndex interpolates the concentration between the breakpoints it falls in.
+// Past the last breakpoint it's capped, unless extrapolate is set.
+func subIndex(c float64, bps []breakpoint, extrapolate bool) float64 {
+	for i, bp := range bps {
+		// concentrations between one band's high and the next band's low, which truncation normally prevents, count with the lower band
+		if c <= bp.cHigh || (i+1 < len(bps) && c < bps[i+1].cLow) {
+			c = math.Max(c, bp.cLow)
+			return (bp.iHigh-bp.iLow)/(bp.cHigh-bp.cLow)*(c-bp.cLow) + bp.iLow
+		}
+	}
+	last := bps[len(bps)-1]
+	if !extrapolate {
+		return last.iHigh
+	}
+	return (last.iHigh-last.iLow)/(last.cHigh-last.cLow)*(c-last.cLow) + last.iLow
+}
+
+// Molecular weights, for converting μg/m³ to parts per billion at 25°C and 1 atmosphere
+const (
+	molarVolume = 24.45
+	weightCO    = 28.01
+	weightNO2   = 46.01
+	weightO3    = 48.00
+	weightSO2   = 64.07
+)
+
+func ppb(ugm3 float64, weight float64) float64 {
+	return ugm3 * molarVolume / weight
+}
+
+// truncate drops digits past places, allowing for concentrations like 35.4 that floats can't hold exactly
+func truncate(f float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Floor(f*p+1e-9) / p
+}
+
+// EPA breakpoints, from the 2024 revision of the AQI technical assistance document
+var (
+	epaPM25 = []breakpoint{
+		{0, 9.0, 0, 50}, {9.1, 35.4, 51, 100}, {35.5, 55.4, 101, 150},
+		{55.5, 125.4, 151, 200}, {125.5, 225.4, 201, 300}, {225.5, 325.4, 301, 500},
+	}
+	epaPM10 = []breakpoint{
+		{0, 54, 0, 50}, {55, 154, 51, 100}, {155, 254, 101, 150},
+		{255, 354, 151, 200}, {355, 424, 201, 300}, {425, 604, 301, 500},
+	}
+	// 8 hour ozone in ppm.  Above 0.200 the 1 hour breakpoints take over, which stay Very Unhealthy until 0.405,
+	// so it's held at 300 until then.
+	epaO3 = []breakpoint{
+		{0, 0.054, 0, 50}, {0.055, 0.070, 51, 100}, {0.071, 0.085, 101, 150},
+		{0.086, 0.105, 151, 200}, {0.106, 0.200, 201, 300}, {0.201, 0.404, 300, 300}, {0.405, 0.604, 301, 500},
+	}
+	// ppm
+	epaCO = []breakpoint{
+		{0, 4.4, 0, 50}, {4.5, 9.4, 51, 100}, {9.5, 12.4, 101, 150},
+		{12.5, 15.4, 151, 200}, {15.5, 30.4, 201, 300}, {30.5, 50.4, 301, 500},
+	}
+	// ppb
+	epaSO2 = []breakpoint{
+		{0, 35, 0, 50}, {36, 75, 51, 100}, {76, 185, 101, 150},
+		{186, 304, 151, 200}, {305, 604, 201, 300}, {605, 1004, 301, 500},
+	}
+	// ppb
+	epaNO2 = []breakpoint{
+		{0, 53, 0, 50}, {54, 100, 51, 100}, {101, 360, 101, 150},
+		{361, 649, 151, 200}, {650, 1249, 201, 300}, {1250, 2049, 301, 500},
+	}
+)
+
+// EPAAQI works out the US EPA Air Quality Index, the highest of the pollutants' sub indexes.
+// The EPA averages each pollutant over 1 to 24 hours, these are single readings so it's an estimate, closer to a NowCast.
+func EPAAQI(p Pollutants) AirQualityIndex {
+	subs := []struct {
+		name  string
+		index float64
+	}{
+		{PollutantPM25, subIndex(truncate(p.PM25, 1), epaPM25, false)},
+		{PollutantPM10, subIndex(truncate(p.PM10, 0), epaPM10, false)},
+		{PollutantO3, subIndex(truncate(ppb(p.O3, weightO3)/1000, 3), epaO3, false)},
+		{PollutantCO, subIndex(truncate(ppb(p.CO, weightCO)/1000, 1), epaCO, false)},
+		{PollutantSO2, subIndex(truncate(ppb(p.SO2, weightSO2), 0), epaSO2, false)},
+		{PollutantNO2, subIndex(truncate(ppb(p.NO2, weightNO2), 0), epaNO2, false)},
+	}
+	best := subs[0]
+	for _, s := range subs[1:] {
+		if s.index > best.index {
+			best = s
+		}
+	}
+	value := int(math.Round(best.index))
+	return AirQualityIndex{Value: value, Category: epaCategory(value), Dominant: best.name}
+}
+
+func epaCategory(aqi int) string {
+	switch {
+	case aqi <= 50:
+		return "Good"
+	case aqi <= 100:
+		return "Moderate"
+	case aqi <= 150:
+		return "Unhealthy for Sensitive Groups"
+	case aqi <= 200:
+		return "Unhealthy"
+	case aqi <= 300:
+		return "Very Unhealthy"
+	}
+	return "Hazardous"
+}
+
+// CAQI hourly background grid, in μg/m³
+var (
+	caqiNO2  = []breakpoint{{0, 50, 0, 25}, {50, 100, 25, 50}, {100, 200, 50, 75}, {200, 400, 75, 100}}
+	caqiPM10 = []breakpoint{{0, 25, 0, 25}, {25, 50, 25, 50}, {50, 90, 50, 75}, {90, 180, 75, 100}}
+	caqiO3   = []breakpoint{{0, 60, 0, 25}, {60, 120, 25, 50}, {120, 180, 50, 75}, {180, 240, 75, 100}}
+	caqiPM25 = []breakpoint{{0, 15, 0, 25}, {15, 30, 25, 50}, {30, 55, 50, 75}, {55, 110, 75, 100}}
+	caqiCO   = []breakpoint{{0, 5000, 0, 25}, {5000, 7500, 25, 50}, {7500, 10000, 50, 75}, {10000, 20000, 75, 100}}
+	caqiSO2  = []breakpoint{{0, 50, 0, 25}, {50, 100, 25, 50}, {100, 350, 50, 75}, {350, 500, 75, 100}}
+)
+
+// CAQI works out the hourly background Common Air Quality Index used across Europe, the highest of the pollutants' sub indexes.
+// Concentrations beyond the grid are extrapolated from its last band, so very high pollution goes over 100.
+func CAQI(p Pollutants) AirQualityIndex {
+	subs := []struct {
+		name  string
+		index float64
+	}{
+		{PollutantNO2, subIndex(p.NO2, caqiNO2, true)},
+		{PollutantPM10, subIndex(p.PM10, caqiPM10, true)},
+		{PollutantO3, subIndex(p.O3, caqiO3, true)},
+		{PollutantPM25, subIndex(p.PM25, caqiPM25, true)},
+		{PollutantCO, subIndex(p.CO, caqiCO, true)},
+		{PollutantSO2, subIndex(p.SO2, caqiSO2, true)},
+	}
+	best := subs[0]
+	for _, s := range subs[1:] {
+		if s.index > best.index {
+			best = s
+		}
+	}
+	value := int(math.Round(best.index))
+	return AirQualityIndex{Value: value, Category: caqiCategory(value), Dominant: best.name}
+}
+
+func caqiCategory(caqi int) string {
+	switch {
+	case caqi < 25:
+		return "Very low"
+	case caqi < 50:
+		return "Low"
+	case caqi < 75:
+		return "Medium"
+	case caqi <= 100:
+		return "High"
+	}
+	return "Very high"
+}
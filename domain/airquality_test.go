@@ -0,0 +1,105 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+type mockAirQualitySource struct {
+	steps []domain.RepoAirQuality
+	err   error
+}
+
+func (m *mockAirQualitySource) GetAirQualityByCoords(ctx context.Context, lat float32, lon float32) (*domain.RepoAirQuality, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &m.steps[0], nil
+}
+
+func (m *mockAirQualitySource) GetAirQualityForecastByCoords(ctx context.Context, lat float32, lon float32) ([]domain.RepoAirQuality, error) {
+	return m.steps, m.err
+}
+
+func TestEPAAQI(t *testing.T) {
+	tests := []struct {
+		name       string
+		pollutants domain.Pollutants
+		expected   domain.AirQualityIndex
+	}{
+		{"clean", domain.Pollutants{}, domain.AirQualityIndex{Value: 0, Category: "Good", Dominant: domain.PollutantPM25}},
+		{"pm2_5-band-top", domain.Pollutants{PM25: 35.4}, domain.AirQualityIndex{Value: 100, Category: "Moderate", Dominant: domain.PollutantPM25}},
+		{"pm2_5-interpolated", domain.Pollutants{PM25: 12.04}, domain.AirQualityIndex{Value: 56, Category: "Moderate", Dominant: domain.PollutantPM25}},
+		{"pm10-hazardous", domain.Pollutants{PM25: 5, PM10: 500}, domain.AirQualityIndex{Value: 384, Category: "Hazardous", Dominant: domain.PollutantPM10}},
+		{"capped", domain.Pollutants{PM25: 1000}, domain.AirQualityIndex{Value: 500, Category: "Hazardous", Dominant: domain.PollutantPM25}},
+		// 200 μg/m³ is 106 ppb
+		{"no2-converted", domain.Pollutants{PM25: 5, NO2: 200}, domain.AirQualityIndex{Value: 102, Category: "Unhealthy for Sensitive Groups", Dominant: domain.PollutantNO2}},
+		// 300 μg/m³ is 0.152 ppm
+		{"o3-converted", domain.Pollutants{O3: 300}, domain.AirQualityIndex{Value: 249, Category: "Very Unhealthy", Dominant: domain.PollutantO3}},
+		{"o3-held-very-unhealthy", domain.Pollutants{O3: 600}, domain.AirQualityIndex{Value: 300, Category: "Very Unhealthy", Dominant: domain.PollutantO3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := domain.EPAAQI(test.pollutants)
+			if got != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestCAQI(t *testing.T) {
+	tests := []struct {
+		name       string
+		pollutants domain.Pollutants
+		expected   domain.AirQualityIndex
+	}{
+		{"clean", domain.Pollutants{}, domain.AirQualityIndex{Value: 0, Category: "Very low", Dominant: domain.PollutantNO2}},
+		{"no2-band-top", domain.Pollutants{NO2: 100}, domain.AirQualityIndex{Value: 50, Category: "Medium", Dominant: domain.PollutantNO2}},
+		{"pm10-interpolated", domain.Pollutants{NO2: 10, PM10: 70}, domain.AirQualityIndex{Value: 63, Category: "Medium", Dominant: domain.PollutantPM10}},
+		{"o3-high", domain.Pollutants{O3: 200}, domain.AirQualityIndex{Value: 83, Category: "High", Dominant: domain.PollutantO3}},
+		{"pm2_5-extrapolated", domain.Pollutants{PM25: 220}, domain.AirQualityIndex{Value: 150, Category: "Very high", Dominant: domain.PollutantPM25}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := domain.CAQI(test.pollutants)
+			if got != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestAirQualityService(t *testing.T) {
+	observed := time.Date(2024, 5, 7, 12, 0, 0, 0, time.UTC)
+	source := &mockAirQualitySource{steps: []domain.RepoAirQuality{
+		{Pollutants: domain.Pollutants{PM25: 35.4}, ProviderIndex: 3, ObservedAt: observed},
+		{Pollutants: domain.Pollutants{PM25: 5}, ProviderIndex: 1, ObservedAt: observed.Add(time.Hour)},
+	}}
+	s := domain.AirQualityService{Source: source}
+	ctx := context.Background()
+	aq, err := s.AirQualityIn(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if aq.AQI.Value != 100 || aq.CAQI.Value != 55 || aq.ProviderIndex != 3 || !aq.ObservedAt.Equal(observed) {
+		t.Errorf("expected the indexes to be worked out got '%v'", aq)
+	}
+	forecast, err := s.AirQualityForecastIn(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(forecast) != 2 || forecast[1].AQI.Value != 28 {
+		t.Errorf("expected the forecast steps to be worked out got '%v'", forecast)
+	}
+
+	boom := errors.New("boom")
+	s.Source = &mockAirQualitySource{err: boom}
+	if _, err := s.AirQualityIn(ctx, 1, 2); !errors.Is(err, boom) {
+		t.Errorf("expected '%v' got '%v'", boom, err)
+	}
+}
@@ -0,0 +1,241 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broganross/weather-exercise/geo"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AlertSeverity follows the CAP severity levels
+type AlertSeverity string
+
+const (
+	SeverityExtreme  AlertSeverity = "extreme"
+	SeveritySevere   AlertSeverity = "severe"
+	SeverityModerate AlertSeverity = "moderate"
+	SeverityMinor    AlertSeverity = "minor"
+	SeverityUnknown  AlertSeverity = "unknown"
+)
+
+// Rank orders severities, higher is more severe
+func (s AlertSeverity) Rank() int {
+	switch s {
+	case SeverityExtreme:
+		return 4
+	case SeveritySevere:
+		return 3
+	case SeverityModerate:
+		return 2
+	case SeverityMinor:
+		return 1
+	}
+	return 0
+}
+
+// ParseSeverity reads a CAP severity in any case, anything unrecognized is unknown
+func ParseSeverity(s string) AlertSeverity {
+	switch sev := AlertSeverity(strings.ToLower(strings.TrimSpace(s))); sev {
+	case SeverityExtreme, SeveritySevere, SeverityModerate, SeverityMinor:
+		return sev
+	}
+	return SeverityUnknown
+}
+
+// AlertUrgency follows the CAP urgency levels
+type AlertUrgency string
+
+const (
+	UrgencyImmediate AlertUrgency = "immediate"
+	UrgencyExpected  AlertUrgency = "expected"
+	UrgencyFuture    AlertUrgency = "future"
+	UrgencyPast      AlertUrgency = "past"
+	UrgencyUnknown   AlertUrgency = "unknown"
+)
+
+// ParseUrgency reads a CAP urgency in any case, anything unrecognized is unknown
+func ParseUrgency(s string) AlertUrgency {
+	switch u := AlertUrgency(strings.ToLower(strings.TrimSpace(s))); u {
+	case UrgencyImmediate, UrgencyExpected, UrgencyFuture, UrgencyPast:
+		return u
+	}
+	return UrgencyUnknown
+}
+
+// AlertCertainty follows the CAP certainty levels
+type AlertCertainty string
+
+const (
+	CertaintyObserved AlertCertainty = "observed"
+	CertaintyLikely   AlertCertainty = "likely"
+	CertaintyPossible AlertCertainty = "possible"
+	CertaintyUnlikely AlertCertainty = "unlikely"
+	CertaintyUnknown  AlertCertainty = "unknown"
+)
+
+// ParseCertainty reads a CAP certainty in any case, anything unrecognized is unknown
+func ParseCertainty(s string) AlertCertainty {
+	switch c := AlertCertainty(strings.ToLower(strings.TrimSpace(s))); c {
+	case CertaintyObserved, CertaintyLikely, CertaintyPossible, CertaintyUnlikely:
+		return c
+	}
+	return CertaintyUnknown
+}
+
+// Alert is a severe weather alert issued for an area
+type Alert struct {
+	// ID is unique per provider
+	ID          string
+	Event       string
+	Headline    string
+	Description string
+	Instruction string
+	// Sender is who issued the alert, like a national weather service office
+	Sender    string
+	Severity  AlertSeverity
+	Urgency   AlertUrgency
+	Certainty AlertCertainty
+	Onset     time.Time
+	// Expires is zero if the alert doesn't say
+	Expires time.Time
+	// AreaDesc names the area the alert covers
+	AreaDesc string
+	// Areas are the shapes the alert covers, when the provider gives them
+	Areas  []geo.Area
+	Source Source
+}
+
+// Active is whether the alert hasn't expired by t
+func (a *Alert) Active(t time.Time) bool {
+	return a.Expires.IsZero() || a.Expires.After(t)
+}
+
+// AlertSummary is a short description of the alerts at a location
+type AlertSummary struct {
+	Count int
+	// Severity is the highest of the alerts, unknown if there aren't any
+	Severity AlertSeverity
+	// Events are the distinct alert events, most severe first
+	Events []string
+}
+
+// Summarize the alerts, which are expected to be ordered most severe first
+func Summarize(alerts []Alert) AlertSummary {
+	s := AlertSummary{Count: len(alerts), Severity: SeverityUnknown, Events: []string{}}
+	seen := map[string]bool{}
+	for _, a := range alerts {
+		if a.Severity.Rank() > s.Severity.Rank() {
+			s.Severity = a.Severity
+		}
+		if !seen[a.Event] {
+			seen[a.Event] = true
+			s.Events = append(s.Events, a.Event)
+		}
+	}
+	return s
+}
+
+// AlertSource is a provider of severe weather alerts
+type AlertSource interface {
+	// GetAlertsByCoords returns the alerts covering the coordinates
+	GetAlertsByCoords(ctx context.Context, latitude float32, longitude float32) ([]Alert, error)
+}
+
+// AlertStore keeps alerts ingested from feeds, looking them up by the areas they cover
+type AlertStore interface {
+	AlertSource
+	// Put adds alerts, replacing any with the same ID
+	Put(ctx context.Context, alerts ...Alert) error
+	// Remove drops alerts by ID, along with the parts of multi part alerts (ID#n).  Missing alerts are ignored.
+	Remove(ctx context.Context, ids ...string) error
+}
+
+// Alerter is the business logic for severe weather alerts
+type Alerter interface {
+	AlertsIn(ctx context.Context, lat float32, lon float32) ([]Alert, error)
+}
+
+// AlertsIn finds the active alerts at a latitude and longitude, from the weather source if SourceAlerts is set along with any other alert sources.
+// The same alert from several providers is only returned once.  Alerts are ordered most severe first, then by onset.
+// It only fails if every source does.
+func (w *WeatherService) AlertsIn(ctx context.Context, lat float32, lon float32) ([]Alert, error) {
+	ctx, span := tracer.Start(ctx, "domain.AlertsIn", trace.WithAttributes(
+		attribute.Float64("geo.latitude", float64(lat)),
+		attribute.Float64("geo.longitude", float64(lon)),
+	))
+	defer span.End()
+	sources := w.AlertSources
+	if w.SourceAlerts {
+		sources = append([]AlertSource{w.Source}, sources...)
+	}
+	if len(sources) == 0 {
+		return []Alert{}, nil
+	}
+	found := make([][]Alert, len(sources))
+	errs := make([]error, len(sources))
+	wg := sync.WaitGroup{}
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source AlertSource) {
+			defer wg.Done()
+			found[i], errs[i] = source.GetAlertsByCoords(ctx, lat, lon)
+		}(i, source)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+			log.Ctx(ctx).Warn().Err(err).Msg("getting alerts")
+		}
+	}
+	if failed == len(sources) {
+		err := errors.Join(errs...)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting alerts")
+		return nil, fmt.Errorf("getting alerts by coordinates: %w", err)
+	}
+	alerts := mergeAlerts(found, time.Now())
+	span.SetAttributes(attribute.Int("weather.alerts", len(alerts)))
+	return alerts, nil
+}
+
+// mergeAlerts combines the active alerts from each source.
+// Alerts for the same event, expiring at the same time, are duplicates; the one with a known severity is kept.
+func mergeAlerts(found [][]Alert, now time.Time) []Alert {
+	alerts := []Alert{}
+	index := map[string]int{}
+	for _, list := range found {
+		for _, a := range list {
+			if !a.Active(now) {
+				continue
+			}
+			key := fmt.Sprintf("%s|%d", strings.ToLower(a.Event), a.Expires.Unix())
+			if i, ok := index[key]; ok {
+				if alerts[i].Severity == SeverityUnknown && a.Severity != SeverityUnknown {
+					alerts[i] = a
+				}
+				continue
+			}
+			index[key] = len(alerts)
+			alerts = append(alerts, a)
+		}
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		if ri, rj := alerts[i].Severity.Rank(), alerts[j].Severity.Rank(); ri != rj {
+			return ri > rj
+		}
+		return alerts[i].Onset.Before(alerts[j].Onset)
+	})
+	return alerts
+}
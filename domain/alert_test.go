@@ -0,0 +1,144 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+type mockAlertSource struct {
+	alerts []domain.Alert
+	err    error
+}
+
+func (mas *mockAlertSource) GetAlertsByCoords(ctx context.Context, lat float32, lon float32) ([]domain.Alert, error) {
+	return mas.alerts, mas.err
+}
+
+func TestWeatherService_AlertsIn(t *testing.T) {
+	now := time.Now().UTC()
+	later := now.Add(2 * time.Hour)
+	owStorm := domain.Alert{ID: "ow-1", Event: "Severe Thunderstorm Warning", Severity: domain.SeverityUnknown, Onset: now, Expires: later}
+	owHeat := domain.Alert{ID: "ow-2", Event: "Heat Advisory", Severity: domain.SeverityMinor, Onset: now, Expires: later}
+	owOld := domain.Alert{ID: "ow-3", Event: "Flood Warning", Severity: domain.SeveritySevere, Onset: now.Add(-3 * time.Hour), Expires: now.Add(-time.Hour)}
+	nwsStorm := domain.Alert{ID: "nws-1", Event: "Severe Thunderstorm Warning", Severity: domain.SeveritySevere, Onset: now, Expires: later}
+	nwsTornado := domain.Alert{ID: "nws-2", Event: "Tornado Watch", Severity: domain.SeverityExtreme, Onset: now.Add(time.Hour)}
+	tests := []struct {
+		name    string
+		repo    []domain.Alert
+		others  []domain.AlertSource
+		want    []string
+		summary domain.AlertSummary
+		err     bool
+	}{
+		{
+			"merged",
+			[]domain.Alert{owStorm, owHeat, owOld},
+			[]domain.AlertSource{&mockAlertSource{alerts: []domain.Alert{nwsStorm, nwsTornado}}},
+			[]string{"nws-2", "nws-1", "ow-2"},
+			domain.AlertSummary{
+				Count:    3,
+				Severity: domain.SeverityExtreme,
+				Events:   []string{"Tornado Watch", "Severe Thunderstorm Warning", "Heat Advisory"},
+			},
+			false,
+		},
+		{
+			"one-source-failing",
+			[]domain.Alert{owHeat},
+			[]domain.AlertSource{&mockAlertSource{err: errors.New("unavailable")}},
+			[]string{"ow-2"},
+			domain.AlertSummary{Count: 1, Severity: domain.SeverityMinor, Events: []string{"Heat Advisory"}},
+			false,
+		},
+		{
+			"none",
+			[]domain.Alert{},
+			nil,
+			[]string{},
+			domain.AlertSummary{Count: 0, Severity: domain.SeverityUnknown, Events: []string{}},
+			false,
+		},
+		{
+			"all-failing",
+			nil,
+			[]domain.AlertSource{&mockAlertSource{err: errors.New("unavailable")}},
+			nil,
+			domain.AlertSummary{},
+			true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &mockWeatherRepo{alerts: map[string][]domain.Alert{}}
+			if test.repo != nil {
+				r.alerts["1.0000:2.0000"] = test.repo
+			}
+			ws := &domain.WeatherService{Source: r, SourceAlerts: true, AlertSources: test.others}
+			got, err := ws.AlertsIn(context.Background(), 1, 2)
+			if test.err {
+				if err == nil {
+					t.Errorf("expected an error got '%v'", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			ids := []string{}
+			for _, a := range got {
+				ids = append(ids, a.ID)
+			}
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Errorf("expected '%v' got '%v'", test.want, ids)
+			}
+			if summary := domain.Summarize(got); !reflect.DeepEqual(summary, test.summary) {
+				t.Errorf("expected '%v' got '%v'", test.summary, summary)
+			}
+		})
+	}
+}
+
+func TestWeatherService_AlertsIn_sourceAlertsOff(t *testing.T) {
+	nwsStorm := domain.Alert{ID: "nws-1", Event: "Severe Thunderstorm Warning", Severity: domain.SeveritySevere}
+	// the repo has no alerts here, so asking it would fail
+	r := &mockWeatherRepo{alerts: map[string][]domain.Alert{}}
+	ws := &domain.WeatherService{Source: r, AlertSources: []domain.AlertSource{&mockAlertSource{alerts: []domain.Alert{nwsStorm}}}}
+	got, err := ws.AlertsIn(context.Background(), 1, 2)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(got) != 1 || got[0].ID != "nws-1" {
+		t.Errorf("expected '[nws-1]' got '%v'", got)
+	}
+	ws = &domain.WeatherService{Source: r}
+	got, err = ws.AlertsIn(context.Background(), 1, 2)
+	if err != nil || len(got) != 0 {
+		t.Errorf("expected no alerts got '%v' '%v'", got, err)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want domain.AlertSeverity
+	}{
+		{"Extreme", domain.SeverityExtreme},
+		{" severe ", domain.SeveritySevere},
+		{"MODERATE", domain.SeverityModerate},
+		{"Minor", domain.SeverityMinor},
+		{"Unknown", domain.SeverityUnknown},
+		{"catastrophic", domain.SeverityUnknown},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			if got := domain.ParseSeverity(test.in); got != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/broganross/weather-exercise/geo"
+)
+
+var ErrNoSamples = errors.New("no samples in area")
+
+// AreaService summarizes the current weather across an area, by sampling a grid of points in it
+type AreaService struct {
+	Batch *Batch
+	// Upper limit on the points sampled in one area, to protect the upstream quota
+	MaxSamples int
+}
+
+// AreaSummary is the aggregated weather of the samples in an area
+type AreaSummary struct {
+	Samples []BatchResult
+	// Number of samples in each temperature class
+	Temperatures map[Temperature]int
+	// Every condition seen in the area, sorted
+	States []string
+	// Lowest and highest temperatures in Fahrenheit
+	MinDegrees float32
+	MaxDegrees float32
+	// Number of samples that couldn't be looked up
+	Failed int
+}
+
+// SummaryIn samples the current weather in the area
+func (as *AreaService) SummaryIn(ctx context.Context, area geo.Area) (*AreaSummary, error) {
+	points := geo.Grid(area, as.MaxSamples)
+	if len(points) == 0 {
+		return nil, ErrNoSamples
+	}
+	coords := make([]Coords, len(points))
+	for i, p := range points {
+		coords[i] = Coords{Latitude: float32(p.Lat), Longitude: float32(p.Lon)}
+	}
+	results, err := as.Batch.CurrentIn(ctx, coords)
+	if err != nil {
+		return nil, fmt.Errorf("sampling area: %w", err)
+	}
+	summary := &AreaSummary{
+		Samples:      results,
+		Temperatures: map[Temperature]int{},
+	}
+	states := map[string]bool{}
+	first := true
+	var lastErr error
+	for _, r := range results {
+		if r.Err != nil {
+			summary.Failed++
+			lastErr = r.Err
+			continue
+		}
+		summary.Temperatures[r.Weather.Temperature]++
+		for _, s := range r.Weather.States {
+			states[s] = true
+		}
+		if first {
+			summary.MinDegrees = r.Weather.Degrees
+			summary.MaxDegrees = r.Weather.Degrees
+			first = false
+		}
+		summary.MinDegrees = min(summary.MinDegrees, r.Weather.Degrees)
+		summary.MaxDegrees = max(summary.MaxDegrees, r.Weather.Degrees)
+	}
+	if summary.Failed == len(results) {
+		return nil, fmt.Errorf("sampling area: %w", lastErr)
+	}
+	for s := range states {
+		summary.States = append(summary.States, s)
+	}
+	sort.Strings(summary.States)
+	return summary, nil
+}
@@ -0,0 +1,64 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+)
+
+// gradientService gets warmer to the east, and rains in the west
+type gradientService struct {
+	fail bool
+}
+
+func (gs *gradientService) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	if gs.fail {
+		return nil, errNotFound
+	}
+	w := &domain.Weather{
+		Coords:      domain.Coords{Latitude: lat, Longitude: lon},
+		States:      []string{"Clear"},
+		Temperature: domain.TempHot,
+		Degrees:     60 + lon,
+	}
+	if lon < 10 {
+		w.States = []string{"Rain", "Mist"}
+		w.Temperature = domain.TempMod
+	}
+	return w, nil
+}
+
+func TestAreaService_SummaryIn(t *testing.T) {
+	area := geo.BBox{MinLon: 0, MinLat: 0, MaxLon: 40, MaxLat: 10}
+	as := domain.AreaService{
+		Batch:      &domain.Batch{Service: &gradientService{}, Concurrency: 4},
+		MaxSamples: 4,
+	}
+	got, err := as.SummaryIn(context.Background(), area)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(got.Samples) != 4 {
+		t.Errorf("expected 4 samples got %d", len(got.Samples))
+	}
+	wantTemps := map[domain.Temperature]int{domain.TempMod: 1, domain.TempHot: 3}
+	if !reflect.DeepEqual(wantTemps, got.Temperatures) {
+		t.Errorf("expected '%v' got '%v'", wantTemps, got.Temperatures)
+	}
+	wantStates := []string{"Clear", "Mist", "Rain"}
+	if !reflect.DeepEqual(wantStates, got.States) {
+		t.Errorf("expected '%v' got '%v'", wantStates, got.States)
+	}
+	if got.MinDegrees != 65 || got.MaxDegrees != 95 {
+		t.Errorf("expected 65 to 95 degrees got %v to %v", got.MinDegrees, got.MaxDegrees)
+	}
+
+	as.Batch.Service = &gradientService{fail: true}
+	if _, err := as.SummaryIn(context.Background(), area); !errors.Is(err, errNotFound) {
+		t.Errorf("expected '%v' got '%v'", errNotFound, err)
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// Batch gets the current weather for many coordinates through a Service.
+// Coordinates are snapped to a grid first, so nearby points share a single lookup.
+type Batch struct {
+	Service Service
+	// Maximum number of coordinates in one batch
+	MaxItems int
+	// Maximum number of concurrent lookups
+	Concurrency int
+	// Grid size in degrees, zero disables snapping
+	GridSize float32
+}
+
+// BatchResult is the outcome for one of the requested coordinates
+type BatchResult struct {
+	Coords  Coords
+	Weather *Weather
+	Err     error
+}
+
+// CurrentIn looks up the current weather for each of the coordinates, returning results in the same order.
+// Failed lookups are reported per item, the error is only for the batch as a whole.
+func (b *Batch) CurrentIn(ctx context.Context, coords []Coords) ([]BatchResult, error) {
+	if b.MaxItems > 0 && len(coords) > b.MaxItems {
+		return nil, fmt.Errorf("%w: %d coordinates, maximum is %d", ErrBatchTooLarge, len(coords), b.MaxItems)
+	}
+	// one lookup per grid cell
+	lookups := map[Coords]*BatchResult{}
+	for _, c := range coords {
+		snapped := Snap(c, b.GridSize)
+		if _, ok := lookups[snapped]; !ok {
+			lookups[snapped] = &BatchResult{Coords: snapped}
+		}
+	}
+	concurrency := b.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for snapped, result := range lookups {
+		wg.Add(1)
+		go func(snapped Coords, result *BatchResult) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				return
+			}
+			result.Weather, result.Err = b.Service.CurrentIn(ctx, snapped.Latitude, snapped.Longitude)
+		}(snapped, result)
+	}
+	wg.Wait()
+
+	results := make([]BatchResult, len(coords))
+	for i, c := range coords {
+		lookup := lookups[Snap(c, b.GridSize)]
+		results[i] = BatchResult{
+			Coords:  c,
+			Weather: lookup.Weather,
+			Err:     lookup.Err,
+		}
+	}
+	return results, nil
+}
+
+// Snap moves coordinates to the centre of their grid cell
+func Snap(c Coords, gridSize float32) Coords {
+	if gridSize <= 0 {
+		return c
+	}
+	snap := func(f float32) float32 {
+		g := float64(gridSize)
+		return float32((math.Floor(float64(f)/g) + 0.5) * g)
+	}
+	return Coords{
+		Latitude:  snap(c.Latitude),
+		Longitude: snap(c.Longitude),
+	}
+}
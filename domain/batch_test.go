@@ -0,0 +1,95 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+type countingService struct {
+	mu    sync.Mutex
+	calls []domain.Coords
+	fail  map[domain.Coords]bool
+}
+
+func (cs *countingService) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	c := domain.Coords{Latitude: lat, Longitude: lon}
+	cs.mu.Lock()
+	cs.calls = append(cs.calls, c)
+	cs.mu.Unlock()
+	if cs.fail[c] {
+		return nil, errNotFound
+	}
+	return &domain.Weather{Coords: c, Temperature: domain.TempMod}, nil
+}
+
+func TestBatch_CurrentIn(t *testing.T) {
+	svc := &countingService{
+		fail: map[domain.Coords]bool{
+			domain.Snap(domain.Coords{Latitude: 50, Longitude: 50}, 0.5): true,
+		},
+	}
+	batch := domain.Batch{
+		Service:     svc,
+		MaxItems:    4,
+		Concurrency: 2,
+		GridSize:    0.5,
+	}
+	coords := []domain.Coords{
+		{Latitude: 10.1, Longitude: 20.1},
+		{Latitude: 50.1, Longitude: 50.1},
+		{Latitude: 10.2, Longitude: 20.2},
+	}
+	results, err := batch.CurrentIn(context.Background(), coords)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(svc.calls) != 2 {
+		t.Errorf("expected nearby coordinates to share a lookup, got %d calls", len(svc.calls))
+	}
+	if len(results) != len(coords) {
+		t.Fatalf("expected %d results got %d", len(coords), len(results))
+	}
+	for i, r := range results {
+		if r.Coords != coords[i] {
+			t.Errorf("expected result %d for '%v' got '%v'", i, coords[i], r.Coords)
+		}
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("expected successful lookups got '%v' and '%v'", results[0].Err, results[2].Err)
+	}
+	if results[0].Weather != results[2].Weather {
+		t.Errorf("expected shared weather for the same grid cell")
+	}
+	if !errors.Is(results[1].Err, errNotFound) {
+		t.Errorf("expected '%v' got '%v'", errNotFound, results[1].Err)
+	}
+
+	_, err = batch.CurrentIn(context.Background(), make([]domain.Coords, 5))
+	if !errors.Is(err, domain.ErrBatchTooLarge) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrBatchTooLarge, err)
+	}
+}
+
+func TestSnap(t *testing.T) {
+	tests := []struct {
+		name string
+		in   domain.Coords
+		grid float32
+		want domain.Coords
+	}{
+		{"disabled", domain.Coords{Latitude: 1.23, Longitude: 4.56}, 0, domain.Coords{Latitude: 1.23, Longitude: 4.56}},
+		{"positive", domain.Coords{Latitude: 1.23, Longitude: 4.56}, 0.5, domain.Coords{Latitude: 1.25, Longitude: 4.75}},
+		{"negative", domain.Coords{Latitude: -1.23, Longitude: -4.56}, 0.5, domain.Coords{Latitude: -1.25, Longitude: -4.75}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := domain.Snap(test.in, test.grid); got != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
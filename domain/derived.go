@@ -0,0 +1,243 @@
+package domain
+
+import "math"
+
+// Conditions are the measurements beyond temperature
+type Conditions struct {
+	// Humidity is the relative humidity in percent
+	Humidity float32
+	// WindSpeed in miles per hour, or metres a second from a Repo
+	WindSpeed float32
+	// WindDegrees is the direction the wind blows from, clockwise from north
+	WindDegrees float32
+	// Visibility in metres, nil when the provider leaves it out
+	Visibility *float32
+	// Precipitation is the rain and snow over the last hour, in millimetres
+	Precipitation float32
+}
+
+// Comfort is how relative humidity feels
+type Comfort string
+
+const (
+	ComfortDry         Comfort = "dry"
+	ComfortComfortable Comfort = "comfortable"
+	ComfortHumid       Comfort = "humid"
+)
+
+// VisibilityCategory is the Met Office's description of visibility
+type VisibilityCategory string
+
+const (
+	VisibilityVeryPoor  VisibilityCategory = "very_poor"
+	VisibilityPoor      VisibilityCategory = "poor"
+	VisibilityModerate  VisibilityCategory = "moderate"
+	VisibilityGood      VisibilityCategory = "good"
+	VisibilityVeryGood  VisibilityCategory = "very_good"
+	VisibilityExcellent VisibilityCategory = "excellent"
+)
+
+// PrecipitationIntensity is how hard it's raining or snowing, by the Met Office's rates
+type PrecipitationIntensity string
+
+const (
+	PrecipitationNone     PrecipitationIntensity = "none"
+	PrecipitationLight    PrecipitationIntensity = "light"
+	PrecipitationModerate PrecipitationIntensity = "moderate"
+	PrecipitationHeavy    PrecipitationIntensity = "heavy"
+	PrecipitationViolent  PrecipitationIntensity = "violent"
+)
+
+// Derived are the metrics worked out from the temperature and conditions
+type Derived struct {
+	// DewPoint in Fahrenheit, nil when the air is completely dry
+	DewPoint *float32
+	Comfort  Comfort
+	// HeatIndex in Fahrenheit, nil when it's too cool for it to apply
+	HeatIndex *float32
+	// WindChill in Fahrenheit, nil when it's too warm or calm for it to apply
+	WindChill           *float32
+	Beaufort            int
+	BeaufortDescription string
+	// WindDirection is the 16 point compass direction the wind blows from, empty when it's calm
+	WindDirection string
+	// Visibility is empty when it isn't known
+	Visibility    VisibilityCategory
+	Precipitation PrecipitationIntensity
+}
+
+// Derive works out the metrics for a temperature in Fahrenheit and the conditions with it
+func Derive(temperature float32, c Conditions) Derived {
+	beaufort, description := Beaufort(c.WindSpeed)
+	d := Derived{
+		Comfort:             ClassifyComfort(c.Humidity),
+		Beaufort:            beaufort,
+		BeaufortDescription: description,
+		Precipitation:       ClassifyPrecipitation(c.Precipitation),
+	}
+	if dp, ok := DewPoint(temperature, c.Humidity); ok {
+		d.DewPoint = &dp
+	}
+	if hi, ok := HeatIndex(temperature, c.Humidity); ok {
+		d.HeatIndex = &hi
+	}
+	if wc, ok := WindChill(temperature, c.WindSpeed); ok {
+		d.WindChill = &wc
+	}
+	if c.Visibility != nil {
+		d.Visibility = ClassifyVisibility(*c.Visibility)
+	}
+	if beaufort > 0 {
+		d.WindDirection = CardinalDirection(c.WindDegrees)
+	}
+	return d
+}
+
+// DewPoint is the temperature air would have to cool to for it to be saturated, by the Magnus formula
+// with Alduchov and Eskridge's coefficients.  Temperatures are in Fahrenheit and humidity in percent.
+// Completely dry air has no dew point, which is false.
+func DewPoint(temperature float32, humidity float32) (float32, bool) {
+	if humidity <= 0 {
+		return 0, false
+	}
+	const b, c = 17.625, 243.04
+	t := fahrenheitToCelsius(float64(temperature))
+	gamma := math.Log(float64(humidity)/100) + b*t/(c+t)
+	return float32(celsiusToFahrenheit(c * gamma / (b - gamma))), true
+}
+
+// ClassifyComfort buckets relative humidity, in percent, by the 30 to 60% usually recommended
+func ClassifyComfort(humidity float32) Comfort {
+	switch {
+	case humidity < 30:
+		return ComfortDry
+	case humidity <= 60:
+		return ComfortComfortable
+	}
+	return ComfortHumid
+}
+
+// HeatIndex is how hot it feels with the humidity, by the National Weather Service's equation.
+// It only applies from 80°F, below which it's false.
+func HeatIndex(temperature float32, humidity float32) (float32, bool) {
+	if temperature < 80 {
+		return temperature, false
+	}
+	t, rh := float64(temperature), float64(humidity)
+	// the NWS tries Steadman's simpler formula first, and only uses the regression when that's 80°F or more
+	simple := 0.5 * (t + 61 + (t-68)*1.2 + rh*0.094)
+	if (simple+t)/2 < 80 {
+		return float32(simple), true
+	}
+	hi := -42.379 + 2.04901523*t + 10.14333127*rh - 0.22475541*t*rh - 0.00683783*t*t -
+		0.05481717*rh*rh + 0.00122874*t*t*rh + 0.00085282*t*rh*rh - 0.00000199*t*t*rh*rh
+	switch {
+	case rh < 13 && t >= 80 && t <= 112:
+		hi -= (13 - rh) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+	case rh > 85 && t >= 80 && t <= 87:
+		hi += (rh - 85) / 10 * (87 - t) / 5
+	}
+	return float32(hi), true
+}
+
+// WindChill is how cold it feels with the wind, by the National Weather Service's 2001 equation.
+// It only applies at 50°F or below, with wind over 3 mph, otherwise it's false.
+func WindChill(temperature float32, windSpeed float32) (float32, bool) {
+	if temperature > 50 || windSpeed <= 3 {
+		return temperature, false
+	}
+	t, v := float64(temperature), math.Pow(float64(windSpeed), 0.16)
+	return float32(35.74 + 0.6215*t - 35.75*v + 0.4275*t*v), true
+}
+
+// beaufortScale is the least wind speed, in metres per second, of each force after calm
+var beaufortScale = []struct {
+	min         float64
+	description string
+}{
+	{0, "Calm"},
+	{0.5, "Light air"},
+	{1.6, "Light breeze"},
+	{3.4, "Gentle breeze"},
+	{5.5, "Moderate breeze"},
+	{8.0, "Fresh breeze"},
+	{10.8, "Strong breeze"},
+	{13.9, "Near gale"},
+	{17.2, "Gale"},
+	{20.8, "Strong gale"},
+	{24.5, "Storm"},
+	{28.5, "Violent storm"},
+	{32.7, "Hurricane force"},
+}
+
+// Beaufort is the Beaufort force, and its description, of a wind speed in miles per hour
+func Beaufort(windSpeed float32) (int, string) {
+	// the scale's speeds are rounded to a tenth of a metre per second
+	ms := math.Round(float64(windSpeed)*0.44704*10) / 10
+	force := 0
+	for i, f := range beaufortScale {
+		if ms >= f.min {
+			force = i
+		}
+	}
+	return force, beaufortScale[force].description
+}
+
+var compassPoints = []string{"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE", "S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW"}
+
+// CardinalDirection is the nearest of the 16 compass points to a direction in degrees
+func CardinalDirection(degrees float32) string {
+	point := int(math.Round(float64(degrees)/22.5)) % 16
+	if point < 0 {
+		point += 16
+	}
+	return compassPoints[point]
+}
+
+// ClassifyVisibility buckets visibility in metres, the provider reports at most 10km
+func ClassifyVisibility(visibility float32) VisibilityCategory {
+	switch {
+	case visibility < 1000:
+		return VisibilityVeryPoor
+	case visibility < 4000:
+		return VisibilityPoor
+	case visibility < 10000:
+		return VisibilityModerate
+	case visibility < 20000:
+		return VisibilityGood
+	case visibility < 40000:
+		return VisibilityVeryGood
+	}
+	return VisibilityExcellent
+}
+
+// ClassifyPrecipitation buckets the rate of rain or snow, in millimetres an hour
+func ClassifyPrecipitation(rate float32) PrecipitationIntensity {
+	switch {
+	case rate <= 0:
+		return PrecipitationNone
+	case rate < 2:
+		return PrecipitationLight
+	case rate < 10:
+		return PrecipitationModerate
+	case rate < 50:
+		return PrecipitationHeavy
+	}
+	return PrecipitationViolent
+}
+
+func kelvinToFahrenheit(k float32) float32 {
+	return float32(celsiusToFahrenheit(float64(k) - 273.15))
+}
+
+func metresPerSecondToMPH(ms float32) float32 {
+	return float32(float64(ms) / 0.44704)
+}
+
+func fahrenheitToCelsius(f float64) float64 {
+	return (f - 32) * 5 / 9
+}
+
+func celsiusToFahrenheit(c float64) float64 {
+	return c*9/5 + 32
+}
@@ -0,0 +1,244 @@
+package domain_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+func TestDewPoint(t *testing.T) {
+	// reference values from NOAA's dew point calculator
+	tests := []struct {
+		name        string
+		temperature float32
+		humidity    float32
+		expected    float32
+		ok          bool
+	}{
+		{"mild", 68, 50, 48.7, true},
+		{"muggy", 86, 70, 75.1, true},
+		{"saturated", 32, 100, 32, true},
+		{"dry", 77, 30, 43.2, true},
+		{"no-humidity", 77, 0, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := domain.DewPoint(test.temperature, test.humidity)
+			if ok != test.ok {
+				t.Fatalf("expected '%v' got '%v'", test.ok, ok)
+			}
+			if ok && math.Abs(float64(got-test.expected)) > 0.1 {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestClassifyComfort(t *testing.T) {
+	tests := []struct {
+		humidity float32
+		expected domain.Comfort
+	}{
+		{0, domain.ComfortDry},
+		{29.9, domain.ComfortDry},
+		{30, domain.ComfortComfortable},
+		{60, domain.ComfortComfortable},
+		{60.1, domain.ComfortHumid},
+		{100, domain.ComfortHumid},
+	}
+	for _, test := range tests {
+		if got := domain.ClassifyComfort(test.humidity); got != test.expected {
+			t.Errorf("%v: expected '%v' got '%v'", test.humidity, test.expected, got)
+		}
+	}
+}
+
+func TestHeatIndex(t *testing.T) {
+	// reference values from the National Weather Service's heat index chart, in whole degrees
+	tests := []struct {
+		name        string
+		temperature float32
+		humidity    float32
+		expected    float32
+		ok          bool
+	}{
+		{"too-cool", 79, 90, 0, false},
+		{"simple", 80, 40, 80, true},
+		{"hot", 90, 50, 95, true},
+		{"very-hot", 100, 40, 109, true},
+		{"dangerous", 96, 65, 121, true},
+		{"humid", 84, 90, 98, true},
+		{"dry-adjustment", 110, 10, 104, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := domain.HeatIndex(test.temperature, test.humidity)
+			if ok != test.ok {
+				t.Fatalf("expected '%v' got '%v'", test.ok, ok)
+			}
+			if ok && math.Round(float64(got)) != float64(test.expected) {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestWindChill(t *testing.T) {
+	// reference values from the National Weather Service's wind chill chart, in whole degrees
+	tests := []struct {
+		name        string
+		temperature float32
+		windSpeed   float32
+		expected    float32
+		ok          bool
+	}{
+		{"too-warm", 51, 20, 0, false},
+		{"calm", 20, 3, 0, false},
+		{"chilly", 40, 5, 36, true},
+		{"cold", 30, 10, 21, true},
+		{"frigid", 0, 15, -19, true},
+		{"dangerous", -10, 30, -39, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := domain.WindChill(test.temperature, test.windSpeed)
+			if ok != test.ok {
+				t.Fatalf("expected '%v' got '%v'", test.ok, ok)
+			}
+			if ok && math.Round(float64(got)) != float64(test.expected) {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestBeaufort(t *testing.T) {
+	tests := []struct {
+		windSpeed   float32
+		expected    int
+		description string
+	}{
+		{0, 0, "Calm"},
+		{1, 0, "Calm"},
+		{2, 1, "Light air"},
+		{5, 2, "Light breeze"},
+		{10, 3, "Gentle breeze"},
+		{15, 4, "Moderate breeze"},
+		{20, 5, "Fresh breeze"},
+		{28, 6, "Strong breeze"},
+		{35, 7, "Near gale"},
+		{42, 8, "Gale"},
+		{50, 9, "Strong gale"},
+		{60, 10, "Storm"},
+		{70, 11, "Violent storm"},
+		{74, 12, "Hurricane force"},
+		{150, 12, "Hurricane force"},
+	}
+	for _, test := range tests {
+		got, description := domain.Beaufort(test.windSpeed)
+		if got != test.expected || description != test.description {
+			t.Errorf("%v mph: expected '%v %v' got '%v %v'", test.windSpeed, test.expected, test.description, got, description)
+		}
+	}
+}
+
+func TestCardinalDirection(t *testing.T) {
+	tests := []struct {
+		degrees  float32
+		expected string
+	}{
+		{0, "N"},
+		{11.2, "N"},
+		{11.3, "NNE"},
+		{45, "NE"},
+		{90, "E"},
+		{135, "SE"},
+		{180, "S"},
+		{202.5, "SSW"},
+		{270, "W"},
+		{348.7, "NNW"},
+		{348.8, "N"},
+		{360, "N"},
+		{-90, "W"},
+	}
+	for _, test := range tests {
+		if got := domain.CardinalDirection(test.degrees); got != test.expected {
+			t.Errorf("%v: expected '%v' got '%v'", test.degrees, test.expected, got)
+		}
+	}
+}
+
+func TestClassifyVisibility(t *testing.T) {
+	tests := []struct {
+		visibility float32
+		expected   domain.VisibilityCategory
+	}{
+		{200, domain.VisibilityVeryPoor},
+		{999, domain.VisibilityVeryPoor},
+		{1000, domain.VisibilityPoor},
+		{3999, domain.VisibilityPoor},
+		{4000, domain.VisibilityModerate},
+		{10000, domain.VisibilityGood},
+		{20000, domain.VisibilityVeryGood},
+		{40000, domain.VisibilityExcellent},
+	}
+	for _, test := range tests {
+		if got := domain.ClassifyVisibility(test.visibility); got != test.expected {
+			t.Errorf("%v: expected '%v' got '%v'", test.visibility, test.expected, got)
+		}
+	}
+}
+
+func TestClassifyPrecipitation(t *testing.T) {
+	tests := []struct {
+		rate     float32
+		expected domain.PrecipitationIntensity
+	}{
+		{0, domain.PrecipitationNone},
+		{0.1, domain.PrecipitationLight},
+		{1.99, domain.PrecipitationLight},
+		{2, domain.PrecipitationModerate},
+		{10, domain.PrecipitationHeavy},
+		{50, domain.PrecipitationViolent},
+	}
+	for _, test := range tests {
+		if got := domain.ClassifyPrecipitation(test.rate); got != test.expected {
+			t.Errorf("%v: expected '%v' got '%v'", test.rate, test.expected, got)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	clearSky, misty := float32(10000), float32(3000)
+	tests := []struct {
+		name        string
+		temperature float32
+		conditions  domain.Conditions
+		heatIndex   bool
+		windChill   bool
+		direction   string
+		visibility  domain.VisibilityCategory
+	}{
+		{"hot", 96, domain.Conditions{Humidity: 65, WindSpeed: 10, WindDegrees: 200, Visibility: &clearSky}, true, false, "SSW", domain.VisibilityGood},
+		{"cold", 30, domain.Conditions{Humidity: 80, WindSpeed: 10, WindDegrees: 315, Visibility: &misty, Precipitation: 3}, false, true, "NW", domain.VisibilityPoor},
+		{"calm", 60, domain.Conditions{Humidity: 50, WindDegrees: 90}, false, false, "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := domain.Derive(test.temperature, test.conditions)
+			if (got.HeatIndex != nil) != test.heatIndex || (got.WindChill != nil) != test.windChill {
+				t.Errorf("expected heat index '%v' and wind chill '%v' got '%v' and '%v'", test.heatIndex, test.windChill, got.HeatIndex, got.WindChill)
+			}
+			if got.WindDirection != test.direction {
+				t.Errorf("expected '%v' got '%v'", test.direction, got.WindDirection)
+			}
+			if got.Visibility != test.visibility {
+				t.Errorf("expected '%v' got '%v'", test.visibility, got.Visibility)
+			}
+			if got.DewPoint == nil {
+				t.Errorf("expected a dew point")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/broganross/weather-exercise/i18n"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrNoHistory     = errors.New("no weather history")
+	ErrFutureHistory = errors.New("history is only for the past")
+)
+
+// HistorySource is a provider of past weather
+type HistorySource interface {
+	// GetHistoryByCoords returns the weather observed nearest at
+	GetHistoryByCoords(ctx context.Context, latitude float32, longitude float32, at time.Time) (*RepoWeather, error)
+}
+
+// Archive keeps observations so past weather can be served without asking a provider
+type Archive interface {
+	// Record keeps an observation of the weather asked for at the coordinates
+	Record(ctx context.Context, latitude float32, longitude float32, rw *RepoWeather) error
+	// Nearest finds the observation closest to at, asked for within gridSize degrees of the coordinates and within
+	// window of at.  It returns ErrNoHistory when there isn't one.
+	Nearest(ctx context.Context, latitude float32, longitude float32, gridSize float32, at time.Time, window time.Duration) (*RepoWeather, error)
+}
+
+// Historian is the business logic for past weather
+type Historian interface {
+	HistoryAt(ctx context.Context, lat float32, lon float32, at time.Time) (*Weather, error)
+}
+
+// ArchivingRepo records every current observation fetched through it in the default language.  Observations fetched
+// in another language aren't recorded, as the provider translates their place name and history would keep it.
+// Recording is best effort, a failure is logged rather than failing the weather.
+type ArchivingRepo struct {
+	Next    Repo
+	Archive Archive
+}
+
+func (ar *ArchivingRepo) GetByCoords(ctx context.Context, latitude float32, longitude float32) (*RepoWeather, error) {
+	rw, err := ar.Next.GetByCoords(ctx, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+	if i18n.FromContext(ctx) != i18n.Default {
+		return rw, nil
+	}
+	if err := ar.Archive.Record(ctx, latitude, longitude, rw); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("archiving observation")
+	}
+	return rw, nil
+}
+
+// GetForecastByCoords isn't archived, forecasts aren't observations
+func (ar *ArchivingRepo) GetForecastByCoords(ctx context.Context, latitude float32, longitude float32) ([]RepoWeather, error) {
+	return ar.Next.GetForecastByCoords(ctx, latitude, longitude)
+}
+
+func (ar *ArchivingRepo) GetAlertsByCoords(ctx context.Context, latitude float32, longitude float32) ([]Alert, error) {
+	return ar.Next.GetAlertsByCoords(ctx, latitude, longitude)
+}
+
+// HistoryService finds past weather in the archive, and otherwise from the provider
+type HistoryService struct {
+	// Archive is checked first, when there is one
+	Archive Archive
+	// Source is asked when the archive doesn't have it, when there is one
+	Source HistorySource
+	// GridSize is how far apart, in degrees, coordinates can be and still share archived observations
+	GridSize float32
+	// Window is how far from the time asked for an archived observation can be
+	Window time.Duration
+}
+
+// HistoryAt finds the weather at a latitude and longitude at a past time.  Archived observations are marked as cached.
+// Past weather from the provider is archived too, so it's only asked once.
+func (hs *HistoryService) HistoryAt(ctx context.Context, lat float32, lon float32, at time.Time) (*Weather, error) {
+	ctx, span := tracer.Start(ctx, "domain.HistoryAt", trace.WithAttributes(
+		attribute.Float64("geo.latitude", float64(lat)),
+		attribute.Float64("geo.longitude", float64(lon)),
+		attribute.String("history.at", at.UTC().Format(time.RFC3339)),
+	))
+	defer span.End()
+	if at.After(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrFutureHistory, at.UTC().Format(time.RFC3339))
+	}
+	if hs.Archive != nil {
+		rw, err := hs.Archive.Nearest(ctx, lat, lon, hs.GridSize, at, hs.Window)
+		switch {
+		case err == nil:
+			rw.Source.Cached = true
+			span.SetAttributes(attribute.Bool("history.archived", true))
+			return fromRepo(rw), nil
+		case !errors.Is(err, ErrNoHistory):
+			// the provider may still have it
+			span.RecordError(err)
+			log.Ctx(ctx).Warn().Err(err).Msg("searching the observation archive")
+		}
+	}
+	if hs.Source == nil {
+		return nil, ErrNoHistory
+	}
+	rw, err := hs.Source.GetHistoryByCoords(ctx, lat, lon, at)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting weather history")
+		return nil, fmt.Errorf("getting weather history by coordinates: %w", err)
+	}
+	if hs.Archive != nil {
+		if err := hs.Archive.Record(ctx, lat, lon, rw); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("archiving observation")
+		}
+	}
+	return fromRepo(rw), nil
+}
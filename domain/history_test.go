@@ -0,0 +1,146 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+)
+
+type mockArchive struct {
+	recorded []domain.RepoWeather
+	stored   *domain.RepoWeather
+	err      error
+}
+
+func (ma *mockArchive) Record(ctx context.Context, lat float32, lon float32, rw *domain.RepoWeather) error {
+	if ma.err != nil {
+		return ma.err
+	}
+	ma.recorded = append(ma.recorded, *rw)
+	return nil
+}
+
+func (ma *mockArchive) Nearest(ctx context.Context, lat float32, lon float32, gridSize float32, at time.Time, window time.Duration) (*domain.RepoWeather, error) {
+	if ma.err != nil {
+		return nil, ma.err
+	}
+	if ma.stored == nil {
+		return nil, domain.ErrNoHistory
+	}
+	rw := *ma.stored
+	return &rw, nil
+}
+
+type mockHistorySource struct {
+	resp  *domain.RepoWeather
+	err   error
+	calls int
+}
+
+func (mhs *mockHistorySource) GetHistoryByCoords(ctx context.Context, lat float32, lon float32, at time.Time) (*domain.RepoWeather, error) {
+	mhs.calls++
+	if mhs.err != nil {
+		return nil, mhs.err
+	}
+	return mhs.resp, nil
+}
+
+func TestArchivingRepo_GetByCoords(t *testing.T) {
+	observed := &domain.RepoWeather{States: []string{"rain"}, Temperature: 283, ObservedAt: time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name     string
+		language string
+		archive  *mockArchive
+		repoErr  error
+		recorded int
+	}{
+		{"recorded", "", &mockArchive{}, nil, 1},
+		{"default-language", "en", &mockArchive{}, nil, 1},
+		{"other-language", "de", &mockArchive{}, nil, 0},
+		{"archive-failing", "", &mockArchive{err: errors.New("disk full")}, nil, 0},
+		{"repo-failing", "", &mockArchive{}, errors.New("boom"), 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ar := domain.ArchivingRepo{
+				Next: &mockWeatherRepo{responses: map[string]mockWeatherRepoResponse{
+					"1.0000:2.0000": {resp: observed, err: test.repoErr},
+				}},
+				Archive: test.archive,
+			}
+			ctx := context.Background()
+			if test.language != "" {
+				ctx = i18n.NewContext(ctx, test.language)
+			}
+			got, err := ar.GetByCoords(ctx, 1, 2)
+			if !errors.Is(err, test.repoErr) {
+				t.Fatalf("expected '%v' got '%v'", test.repoErr, err)
+			}
+			if err == nil && got != observed {
+				t.Errorf("expected '%v' got '%v'", observed, got)
+			}
+			if len(test.archive.recorded) != test.recorded {
+				t.Errorf("expected '%v' recorded got '%v'", test.recorded, len(test.archive.recorded))
+			}
+		})
+	}
+}
+
+func TestHistoryService_HistoryAt(t *testing.T) {
+	at := time.Date(2024, 3, 5, 14, 10, 0, 0, time.UTC)
+	archived := &domain.RepoWeather{States: []string{"rain"}, Temperature: 283, ObservedAt: at.Add(-10 * time.Minute), Source: domain.Source{Provider: "openweather"}}
+	provided := &domain.RepoWeather{States: []string{"snow"}, Temperature: 272, ObservedAt: at, Source: domain.Source{Provider: "openweather"}}
+	tests := []struct {
+		name     string
+		at       time.Time
+		archive  *mockArchive
+		source   *mockHistorySource
+		expected string
+		cached   bool
+		calls    int
+		recorded int
+		err      error
+	}{
+		{"archived", at, &mockArchive{stored: archived}, &mockHistorySource{resp: provided}, "rain", true, 0, 0, nil},
+		{"provided", at, &mockArchive{}, &mockHistorySource{resp: provided}, "snow", false, 1, 1, nil},
+		{"archive-failing", at, &mockArchive{err: errors.New("disk full")}, &mockHistorySource{resp: provided}, "snow", false, 1, 0, nil},
+		{"no-archive", at, nil, &mockHistorySource{resp: provided}, "snow", false, 1, 0, nil},
+		{"no-source", at, &mockArchive{}, nil, "", false, 0, 0, domain.ErrNoHistory},
+		{"source-failing", at, &mockArchive{}, &mockHistorySource{err: errNotFound}, "", false, 1, 0, errNotFound},
+		{"future", time.Now().Add(time.Hour), &mockArchive{}, &mockHistorySource{resp: provided}, "", false, 0, 0, domain.ErrFutureHistory},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hs := domain.HistoryService{GridSize: 0.01, Window: time.Hour}
+			if test.archive != nil {
+				hs.Archive = test.archive
+			}
+			if test.source != nil {
+				hs.Source = test.source
+			}
+			got, err := hs.HistoryAt(context.Background(), 1, 2, test.at)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if test.source != nil && test.source.calls != test.calls {
+				t.Errorf("expected '%v' provider calls got '%v'", test.calls, test.source.calls)
+			}
+			if test.archive != nil && len(test.archive.recorded) != test.recorded {
+				t.Errorf("expected '%v' recorded got '%v'", test.recorded, len(test.archive.recorded))
+			}
+			if err != nil {
+				return
+			}
+			if got.States[0] != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got.States[0])
+			}
+			if got.Source.Cached != test.cached {
+				t.Errorf("expected cached '%v' got '%v'", test.cached, got.Source.Cached)
+			}
+		})
+	}
+}
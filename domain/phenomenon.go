@@ -0,0 +1,184 @@
+package domain
+
+// PhenomenonCategory is the broad kind of weather a phenomenon is
+type PhenomenonCategory string
+
+const (
+	PhenomenonClear        PhenomenonCategory = "clear"
+	PhenomenonClouds       PhenomenonCategory = "clouds"
+	PhenomenonMist         PhenomenonCategory = "mist"
+	PhenomenonHaze         PhenomenonCategory = "haze"
+	PhenomenonSmoke        PhenomenonCategory = "smoke"
+	PhenomenonDust         PhenomenonCategory = "dust"
+	PhenomenonSand         PhenomenonCategory = "sand"
+	PhenomenonDrizzle      PhenomenonCategory = "drizzle"
+	PhenomenonFog          PhenomenonCategory = "fog"
+	PhenomenonRain         PhenomenonCategory = "rain"
+	PhenomenonAsh          PhenomenonCategory = "ash"
+	PhenomenonSnow         PhenomenonCategory = "snow"
+	PhenomenonThunderstorm PhenomenonCategory = "thunderstorm"
+	PhenomenonSquall       PhenomenonCategory = "squall"
+	PhenomenonTornado      PhenomenonCategory = "tornado"
+)
+
+// severities ranks the phenomena categories from harmless to dangerous
+var severities = map[PhenomenonCategory]int{
+	PhenomenonClear:        0,
+	PhenomenonClouds:       1,
+	PhenomenonMist:         2,
+	PhenomenonHaze:         2,
+	PhenomenonSmoke:        3,
+	PhenomenonDust:         3,
+	PhenomenonSand:         3,
+	PhenomenonDrizzle:      4,
+	PhenomenonFog:          4,
+	PhenomenonRain:         5,
+	PhenomenonAsh:          6,
+	PhenomenonSnow:         7,
+	PhenomenonThunderstorm: 8,
+	PhenomenonSquall:       9,
+	PhenomenonTornado:      10,
+}
+
+// PhenomenonIntensity is how strong a phenomenon is, none for those that aren't graded
+type PhenomenonIntensity string
+
+const (
+	IntensityNone     PhenomenonIntensity = "none"
+	IntensityLight    PhenomenonIntensity = "light"
+	IntensityModerate PhenomenonIntensity = "moderate"
+	IntensityHeavy    PhenomenonIntensity = "heavy"
+	IntensityExtreme  PhenomenonIntensity = "extreme"
+)
+
+// Rank orders intensities, higher is stronger
+func (i PhenomenonIntensity) Rank() int {
+	switch i {
+	case IntensityExtreme:
+		return 4
+	case IntensityHeavy:
+		return 3
+	case IntensityModerate:
+		return 2
+	case IntensityLight:
+		return 1
+	}
+	return 0
+}
+
+// Phenomenon is a weather condition in the provider independent taxonomy.  Codes are stable,
+// providers map their own codes onto them.
+type Phenomenon struct {
+	Code        string
+	Category    PhenomenonCategory
+	Intensity   PhenomenonIntensity
+	Description string
+	// DayIcon and NightIcon are keys for the icon to show, the same when it doesn't matter
+	DayIcon   string
+	NightIcon string
+}
+
+// Severity is the rank of the phenomenon's category, higher is worse
+func (p Phenomenon) Severity() int {
+	return severities[p.Category]
+}
+
+// Icon is the icon key for day or night
+func (p Phenomenon) Icon(daytime bool) string {
+	if daytime {
+		return p.DayIcon
+	}
+	return p.NightIcon
+}
+
+var phenomena = indexPhenomena([]Phenomenon{
+	{"thunderstorm_light_rain", PhenomenonThunderstorm, IntensityLight, "thunderstorm with light rain", "thunderstorm", "thunderstorm"},
+	{"thunderstorm_rain", PhenomenonThunderstorm, IntensityModerate, "thunderstorm with rain", "thunderstorm", "thunderstorm"},
+	{"thunderstorm_heavy_rain", PhenomenonThunderstorm, IntensityHeavy, "thunderstorm with heavy rain", "thunderstorm", "thunderstorm"},
+	{"light_thunderstorm", PhenomenonThunderstorm, IntensityLight, "light thunderstorm", "thunderstorm", "thunderstorm"},
+	{"thunderstorm", PhenomenonThunderstorm, IntensityModerate, "thunderstorm", "thunderstorm", "thunderstorm"},
+	{"heavy_thunderstorm", PhenomenonThunderstorm, IntensityHeavy, "heavy thunderstorm", "thunderstorm", "thunderstorm"},
+	{"ragged_thunderstorm", PhenomenonThunderstorm, IntensityModerate, "ragged thunderstorm", "thunderstorm", "thunderstorm"},
+	{"thunderstorm_light_drizzle", PhenomenonThunderstorm, IntensityLight, "thunderstorm with light drizzle", "thunderstorm", "thunderstorm"},
+	{"thunderstorm_drizzle", PhenomenonThunderstorm, IntensityModerate, "thunderstorm with drizzle", "thunderstorm", "thunderstorm"},
+	{"thunderstorm_heavy_drizzle", PhenomenonThunderstorm, IntensityHeavy, "thunderstorm with heavy drizzle", "thunderstorm", "thunderstorm"},
+
+	{"light_drizzle", PhenomenonDrizzle, IntensityLight, "light drizzle", "drizzle", "drizzle"},
+	{"drizzle", PhenomenonDrizzle, IntensityModerate, "drizzle", "drizzle", "drizzle"},
+	{"heavy_drizzle", PhenomenonDrizzle, IntensityHeavy, "heavy drizzle", "drizzle", "drizzle"},
+	{"light_drizzle_rain", PhenomenonDrizzle, IntensityLight, "light drizzle and rain", "drizzle", "drizzle"},
+	{"drizzle_rain", PhenomenonDrizzle, IntensityModerate, "drizzle and rain", "drizzle", "drizzle"},
+	{"heavy_drizzle_rain", PhenomenonDrizzle, IntensityHeavy, "heavy drizzle and rain", "drizzle", "drizzle"},
+	{"drizzle_rain_showers", PhenomenonDrizzle, IntensityModerate, "rain showers and drizzle", "showers-day", "showers-night"},
+	{"heavy_drizzle_rain_showers", PhenomenonDrizzle, IntensityHeavy, "heavy rain showers and drizzle", "showers-day", "showers-night"},
+	{"drizzle_showers", PhenomenonDrizzle, IntensityModerate, "drizzle showers", "showers-day", "showers-night"},
+
+	{"light_rain", PhenomenonRain, IntensityLight, "light rain", "rain", "rain"},
+	{"moderate_rain", PhenomenonRain, IntensityModerate, "moderate rain", "rain", "rain"},
+	{"heavy_rain", PhenomenonRain, IntensityHeavy, "heavy rain", "heavy-rain", "heavy-rain"},
+	{"very_heavy_rain", PhenomenonRain, IntensityExtreme, "very heavy rain", "heavy-rain", "heavy-rain"},
+	{"extreme_rain", PhenomenonRain, IntensityExtreme, "extreme rain", "heavy-rain", "heavy-rain"},
+	{"freezing_rain", PhenomenonRain, IntensityModerate, "freezing rain", "freezing-rain", "freezing-rain"},
+	{"light_rain_showers", PhenomenonRain, IntensityLight, "light rain showers", "showers-day", "showers-night"},
+	{"rain_showers", PhenomenonRain, IntensityModerate, "rain showers", "showers-day", "showers-night"},
+	{"heavy_rain_showers", PhenomenonRain, IntensityHeavy, "heavy rain showers", "showers-day", "showers-night"},
+	{"ragged_rain_showers", PhenomenonRain, IntensityModerate, "ragged rain showers", "showers-day", "showers-night"},
+
+	{"light_snow", PhenomenonSnow, IntensityLight, "light snow", "snow", "snow"},
+	{"snow", PhenomenonSnow, IntensityModerate, "snow", "snow", "snow"},
+	{"heavy_snow", PhenomenonSnow, IntensityHeavy, "heavy snow", "heavy-snow", "heavy-snow"},
+	{"sleet", PhenomenonSnow, IntensityModerate, "sleet", "sleet", "sleet"},
+	{"light_sleet_showers", PhenomenonSnow, IntensityLight, "light sleet showers", "sleet", "sleet"},
+	{"sleet_showers", PhenomenonSnow, IntensityModerate, "sleet showers", "sleet", "sleet"},
+	{"light_rain_snow", PhenomenonSnow, IntensityLight, "light rain and snow", "sleet", "sleet"},
+	{"rain_snow", PhenomenonSnow, IntensityModerate, "rain and snow", "sleet", "sleet"},
+	{"light_snow_showers", PhenomenonSnow, IntensityLight, "light snow showers", "snow-showers-day", "snow-showers-night"},
+	{"snow_showers", PhenomenonSnow, IntensityModerate, "snow showers", "snow-showers-day", "snow-showers-night"},
+	{"heavy_snow_showers", PhenomenonSnow, IntensityHeavy, "heavy snow showers", "snow-showers-day", "snow-showers-night"},
+
+	{"mist", PhenomenonMist, IntensityNone, "mist", "mist", "mist"},
+	{"smoke", PhenomenonSmoke, IntensityNone, "smoke", "smoke", "smoke"},
+	{"haze", PhenomenonHaze, IntensityNone, "haze", "haze-day", "haze-night"},
+	{"dust_whirls", PhenomenonDust, IntensityNone, "sand and dust whirls", "dust", "dust"},
+	{"fog", PhenomenonFog, IntensityNone, "fog", "fog", "fog"},
+	{"sand", PhenomenonSand, IntensityNone, "sand", "dust", "dust"},
+	{"dust", PhenomenonDust, IntensityNone, "dust", "dust", "dust"},
+	{"volcanic_ash", PhenomenonAsh, IntensityNone, "volcanic ash", "ash", "ash"},
+	{"squalls", PhenomenonSquall, IntensityNone, "squalls", "wind", "wind"},
+	{"tornado", PhenomenonTornado, IntensityNone, "tornado", "tornado", "tornado"},
+
+	{"clear", PhenomenonClear, IntensityNone, "clear sky", "clear-day", "clear-night"},
+	{"few_clouds", PhenomenonClouds, IntensityNone, "few clouds", "partly-cloudy-day", "partly-cloudy-night"},
+	{"scattered_clouds", PhenomenonClouds, IntensityNone, "scattered clouds", "partly-cloudy-day", "partly-cloudy-night"},
+	{"broken_clouds", PhenomenonClouds, IntensityNone, "broken clouds", "mostly-cloudy-day", "mostly-cloudy-night"},
+	{"overcast_clouds", PhenomenonClouds, IntensityNone, "overcast clouds", "cloudy", "cloudy"},
+})
+
+func indexPhenomena(ps []Phenomenon) map[string]Phenomenon {
+	index := make(map[string]Phenomenon, len(ps))
+	for _, p := range ps {
+		index[p.Code] = p
+	}
+	return index
+}
+
+// LookupPhenomenon finds the phenomenon with a code, false if there isn't one
+func LookupPhenomenon(code string) (Phenomenon, bool) {
+	p, ok := phenomena[code]
+	return p, ok
+}
+
+// PrimaryPhenomenon is the most severe of the phenomena, the most intense of those equally severe,
+// and the first of those the provider listed.  It's false when there aren't any.
+func PrimaryPhenomenon(ps []Phenomenon) (Phenomenon, bool) {
+	if len(ps) == 0 {
+		return Phenomenon{}, false
+	}
+	primary := ps[0]
+	for _, p := range ps[1:] {
+		if s, worst := p.Severity(), primary.Severity(); s > worst || s == worst && p.Intensity.Rank() > primary.Intensity.Rank() {
+			primary = p
+		}
+	}
+	return primary, true
+}
@@ -0,0 +1,86 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+func lookupPhenomena(t *testing.T, codes ...string) []domain.Phenomenon {
+	t.Helper()
+	ps := make([]domain.Phenomenon, len(codes))
+	for i, code := range codes {
+		p, ok := domain.LookupPhenomenon(code)
+		if !ok {
+			t.Fatalf("expected phenomenon '%v'", code)
+		}
+		ps[i] = p
+	}
+	return ps
+}
+
+func TestLookupPhenomenon(t *testing.T) {
+	tests := []struct {
+		code      string
+		category  domain.PhenomenonCategory
+		intensity domain.PhenomenonIntensity
+		severity  int
+		dayIcon   string
+		nightIcon string
+	}{
+		{"clear", domain.PhenomenonClear, domain.IntensityNone, 0, "clear-day", "clear-night"},
+		{"broken_clouds", domain.PhenomenonClouds, domain.IntensityNone, 1, "mostly-cloudy-day", "mostly-cloudy-night"},
+		{"fog", domain.PhenomenonFog, domain.IntensityNone, 4, "fog", "fog"},
+		{"heavy_rain", domain.PhenomenonRain, domain.IntensityHeavy, 5, "heavy-rain", "heavy-rain"},
+		{"light_rain_showers", domain.PhenomenonRain, domain.IntensityLight, 5, "showers-day", "showers-night"},
+		{"sleet", domain.PhenomenonSnow, domain.IntensityModerate, 7, "sleet", "sleet"},
+		{"thunderstorm_heavy_rain", domain.PhenomenonThunderstorm, domain.IntensityHeavy, 8, "thunderstorm", "thunderstorm"},
+		{"tornado", domain.PhenomenonTornado, domain.IntensityNone, 10, "tornado", "tornado"},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			got, ok := domain.LookupPhenomenon(test.code)
+			if !ok {
+				t.Fatalf("expected '%v' to be found", test.code)
+			}
+			if got.Category != test.category || got.Intensity != test.intensity || got.Severity() != test.severity {
+				t.Errorf("expected '%v %v %v' got '%v %v %v'", test.category, test.intensity, test.severity, got.Category, got.Intensity, got.Severity())
+			}
+			if got.Icon(true) != test.dayIcon || got.Icon(false) != test.nightIcon {
+				t.Errorf("expected '%v/%v' got '%v/%v'", test.dayIcon, test.nightIcon, got.Icon(true), got.Icon(false))
+			}
+			if got.Description == "" {
+				t.Errorf("expected a description")
+			}
+		})
+	}
+	if _, ok := domain.LookupPhenomenon("frogs"); ok {
+		t.Errorf("expected an unknown code not to be found")
+	}
+}
+
+func TestPrimaryPhenomenon(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []string
+		expected string
+		ok       bool
+	}{
+		{"none", nil, "", false},
+		{"one", []string{"mist"}, "mist", true},
+		{"most-severe", []string{"light_rain", "mist", "thunderstorm"}, "thunderstorm", true},
+		{"most-intense", []string{"light_rain", "heavy_rain_showers", "moderate_rain"}, "heavy_rain_showers", true},
+		{"first-of-equals", []string{"rain_showers", "moderate_rain"}, "rain_showers", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := domain.PrimaryPhenomenon(lookupPhenomena(t, test.codes...))
+			if ok != test.ok {
+				t.Fatalf("expected '%v' got '%v'", test.ok, ok)
+			}
+			if got.Code != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got.Code)
+			}
+		})
+	}
+}
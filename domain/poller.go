@@ -0,0 +1,234 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrPollerClosed = errors.New("poller closed")
+
+// Update is a change in the current weather at a polled location
+type Update struct {
+	// Increases with every update from the poller, across all locations
+	ID uint64
+	// The snapped location that was polled
+	Coords  Coords
+	Weather *Weather
+	Err     error
+	At      time.Time
+}
+
+// Poller polls the current weather at subscribed locations, and publishes an update whenever the reading changes.
+// Coordinates are snapped to a grid, so every subscriber in the same cell shares one upstream poll.
+// A location is polled from its first subscription until its last one is closed.
+type Poller struct {
+	Service  Service
+	Interval time.Duration
+	// Grid size in degrees, zero disables snapping
+	GridSize float32
+	// Updates buffered for each subscriber, slow subscribers skip the oldest
+	Buffer int
+	// Updates kept per location for resuming subscriptions
+	History int
+
+	mu        sync.Mutex
+	seq       uint64
+	locations map[Coords]*polledLocation
+	closed    bool
+}
+
+type polledLocation struct {
+	coords      Coords
+	subscribers map[*Subscription]bool
+	history     []Update
+	cancel      context.CancelFunc
+}
+
+// Subscription receives the updates for one location until it's closed
+type Subscription struct {
+	// The snapped location
+	Coords  Coords
+	updates chan Update
+	poller  *Poller
+	loc     *polledLocation
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// Subscribe starts receiving updates for the coordinates.
+// The latest reading is sent straight away, unless lastID is the ID of a previous update,
+// in which case only the updates since it are.
+func (p *Poller) Subscribe(c Coords, lastID uint64) (*Subscription, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrPollerClosed
+	}
+	if p.locations == nil {
+		p.locations = map[Coords]*polledLocation{}
+	}
+	snapped := Snap(c, p.GridSize)
+	loc, ok := p.locations[snapped]
+	if !ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		loc = &polledLocation{
+			coords:      snapped,
+			subscribers: map[*Subscription]bool{},
+			cancel:      cancel,
+		}
+		p.locations[snapped] = loc
+		go p.poll(ctx, loc)
+	}
+	sub := &Subscription{
+		Coords:  snapped,
+		updates: make(chan Update, max(p.Buffer, 1)),
+		poller:  p,
+		loc:     loc,
+	}
+	loc.subscribers[sub] = true
+	for _, u := range replay(loc.history, lastID) {
+		sub.send(u)
+	}
+	return sub, nil
+}
+
+// replay picks the updates a subscriber has missed since lastID
+func replay(history []Update, lastID uint64) []Update {
+	if len(history) == 0 {
+		return nil
+	}
+	latest := history[len(history)-1]
+	switch {
+	case lastID == latest.ID:
+		return nil
+	case lastID == 0 || lastID > latest.ID:
+		// new, or from before a restart
+		return []Update{latest}
+	}
+	for i, u := range history {
+		if u.ID > lastID {
+			return history[i:]
+		}
+	}
+	return nil
+}
+
+// Close stops polling every location, and closes all the subscriptions
+func (p *Poller) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for _, loc := range p.locations {
+		loc.cancel()
+		for sub := range loc.subscribers {
+			sub.closed = true
+			close(sub.updates)
+		}
+		loc.subscribers = nil
+	}
+	p.locations = nil
+}
+
+func (p *Poller) poll(ctx context.Context, loc *polledLocation) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		w, err := p.Service.CurrentIn(ctx, loc.coords.Latitude, loc.coords.Longitude)
+		if ctx.Err() != nil {
+			return
+		}
+		p.publish(loc, w, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publish sends the reading to the location's subscribers, if it's changed since the last one
+func (p *Poller) publish(loc *polledLocation, w *Weather, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// a poll can finish as the poller's closed, after its subscriptions are
+	if p.closed {
+		return
+	}
+	if len(loc.history) > 0 && !changed(loc.history[len(loc.history)-1], w, err) {
+		return
+	}
+	p.seq++
+	u := Update{
+		ID:      p.seq,
+		Coords:  loc.coords,
+		Weather: w,
+		Err:     err,
+		At:      time.Now().UTC(),
+	}
+	loc.history = append(loc.history, u)
+	if over := len(loc.history) - max(p.History, 1); over > 0 {
+		loc.history = slices.Delete(loc.history, 0, over)
+	}
+	for sub := range loc.subscribers {
+		sub.send(u)
+	}
+}
+
+// changed reports whether a reading differs from the last update.
+// Only the conditions and temperature count, not when they were observed.
+func changed(last Update, w *Weather, err error) bool {
+	if err != nil || last.Err != nil {
+		return err == nil || last.Err == nil || err.Error() != last.Err.Error()
+	}
+	return last.Weather.Temperature != w.Temperature ||
+		last.Weather.Degrees != w.Degrees ||
+		!slices.Equal(last.Weather.States, w.States)
+}
+
+// Updates is closed when the subscription, or the poller, is closed
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+// Dropped is the number of updates skipped because the subscriber fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close stops receiving updates, and stops polling the location if it was the last subscriber
+func (s *Subscription) Close() {
+	p := s.poller
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.updates)
+	delete(s.loc.subscribers, s)
+	if len(s.loc.subscribers) == 0 {
+		s.loc.cancel()
+		delete(p.locations, s.loc.coords)
+	}
+}
+
+// send queues an update without blocking the poller, dropping the oldest queued update if the buffer is full.
+// Callers hold the poller's lock.
+func (s *Subscription) send(u Update) {
+	for {
+		select {
+		case s.updates <- u:
+			return
+		default:
+		}
+		select {
+		case <-s.updates:
+			s.dropped.Add(1)
+		default:
+		}
+	}
+}
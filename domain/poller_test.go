@@ -0,0 +1,202 @@
+package domain_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+// changingService reports whatever degrees it's been set to
+type changingService struct {
+	mu      sync.Mutex
+	degrees float32
+	calls   int
+}
+
+func (cs *changingService) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.calls++
+	return &domain.Weather{States: []string{"Clear"}, Degrees: cs.degrees}, nil
+}
+
+func (cs *changingService) set(degrees float32) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.degrees = degrees
+}
+
+func (cs *changingService) callCount() int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.calls
+}
+
+func next(t *testing.T, sub *domain.Subscription) domain.Update {
+	t.Helper()
+	select {
+	case u, ok := <-sub.Updates():
+		if !ok {
+			t.Fatalf("subscription closed")
+		}
+		return u
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for an update")
+	}
+	return domain.Update{}
+}
+
+func TestPoller(t *testing.T) {
+	svc := &changingService{degrees: 50}
+	p := &domain.Poller{Service: svc, Interval: 10 * time.Millisecond, GridSize: 1, Buffer: 4, History: 4}
+	defer p.Close()
+
+	a, err := p.Subscribe(domain.Coords{Latitude: 10.1, Longitude: 20.1}, 0)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	first := next(t, a)
+	if first.Weather.Degrees != 50 {
+		t.Errorf("expected '%v' got '%v'", 50, first.Weather.Degrees)
+	}
+	// a second subscriber in the same cell gets the latest reading without another poll
+	calls := svc.callCount()
+	b, err := p.Subscribe(domain.Coords{Latitude: 10.4, Longitude: 20.4}, 0)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if u := next(t, b); u.ID != first.ID {
+		t.Errorf("expected '%v' got '%v'", first.ID, u.ID)
+	}
+	if b.Coords != a.Coords {
+		t.Errorf("expected '%v' got '%v'", a.Coords, b.Coords)
+	}
+
+	// unchanged readings aren't published
+	time.Sleep(50 * time.Millisecond)
+	if svc.callCount() <= calls {
+		t.Errorf("expected the location to keep being polled")
+	}
+	select {
+	case u := <-a.Updates():
+		t.Fatalf("expected no update got '%v'", u)
+	default:
+	}
+
+	svc.set(60)
+	second := next(t, a)
+	if second.Weather.Degrees != 60 || second.ID <= first.ID {
+		t.Errorf("expected a newer update of '%v' got '%v'", 60, second)
+	}
+	next(t, b)
+
+	// resuming replays only what was missed
+	c, err := p.Subscribe(domain.Coords{Latitude: 10.1, Longitude: 20.1}, first.ID)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if u := next(t, c); u.ID != second.ID {
+		t.Errorf("expected '%v' got '%v'", second.ID, u.ID)
+	}
+	d, err := p.Subscribe(domain.Coords{Latitude: 10.1, Longitude: 20.1}, second.ID)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	select {
+	case u := <-d.Updates():
+		t.Fatalf("expected no update got '%v'", u)
+	default:
+	}
+
+	// the last subscriber leaving stops the polling
+	for _, sub := range []*domain.Subscription{a, b, c, d} {
+		sub.Close()
+	}
+	time.Sleep(20 * time.Millisecond)
+	calls = svc.callCount()
+	time.Sleep(50 * time.Millisecond)
+	if svc.callCount() != calls {
+		t.Errorf("expected polling to stop after the last subscriber left")
+	}
+	if _, ok := <-a.Updates(); ok {
+		t.Errorf("expected closed subscriptions to be closed")
+	}
+}
+
+func TestPoller_SlowSubscriber(t *testing.T) {
+	svc := &changingService{degrees: 0}
+	p := &domain.Poller{Service: svc, Interval: 5 * time.Millisecond, Buffer: 2, History: 2}
+	sub, err := p.Subscribe(domain.Coords{Latitude: 1, Longitude: 1}, 0)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	for i := 1; i <= 10; i++ {
+		svc.set(float32(i))
+		time.Sleep(10 * time.Millisecond)
+	}
+	if sub.Dropped() == 0 {
+		t.Errorf("expected updates to be dropped for a slow subscriber")
+	}
+	var last domain.Update
+	for len(sub.Updates()) > 0 {
+		last = <-sub.Updates()
+	}
+	if last.Weather == nil || last.Weather.Degrees != 10 {
+		t.Errorf("expected the latest reading to be kept got '%v'", last)
+	}
+	p.Close()
+	if _, ok := <-sub.Updates(); ok {
+		t.Errorf("expected closing the poller to close subscriptions")
+	}
+	sub.Close()
+	if _, err := p.Subscribe(domain.Coords{}, 0); err != domain.ErrPollerClosed {
+		t.Errorf("expected '%v' got '%v'", domain.ErrPollerClosed, err)
+	}
+}
+
+// gatedService holds every lookup until it's released
+type gatedService struct {
+	called  chan struct{}
+	release chan struct{}
+}
+
+func (gs *gatedService) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	gs.called <- struct{}{}
+	<-gs.release
+	return &domain.Weather{States: []string{"Clear"}}, nil
+}
+
+func TestPoller_CloseWhilePolling(t *testing.T) {
+	const locations = 50
+	for i := 0; i < 100; i++ {
+		svc := &gatedService{called: make(chan struct{}, locations), release: make(chan struct{})}
+		p := &domain.Poller{Service: svc, Interval: time.Millisecond}
+		subs := make([]*domain.Subscription, locations)
+		for j := range subs {
+			sub, err := p.Subscribe(domain.Coords{Latitude: float32(j), Longitude: 1}, 0)
+			if err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			subs[j] = sub
+		}
+		for range subs {
+			<-svc.called
+		}
+		// the polls finish as the poller closes, which mustn't send on the closed subscriptions
+		done := make(chan struct{})
+		go func() {
+			p.Close()
+			close(done)
+		}()
+		close(svc.release)
+		<-done
+		for _, sub := range subs {
+			for range sub.Updates() {
+			}
+			sub.Close()
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/broganross/weather-exercise/geo"
+)
+
+var (
+	ErrInvalidRoute   = errors.New("invalid route")
+	ErrBeyondForecast = errors.New("beyond the forecast")
+)
+
+// RouteService reports the weather along a route, by sampling points along it at the time
+// they'll be reached.
+type RouteService struct {
+	Current  Service
+	Forecast Forecaster
+	// Default distance between samples in kilometres
+	Spacing float64
+	// Upper limit on the samples along one route, the spacing is widened to fit
+	MaxSamples int
+	// Maximum number of concurrent lookups
+	Concurrency int
+	// Grid size in degrees, samples in the same cell share a lookup
+	GridSize float32
+	// Samples reached within this long use the current weather rather than the forecast
+	CurrentWindow time.Duration
+}
+
+// RouteQuery is a journey along a line
+type RouteQuery struct {
+	Line      geo.LineString
+	Departure time.Time
+	// Average speed in kilometres per hour
+	SpeedKPH float64
+	// Distance between samples in kilometres, zero uses the service default
+	Spacing float64
+}
+
+// RouteSegment is a stretch of the route, with the weather at its midpoint when it's reached
+type RouteSegment struct {
+	Start geo.Point
+	End   geo.Point
+	// Distance along the route to the start of the segment, and the segment length, in kilometres
+	FromKM   float64
+	LengthKM float64
+	Sample   geo.Point
+	ETA      time.Time
+	// Whether the weather is forecast, rather than current
+	Forecast bool
+	Weather  *Weather
+	Err      error
+}
+
+// RouteWeather is the weather along a route
+type RouteWeather struct {
+	LengthKM  float64
+	Departure time.Time
+	Arrival   time.Time
+	Segments  []RouteSegment
+	// Index of the segment with the worst conditions, -1 when every lookup failed
+	Worst int
+}
+
+// routeLookup is one provider call shared by samples in the same grid cell
+type routeLookup struct {
+	forecast bool
+	cell     Coords
+}
+
+type routeLookupResult struct {
+	current  *Weather
+	forecast []Weather
+	err      error
+}
+
+// WeatherAlong samples the weather along the route.
+// Failed lookups are reported per segment, the error is only for the route as a whole.
+func (rs *RouteService) WeatherAlong(ctx context.Context, q RouteQuery) (*RouteWeather, error) {
+	if len(q.Line) < 2 {
+		return nil, fmt.Errorf("%w: expected at least two positions", ErrInvalidRoute)
+	}
+	if q.SpeedKPH <= 0 || math.IsInf(q.SpeedKPH, 0) || math.IsNaN(q.SpeedKPH) {
+		return nil, fmt.Errorf("%w: speed must be positive", ErrInvalidRoute)
+	}
+	spacing := q.Spacing
+	if spacing == 0 {
+		spacing = rs.Spacing
+	}
+	if spacing <= 0 || math.IsInf(spacing, 0) || math.IsNaN(spacing) {
+		return nil, fmt.Errorf("%w: spacing must be positive", ErrInvalidRoute)
+	}
+	length := q.Line.Length()
+	count := max(int(math.Ceil(length/spacing)), 1)
+	if rs.MaxSamples > 0 && count > rs.MaxSamples {
+		count = rs.MaxSamples
+		spacing = length / float64(count)
+	}
+	route := &RouteWeather{
+		LengthKM:  length,
+		Departure: q.Departure,
+		Arrival:   q.Departure.Add(travelTime(length, q.SpeedKPH)),
+		Segments:  make([]RouteSegment, count),
+		Worst:     -1,
+	}
+	currentUntil := time.Now().Add(rs.CurrentWindow)
+	lookups := map[routeLookup]*routeLookupResult{}
+	keys := make([]routeLookup, count)
+	for i := range route.Segments {
+		from := float64(i) * spacing
+		to := min(from+spacing, length)
+		mid := (from + to) / 2
+		sample := q.Line.At(mid)
+		eta := q.Departure.Add(travelTime(mid, q.SpeedKPH))
+		route.Segments[i] = RouteSegment{
+			Start:    q.Line.At(from),
+			End:      q.Line.At(to),
+			FromKM:   from,
+			LengthKM: to - from,
+			Sample:   sample,
+			ETA:      eta,
+			Forecast: eta.After(currentUntil),
+		}
+		key := routeLookup{
+			forecast: route.Segments[i].Forecast,
+			cell:     Snap(Coords{Latitude: float32(sample.Lat), Longitude: float32(sample.Lon)}, rs.GridSize),
+		}
+		keys[i] = key
+		if _, ok := lookups[key]; !ok {
+			lookups[key] = &routeLookupResult{}
+		}
+	}
+	rs.lookup(ctx, lookups)
+
+	worst := -1
+	for i := range route.Segments {
+		seg := &route.Segments[i]
+		result := lookups[keys[i]]
+		switch {
+		case result.err != nil:
+			seg.Err = result.err
+		case seg.Forecast:
+			seg.Weather, seg.Err = forecastAt(result.forecast, seg.ETA)
+		default:
+			seg.Weather = result.current
+		}
+		if seg.Err != nil {
+			continue
+		}
+		// with no phenomena the primary is the zero value, which ranks as harmless
+		primary, _ := PrimaryPhenomenon(seg.Weather.Phenomena)
+		if severity := primary.Severity(); severity > worst {
+			worst = severity
+			route.Worst = i
+		}
+	}
+	return route, nil
+}
+
+// lookup fetches the current weather, or forecast, for each of the lookups concurrently
+func (rs *RouteService) lookup(ctx context.Context, lookups map[routeLookup]*routeLookupResult) {
+	concurrency := max(rs.Concurrency, 1)
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for key, result := range lookups {
+		wg.Add(1)
+		go func(key routeLookup, result *routeLookupResult) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				result.err = ctx.Err()
+				return
+			}
+			if key.forecast {
+				result.forecast, result.err = rs.Forecast.ForecastIn(ctx, key.cell.Latitude, key.cell.Longitude)
+				return
+			}
+			result.current, result.err = rs.Current.CurrentIn(ctx, key.cell.Latitude, key.cell.Longitude)
+		}(key, result)
+	}
+	wg.Wait()
+}
+
+// forecastAt picks the forecast step covering a time.
+// Times after the last step are covered for one more step's length.
+func forecastAt(steps []Weather, at time.Time) (*Weather, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: no forecast", ErrBeyondForecast)
+	}
+	last := len(steps) - 1
+	if len(steps) > 1 {
+		step := steps[last].ObservedAt.Sub(steps[last-1].ObservedAt)
+		if at.After(steps[last].ObservedAt.Add(step)) {
+			return nil, fmt.Errorf("%w: forecast ends at %s", ErrBeyondForecast, steps[last].ObservedAt.Add(step).Format(time.RFC3339))
+		}
+	}
+	chosen := 0
+	for i, s := range steps {
+		if s.ObservedAt.After(at) {
+			break
+		}
+		chosen = i
+	}
+	w := steps[chosen]
+	return &w, nil
+}
+
+func travelTime(km float64, kph float64) time.Duration {
+	return time.Duration(km / kph * float64(time.Hour))
+}
@@ -0,0 +1,144 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+)
+
+func mustPhenomenon(code string) domain.Phenomenon {
+	p, ok := domain.LookupPhenomenon(code)
+	if !ok {
+		panic("unknown phenomenon " + code)
+	}
+	return p
+}
+
+// routeDomain reports cloudy current weather, and a fixed forecast
+type routeDomain struct {
+	forecast []domain.Weather
+}
+
+func (rd *routeDomain) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	return &domain.Weather{States: []string{"Clouds"}, Phenomena: []domain.Phenomenon{mustPhenomenon("overcast_clouds")}, Temperature: domain.TempMod}, nil
+}
+
+func (rd *routeDomain) ForecastIn(ctx context.Context, lat float32, lon float32) ([]domain.Weather, error) {
+	return rd.forecast, nil
+}
+
+func TestRouteService_WeatherAlong(t *testing.T) {
+	now := time.Now()
+	svc := &routeDomain{
+		forecast: []domain.Weather{
+			{States: []string{"Clear"}, Phenomena: []domain.Phenomenon{mustPhenomenon("clear")}, ObservedAt: now.Add(-time.Hour)},
+			{States: []string{"Thunderstorm"}, Phenomena: []domain.Phenomenon{mustPhenomenon("thunderstorm")}, ObservedAt: now.Add(90 * time.Minute)},
+			{States: []string{"Rain"}, Phenomena: []domain.Phenomenon{mustPhenomenon("moderate_rain")}, ObservedAt: now.Add(270 * time.Minute)},
+		},
+	}
+	rs := domain.RouteService{
+		Current:       svc,
+		Forecast:      svc,
+		Spacing:       50,
+		MaxSamples:    10,
+		Concurrency:   2,
+		CurrentWindow: time.Hour,
+	}
+	// roughly 222km due north
+	line := geo.LineString{{Lon: 0, Lat: 0}, {Lon: 0, Lat: 1}, {Lon: 0, Lat: 2}}
+	got, err := rs.WeatherAlong(context.Background(), domain.RouteQuery{Line: line, Departure: now, SpeedKPH: 100})
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	want := []struct {
+		forecast bool
+		state    string
+	}{
+		{false, "Clouds"},
+		{false, "Clouds"},
+		{true, "Clear"},
+		{true, "Thunderstorm"},
+		{true, "Thunderstorm"},
+	}
+	if len(got.Segments) != len(want) {
+		t.Fatalf("expected '%v' segments got '%v'", len(want), len(got.Segments))
+	}
+	for i, w := range want {
+		seg := got.Segments[i]
+		if seg.Err != nil {
+			t.Errorf("segment %d got unexpected error: '%v'", i, seg.Err)
+			continue
+		}
+		if seg.Forecast != w.forecast || seg.Weather.States[0] != w.state {
+			t.Errorf("segment %d expected '%v %v' got '%v %v'", i, w.forecast, w.state, seg.Forecast, seg.Weather.States[0])
+		}
+	}
+	if got.Worst != 3 {
+		t.Errorf("expected worst segment '%v' got '%v'", 3, got.Worst)
+	}
+	if got.Segments[4].End != line[2] {
+		t.Errorf("expected the last segment to end at '%v' got '%v'", line[2], got.Segments[4].End)
+	}
+	if d := got.Arrival.Sub(now); d < 133*time.Minute || d > 134*time.Minute {
+		t.Errorf("expected arrival after about 133 minutes got '%v'", d)
+	}
+}
+
+func TestRouteService_WeatherAlong_phenomena(t *testing.T) {
+	now := time.Now()
+	// the worst is ranked by the phenomena, not the provider's text
+	svc := &routeDomain{
+		forecast: []domain.Weather{
+			{States: []string{"Tornado"}, Phenomena: []domain.Phenomenon{mustPhenomenon("clear")}, ObservedAt: now},
+			{States: []string{"Clear"}, Phenomena: []domain.Phenomenon{mustPhenomenon("thunderstorm")}, ObservedAt: now.Add(90 * time.Minute)},
+		},
+	}
+	rs := domain.RouteService{Current: svc, Forecast: svc, Spacing: 50, MaxSamples: 10, CurrentWindow: time.Minute}
+	line := geo.LineString{{Lon: 0, Lat: 0}, {Lon: 0, Lat: 2}}
+	got, err := rs.WeatherAlong(context.Background(), domain.RouteQuery{Line: line, Departure: now, SpeedKPH: 100})
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if got.Worst < 0 || got.Segments[got.Worst].Weather.States[0] != "Clear" {
+		t.Errorf("expected the thunderstorm segment to be worst got '%v'", got.Worst)
+	}
+}
+
+func TestRouteService_WeatherAlong_Limits(t *testing.T) {
+	now := time.Now()
+	svc := &routeDomain{
+		forecast: []domain.Weather{
+			{States: []string{"Clear"}, ObservedAt: now},
+			{States: []string{"Clear"}, ObservedAt: now.Add(3 * time.Hour)},
+		},
+	}
+	rs := domain.RouteService{Current: svc, Forecast: svc, Spacing: 1, MaxSamples: 4, CurrentWindow: time.Hour}
+	line := geo.LineString{{Lon: 0, Lat: 0}, {Lon: 0, Lat: 1}}
+
+	got, err := rs.WeatherAlong(context.Background(), domain.RouteQuery{Line: line, Departure: now.Add(48 * time.Hour), SpeedKPH: 50})
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(got.Segments) != 4 {
+		t.Errorf("expected samples to be capped at '%v' got '%v'", 4, len(got.Segments))
+	}
+	for i, seg := range got.Segments {
+		if !errors.Is(seg.Err, domain.ErrBeyondForecast) {
+			t.Errorf("segment %d expected '%v' got '%v'", i, domain.ErrBeyondForecast, seg.Err)
+		}
+	}
+	if got.Worst != -1 {
+		t.Errorf("expected no worst segment got '%v'", got.Worst)
+	}
+
+	if _, err := rs.WeatherAlong(context.Background(), domain.RouteQuery{Line: line, Departure: now}); !errors.Is(err, domain.ErrInvalidRoute) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrInvalidRoute, err)
+	}
+	if _, err := rs.WeatherAlong(context.Background(), domain.RouteQuery{Line: line[:1], SpeedKPH: 50}); !errors.Is(err, domain.ErrInvalidRoute) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrInvalidRoute, err)
+	}
+}
@@ -3,47 +3,134 @@ package domain
 import (
 	"context"
 	"fmt"
+
+	"github.com/broganross/weather-exercise/astro"
+	"github.com/broganross/weather-exercise/tz"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/broganross/weather-exercise/domain")
+
 // Exported Business logic interface
 type Service interface {
 	CurrentIn(ctx context.Context, lat float32, lon float32) (*Weather, error)
 }
 
+// Forecaster is the business logic for forecast weather
+type Forecaster interface {
+	ForecastIn(ctx context.Context, lat float32, lon float32) ([]Weather, error)
+}
+
 // Interface for where we're getting actual weather data from
 type Repo interface {
 	GetByCoords(ctx context.Context, latitude float32, longitude float32) (*RepoWeather, error)
+	// GetForecastByCoords returns the forecast steps in time order
+	GetForecastByCoords(ctx context.Context, latitude float32, longitude float32) ([]RepoWeather, error)
+	// GetAlertsByCoords returns the severe weather alerts covering the coordinates
+	GetAlertsByCoords(ctx context.Context, latitude float32, longitude float32) ([]Alert, error)
 }
 
 // Our domain object for business logic
 type WeatherService struct {
 	Source Repo
+	// SourceAlerts is whether Source is asked for alerts, which may need a separate subscription
+	SourceAlerts bool
+	// AlertSources are other alert providers, consulted alongside Source
+	AlertSources []AlertSource
+	// UV is where UV indexes come from, weather goes without when it's nil
+	UV UVSource
 }
 
 // CurrentIn handles GET requests for finding current weather conditions at a latitude and longitude
 func (w *WeatherService) CurrentIn(ctx context.Context, lat float32, lon float32) (*Weather, error) {
+	ctx, span := tracer.Start(ctx, "domain.CurrentIn", trace.WithAttributes(
+		attribute.Float64("geo.latitude", float64(lat)),
+		attribute.Float64("geo.longitude", float64(lon)),
+	))
+	defer span.End()
 	cw, err := w.Source.GetByCoords(ctx, lat, lon)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting current weather")
 		return nil, fmt.Errorf("getting current weather by coordinates: %w", err)
 	}
-	// NOTE: this assumes we're using Imperial units, and is relative
-	temp := TempUnknown
-	switch {
-	case cw.Temperature < 40.0:
-		temp = TempCold
-	case cw.Temperature < 80.0:
-		temp = TempMod
-	case cw.Temperature > 80.0:
-		temp = TempHot
+	s := fromRepo(cw)
+	w.withUV(ctx, lat, lon, s)
+	span.SetAttributes(attribute.String("weather.temperature", string(s.Temperature)))
+	return s, nil
+}
+
+// ForecastIn finds the forecast weather conditions at a latitude and longitude, in time order
+func (w *WeatherService) ForecastIn(ctx context.Context, lat float32, lon float32) ([]Weather, error) {
+	ctx, span := tracer.Start(ctx, "domain.ForecastIn", trace.WithAttributes(
+		attribute.Float64("geo.latitude", float64(lat)),
+		attribute.Float64("geo.longitude", float64(lon)),
+	))
+	defer span.End()
+	steps, err := w.Source.GetForecastByCoords(ctx, lat, lon)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting forecast")
+		return nil, fmt.Errorf("getting forecast by coordinates: %w", err)
 	}
+	forecast := make([]Weather, len(steps))
+	weathers := make([]*Weather, len(steps))
+	for i := range steps {
+		forecast[i] = *fromRepo(&steps[i])
+		weathers[i] = &forecast[i]
+	}
+	w.withUV(ctx, lat, lon, weathers...)
+	return forecast, nil
+}
 
-	s := &Weather{
+// fromRepo classifies the provider's weather
+func fromRepo(rw *RepoWeather) *Weather {
+	// the offset is for when it was fetched, which a forecast step can be across a daylight saving change from
+	offsetAt := rw.Source.FetchedAt
+	if offsetAt.IsZero() {
+		offsetAt = rw.ObservedAt
+	}
+	// the domain works in Fahrenheit and miles per hour
+	degrees := kelvinToFahrenheit(rw.Temperature)
+	w := &Weather{
 		Coords: Coords{
-			Latitude:  cw.Coords.Latitude,
-			Longitude: cw.Coords.Longitude,
+			Latitude:  rw.Coords.Latitude,
+			Longitude: rw.Coords.Longitude,
 		},
-		States:      cw.States,
-		Temperature: temp,
+		Place:       rw.Place,
+		States:      rw.States,
+		Phenomena:   rw.Phenomena,
+		Temperature: classify(degrees),
+		Degrees:     degrees,
+		ObservedAt:  rw.ObservedAt,
+		Sunrise:     rw.Sunrise,
+		Sunset:      rw.Sunset,
+		Daytime:     astro.IsDay(float64(rw.Coords.Latitude), float64(rw.Coords.Longitude), rw.ObservedAt),
+		Location:    tz.Lookup(float64(rw.Coords.Latitude), float64(rw.Coords.Longitude), offsetAt, rw.UTCOffset),
+		Source:      rw.Source,
 	}
-	return s, nil
+	if rw.Conditions != nil {
+		conditions := *rw.Conditions
+		conditions.WindSpeed = metresPerSecondToMPH(conditions.WindSpeed)
+		derived := Derive(degrees, conditions)
+		w.Derived = &derived
+	}
+	return w
+}
+
+// classify buckets a temperature.
+// NOTE: this is in Fahrenheit, and is relative
+func classify(degrees float32) Temperature {
+	switch {
+	case degrees < 40.0:
+		return TempCold
+	case degrees < 80.0:
+		return TempMod
+	case degrees > 80.0:
+		return TempHot
+	}
+	return TempUnknown
 }
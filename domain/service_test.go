@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/broganross/weather-exercise/domain"
 	"github.com/broganross/weather-exercise/repo"
@@ -20,6 +21,8 @@ type mockWeatherRepoResponse struct {
 
 type mockWeatherRepo struct {
 	responses map[string]mockWeatherRepoResponse
+	forecasts map[string][]domain.RepoWeather
+	alerts    map[string][]domain.Alert
 }
 
 func (mwr *mockWeatherRepo) GetByCoords(ctx context.Context, lat float32, lon float32) (*domain.RepoWeather, error) {
@@ -31,6 +34,24 @@ func (mwr *mockWeatherRepo) GetByCoords(ctx context.Context, lat float32, lon fl
 	return i.resp, i.err
 }
 
+func (mwr *mockWeatherRepo) GetForecastByCoords(ctx context.Context, lat float32, lon float32) ([]domain.RepoWeather, error) {
+	s := fmt.Sprintf("%.04f:%.04f", lat, lon)
+	steps, ok := mwr.forecasts[s]
+	if !ok {
+		return nil, errNotFound
+	}
+	return steps, nil
+}
+
+func (mwr *mockWeatherRepo) GetAlertsByCoords(ctx context.Context, lat float32, lon float32) ([]domain.Alert, error) {
+	s := fmt.Sprintf("%.04f:%.04f", lat, lon)
+	alerts, ok := mwr.alerts[s]
+	if !ok {
+		return nil, errNotFound
+	}
+	return alerts, nil
+}
+
 func TestWeatherService_CurrentIn(t *testing.T) {
 	rainState := repo.WeatherState{
 		ID:          1,
@@ -42,6 +63,13 @@ func TestWeatherService_CurrentIn(t *testing.T) {
 		Name:        "hail",
 		Description: "hard wetness falls from the sky",
 	}
+	juba, err := time.LoadLocation("Africa/Juba")
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	// the repo's wind speed is in metres a second, the domain's in miles per hour
+	conditions := domain.Conditions{Humidity: 80, WindSpeed: 4.4704, WindDegrees: 315}
+	derived := domain.Derive(39.83, domain.Conditions{Humidity: 80, WindSpeed: 10, WindDegrees: 315})
 	tests := []struct {
 		name string
 		lat  float32
@@ -61,6 +89,8 @@ func TestWeatherService_CurrentIn(t *testing.T) {
 				},
 				States:      []string{"rain", "hail"},
 				Temperature: domain.TempCold,
+				Degrees:     39.83,
+				Location:    juba,
 			},
 			nil,
 			mockWeatherRepo{
@@ -76,7 +106,33 @@ func TestWeatherService_CurrentIn(t *testing.T) {
 								rainState.Name,
 								hailState.Name,
 							},
-							Temperature: 39.99,
+							Temperature: 277.5,
+						},
+					},
+				},
+			},
+		},
+		{
+			"derived",
+			10.1,
+			32.1,
+			&domain.Weather{
+				Coords:      domain.Coords{Latitude: 10.1, Longitude: 32.1},
+				States:      []string{"rain"},
+				Temperature: domain.TempCold,
+				Degrees:     39.83,
+				Location:    juba,
+				Derived:     &derived,
+			},
+			nil,
+			mockWeatherRepo{
+				responses: map[string]mockWeatherRepoResponse{
+					"10.1000:32.1000": {
+						resp: &domain.RepoWeather{
+							Coords:      domain.Coords{Latitude: 10.1, Longitude: 32.1},
+							States:      []string{rainState.Name},
+							Temperature: 277.5,
+							Conditions:  &conditions,
 						},
 					},
 				},
@@ -105,6 +161,13 @@ func TestWeatherService_CurrentIn(t *testing.T) {
 				t.Errorf("expected '%v' got '%v'", test.err, err)
 				return
 			}
+			// the zone is compared by name, its lookup cache depends on when it was used
+			if got != nil && test.ans != nil {
+				if got.Location.String() != test.ans.Location.String() {
+					t.Errorf("expected '%v' got '%v'", test.ans.Location, got.Location)
+				}
+				got.Location = test.ans.Location
+			}
 			// best not to use reflect, but it a fine placeholder for this exercise
 			if !reflect.DeepEqual(test.ans, got) {
 				t.Errorf("expected '%v' got '%v'", test.ans, *got)
@@ -112,3 +175,33 @@ func TestWeatherService_CurrentIn(t *testing.T) {
 		})
 	}
 }
+
+func TestWeatherService_ForecastIn(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	serv := domain.WeatherService{
+		Source: &mockWeatherRepo{
+			forecasts: map[string][]domain.RepoWeather{
+				"10.0000:20.0000": {
+					{States: []string{"Clear"}, Temperature: 303, ObservedAt: now},
+					{States: []string{"Snow"}, Temperature: 266, ObservedAt: now.Add(3 * time.Hour)},
+				},
+			},
+		},
+	}
+	got, err := serv.ForecastIn(context.Background(), 10, 20)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	want := []domain.Temperature{domain.TempHot, domain.TempCold}
+	if len(got) != len(want) {
+		t.Fatalf("expected '%v' steps got '%v'", len(want), len(got))
+	}
+	for i, w := range want {
+		if got[i].Temperature != w {
+			t.Errorf("expected '%v' got '%v'", w, got[i].Temperature)
+		}
+	}
+	if _, err := serv.ForecastIn(context.Background(), 1, 2); !errors.Is(err, errNotFound) {
+		t.Errorf("expected '%v' got '%v'", errNotFound, err)
+	}
+}
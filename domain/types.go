@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 // Types that are reusable across the app
 
 type Coords struct {
@@ -16,16 +18,58 @@ const (
 	TempMod     Temperature = "moderate"
 )
 
+// Place is the named location a provider reports weather for
+type Place struct {
+	Name    string
+	Country string
+}
+
+// Source describes where, and when, weather data came from
+type Source struct {
+	Provider  string
+	FetchedAt time.Time
+	Cached    bool
+}
+
 type Weather struct {
-	Coords      Coords
-	States      []string
+	Coords Coords
+	Place  Place
+	States []string
+	// Phenomena are the conditions in the provider independent taxonomy, in the provider's order
+	Phenomena   []Phenomenon
 	Temperature Temperature
+	// Temperature in Fahrenheit
+	Degrees    float32
+	ObservedAt time.Time
+	// Sunrise and Sunset are for the day of ObservedAt, or a nearby one, zero when the provider doesn't know
+	Sunrise time.Time
+	Sunset  time.Time
+	// Daytime is whether the sun was up at ObservedAt, worked out locally
+	Daytime bool
+	// Location is the time zone at Coords
+	Location *time.Location
+	// UV is nil when there's no UV source, or no reading for ObservedAt
+	UV *UV
+	// Derived is nil when the provider doesn't give the conditions it's worked out from
+	Derived *Derived
+	Source  Source
 }
 
 // RepoWeather purely existing so that WeatherService.CurrentIn actually does something.
 // In a normal case we would convert the repo data into domain data.  AKA join states, and convert the temperature.
 type RepoWeather struct {
-	Coords      Coords
-	States      []string
+	Coords    Coords
+	Place     Place
+	States    []string
+	Phenomena []Phenomenon
+	// Temperature in Kelvin, as providers report it
 	Temperature float32
+	ObservedAt  time.Time
+	Sunrise     time.Time
+	Sunset      time.Time
+	// UTCOffset is the offset from UTC at Coords when it was fetched, nil when the provider doesn't say
+	UTCOffset *time.Duration
+	// Conditions is nil when the provider doesn't give them, its wind speed is in metres a second
+	Conditions *Conditions
+	Source     Source
 }
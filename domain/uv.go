@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// UVCategory is the WHO exposure category of a UV index
+type UVCategory string
+
+const (
+	UVLow      UVCategory = "low"
+	UVModerate UVCategory = "moderate"
+	UVHigh     UVCategory = "high"
+	UVVeryHigh UVCategory = "very_high"
+	UVExtreme  UVCategory = "extreme"
+)
+
+// Sun protection recommended by the WHO's Global Solar UV Index guide
+const (
+	protectionNone  = "No protection needed. You can safely stay outside."
+	protectionSome  = "Protection needed. Seek shade during midday hours, cover up, and wear sunscreen, a hat and sunglasses."
+	protectionExtra = "Extra protection needed. Avoid being outside during midday hours, and make sure you seek shade. Shirt, sunscreen and hat are a must."
+)
+
+// UV is a UV index with its exposure category and the protection recommended for it
+type UV struct {
+	Index      float32
+	Category   UVCategory
+	Protection string
+	// Night is set when the index is zero because the sun is down, rather than reported
+	Night bool
+}
+
+// UVReading is the UV index at a time
+type UVReading struct {
+	At    time.Time
+	Index float32
+}
+
+// UVSource is where UV indexes come from
+type UVSource interface {
+	// GetUVByCoords returns the current UV index followed by the hourly forecast, in time order
+	GetUVByCoords(ctx context.Context, latitude float32, longitude float32) ([]UVReading, error)
+}
+
+// ClassifyUV categorizes a UV index, which the WHO rounds to a whole number first
+func ClassifyUV(index float32) UV {
+	uv := UV{Index: index}
+	switch rounded := math.Round(float64(index)); {
+	case rounded <= 2:
+		uv.Category, uv.Protection = UVLow, protectionNone
+	case rounded <= 5:
+		uv.Category, uv.Protection = UVModerate, protectionSome
+	case rounded <= 7:
+		uv.Category, uv.Protection = UVHigh, protectionSome
+	case rounded <= 10:
+		uv.Category, uv.Protection = UVVeryHigh, protectionExtra
+	default:
+		uv.Category, uv.Protection = UVExtreme, protectionExtra
+	}
+	return uv
+}
+
+// uvReadingWindow is how far a reading can be from a time and still stand for it, the hourly forecast is on the hour
+const uvReadingWindow = 30 * time.Minute
+
+// withUV sets the UV index of each weather.  Those at night, by the provider's sunrise and sunset or the sun's position
+// when it doesn't know, are zero, and when they all are the source isn't asked.
+// Weather without a reading near its time, or when the source fails, is left without; UV is extra, so it never fails the weather.
+func (w *WeatherService) withUV(ctx context.Context, lat float32, lon float32, weathers ...*Weather) {
+	if w.UV == nil {
+		return
+	}
+	day := []*Weather{}
+	for _, weather := range weathers {
+		night, known := isNight(weather.ObservedAt, weather.Sunrise, weather.Sunset)
+		if !known {
+			night = !weather.Daytime
+		}
+		if night {
+			uv := ClassifyUV(0)
+			uv.Night = true
+			weather.UV = &uv
+			continue
+		}
+		day = append(day, weather)
+	}
+	if len(day) == 0 {
+		return
+	}
+	readings, err := w.UV.GetUVByCoords(ctx, lat, lon)
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "getting uv index")
+		log.Ctx(ctx).Warn().Err(err).Msg("getting uv index")
+		return
+	}
+	for _, weather := range day {
+		if index, ok := uvAt(readings, weather.ObservedAt); ok {
+			uv := ClassifyUV(index)
+			weather.UV = &uv
+		}
+	}
+}
+
+// uvAt finds the reading closest to t, if there's one close enough
+func uvAt(readings []UVReading, t time.Time) (float32, bool) {
+	best := -1
+	var bestDiff time.Duration
+	for i, r := range readings {
+		diff := r.At.Sub(t).Abs()
+		if diff <= uvReadingWindow && (best < 0 || diff < bestDiff) {
+			best, bestDiff = i, diff
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return readings[best].Index, true
+}
+
+// isNight reports whether t is after sunset and before sunrise, unless they aren't known.
+// Sunrise and sunset can be from any day, they're moved to t's, which drifts by a few minutes for each day between.
+func isNight(t time.Time, sunrise time.Time, sunset time.Time) (night bool, known bool) {
+	// the provider leaves them out during polar day and night
+	if sunrise.IsZero() || sunset.IsZero() || !sunset.After(sunrise) {
+		return false, false
+	}
+	days := math.Floor(t.Sub(sunrise).Hours() / 24)
+	shift := time.Duration(days) * 24 * time.Hour
+	return !t.Before(sunset.Add(shift)), true
+}
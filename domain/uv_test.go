@@ -0,0 +1,132 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+type mockUVSource struct {
+	readings []domain.UVReading
+	err      error
+	calls    int
+}
+
+func (mus *mockUVSource) GetUVByCoords(ctx context.Context, lat float32, lon float32) ([]domain.UVReading, error) {
+	mus.calls++
+	return mus.readings, mus.err
+}
+
+func TestClassifyUV(t *testing.T) {
+	tests := []struct {
+		index    float32
+		category domain.UVCategory
+	}{
+		{0, domain.UVLow},
+		{2.4, domain.UVLow},
+		{2.5, domain.UVModerate},
+		{5.4, domain.UVModerate},
+		{6, domain.UVHigh},
+		{7.49, domain.UVHigh},
+		{8, domain.UVVeryHigh},
+		{10.4, domain.UVVeryHigh},
+		{10.5, domain.UVExtreme},
+		{14, domain.UVExtreme},
+	}
+	for _, test := range tests {
+		t.Run(string(test.category), func(t *testing.T) {
+			got := domain.ClassifyUV(test.index)
+			if got.Category != test.category || got.Index != test.index || got.Protection == "" {
+				t.Errorf("expected '%v' for '%v' got '%v'", test.category, test.index, got)
+			}
+		})
+	}
+}
+
+func TestWeatherService_CurrentIn_UV(t *testing.T) {
+	sunrise := time.Date(2024, 6, 21, 10, 30, 0, 0, time.UTC)
+	sunset := time.Date(2024, 6, 22, 1, 30, 0, 0, time.UTC)
+	noon := time.Date(2024, 6, 21, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		observed time.Time
+		sunrise  time.Time
+		uv       *mockUVSource
+		expected *domain.UV
+		calls    int
+	}{
+		{
+			"day", noon, sunrise,
+			&mockUVSource{readings: []domain.UVReading{{At: noon.Add(-time.Hour), Index: 7}, {At: noon.Add(5 * time.Minute), Index: 9}}},
+			&domain.UV{Index: 9, Category: domain.UVVeryHigh}, 1,
+		},
+		{"night", sunset.Add(time.Hour), sunrise, &mockUVSource{}, &domain.UV{Index: 0, Category: domain.UVLow, Night: true}, 0},
+		{"before-sunrise", sunrise.Add(-time.Minute), sunrise, &mockUVSource{}, &domain.UV{Index: 0, Category: domain.UVLow, Night: true}, 0},
+		{"unknown-sunrise", noon, time.Time{}, &mockUVSource{readings: []domain.UVReading{{At: noon, Index: 3}}}, &domain.UV{Index: 3, Category: domain.UVModerate}, 1},
+		{"unknown-sunrise-night", sunset.Add(4 * time.Hour), time.Time{}, &mockUVSource{}, &domain.UV{Index: 0, Category: domain.UVLow, Night: true}, 0},
+		{"no-reading", noon, sunrise, &mockUVSource{readings: []domain.UVReading{{At: noon.Add(2 * time.Hour), Index: 3}}}, nil, 1},
+		{"failing", noon, sunrise, &mockUVSource{err: errors.New("boom")}, nil, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := domain.WeatherService{
+				Source: &mockWeatherRepo{responses: map[string]mockWeatherRepoResponse{
+					"35.0000:-97.0000": {resp: &domain.RepoWeather{
+						Coords:     domain.Coords{Latitude: 35, Longitude: -97},
+						ObservedAt: test.observed,
+						Sunrise:    test.sunrise,
+						Sunset:     sunset,
+					}},
+				}},
+				UV: test.uv,
+			}
+			got, err := s.CurrentIn(context.Background(), 35, -97)
+			if err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if test.uv.calls != test.calls {
+				t.Errorf("expected '%v' uv lookups got '%v'", test.calls, test.uv.calls)
+			}
+			if (got.UV == nil) != (test.expected == nil) {
+				t.Fatalf("expected '%v' got '%v'", test.expected, got.UV)
+			}
+			if got.UV != nil && (got.UV.Index != test.expected.Index || got.UV.Category != test.expected.Category || got.UV.Night != test.expected.Night) {
+				t.Errorf("expected '%v' got '%v'", *test.expected, *got.UV)
+			}
+		})
+	}
+}
+
+func TestWeatherService_ForecastIn_UV(t *testing.T) {
+	// today's sunrise and sunset stand in for the following days
+	sunrise := time.Date(2024, 6, 21, 4, 0, 0, 0, time.UTC)
+	sunset := time.Date(2024, 6, 21, 20, 0, 0, 0, time.UTC)
+	tomorrowNoon := time.Date(2024, 6, 22, 12, 0, 0, 0, time.UTC)
+	tomorrowNight := time.Date(2024, 6, 22, 23, 0, 0, 0, time.UTC)
+	uv := &mockUVSource{readings: []domain.UVReading{{At: tomorrowNoon, Index: 6.5}}}
+	s := domain.WeatherService{
+		Source: &mockWeatherRepo{forecasts: map[string][]domain.RepoWeather{
+			"51.5000:0.0000": {
+				{ObservedAt: tomorrowNoon, Sunrise: sunrise, Sunset: sunset},
+				{ObservedAt: tomorrowNight, Sunrise: sunrise, Sunset: sunset},
+			},
+		}},
+		UV: uv,
+	}
+	got, err := s.ForecastIn(context.Background(), 51.5, 0)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if got[0].UV == nil || got[0].UV.Category != domain.UVHigh || got[0].UV.Night {
+		t.Errorf("expected a high index at noon got '%v'", got[0].UV)
+	}
+	if got[1].UV == nil || !got[1].UV.Night {
+		t.Errorf("expected night got '%v'", got[1].UV)
+	}
+	if uv.calls != 1 {
+		t.Errorf("expected one uv lookup got '%v'", uv.calls)
+	}
+}
@@ -0,0 +1,245 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("webhook subscription not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook subscription")
+)
+
+// Delivery outcomes
+const (
+	DeliverySucceeded  = "succeeded"
+	DeliveryFailed     = "failed"
+	DeliveryDeadLetter = "dead_letter"
+)
+
+// Predicate is the condition a webhook subscription is notified about.
+// Every field that's set has to match.
+type Predicate struct {
+	// Temperature class, matched exactly
+	Temperature Temperature
+	// Matched against each condition, ignoring case
+	ConditionContains string
+}
+
+// Validate checks the predicate has something to match
+func (p Predicate) Validate() error {
+	switch p.Temperature {
+	case "", TempCold, TempMod, TempHot:
+	default:
+		return fmt.Errorf("%w: unknown temperature %q", ErrInvalidWebhook, p.Temperature)
+	}
+	if p.Temperature == "" && p.ConditionContains == "" {
+		return fmt.Errorf("%w: predicate needs a temperature or condition", ErrInvalidWebhook)
+	}
+	return nil
+}
+
+// Matches reports whether the weather satisfies the predicate
+func (p Predicate) Matches(w *Weather) bool {
+	if p.Temperature != "" && w.Temperature != p.Temperature {
+		return false
+	}
+	if p.ConditionContains == "" {
+		return true
+	}
+	for _, s := range w.States {
+		if strings.Contains(strings.ToLower(s), strings.ToLower(p.ConditionContains)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is a subscription to be notified, at a callback URL, when the weather at some coordinates starts matching a predicate
+type Webhook struct {
+	ID          string
+	Owner       string
+	Coords      Coords
+	Predicate   Predicate
+	CallbackURL string
+	// Key the payloads are signed with
+	Secret    string
+	CreatedAt time.Time
+	// Whether the predicate matched at the last evaluation, so it's only notified when it starts matching
+	Matching bool
+}
+
+// WebhookDelivery is one attempt to notify a webhook
+type WebhookDelivery struct {
+	ID        string
+	WebhookID string
+	// Deliveries of the same event share an event ID
+	EventID    string
+	Attempt    int
+	Outcome    string
+	StatusCode int
+	Error      string
+	At         time.Time
+}
+
+// WebhookStore persists webhook subscriptions, and their delivery history
+type WebhookStore interface {
+	Create(ctx context.Context, w *Webhook) error
+	Get(ctx context.Context, id string) (*Webhook, error)
+	List(ctx context.Context) ([]Webhook, error)
+	Delete(ctx context.Context, id string) error
+	SetMatching(ctx context.Context, id string, matching bool) error
+	AddDelivery(ctx context.Context, d *WebhookDelivery) error
+	// Deliveries returns a webhook's delivery attempts, oldest first
+	Deliveries(ctx context.Context, webhookID string) ([]WebhookDelivery, error)
+}
+
+// WebhookEvent is a notification that a webhook's predicate started matching
+type WebhookEvent struct {
+	ID         string
+	Webhook    *Webhook
+	Weather    *Weather
+	OccurredAt time.Time
+}
+
+// WebhookSender delivers an event to a webhook's callback, returning the response status code
+type WebhookSender interface {
+	Send(ctx context.Context, e *WebhookEvent) (int, error)
+}
+
+// WebhookEvaluator periodically checks the weather for every webhook subscription, and notifies those
+// whose predicate has started matching.
+// Failed deliveries are retried with exponential backoff, then recorded as dead letters.
+type WebhookEvaluator struct {
+	Service  Service
+	Store    WebhookStore
+	Sender   WebhookSender
+	Interval time.Duration
+	// Grid size in degrees, subscriptions in the same cell share a lookup
+	GridSize float32
+	// Attempts made to deliver each event
+	MaxAttempts int
+	// Delay before the first retry, doubled for each one after
+	Backoff time.Duration
+	// NewID generates IDs for events and deliveries, it's called concurrently
+	NewID func() string
+
+	deliveries sync.WaitGroup
+}
+
+// Run evaluates the subscriptions every interval until the context is cancelled,
+// then waits for deliveries in progress to finish.
+func (we *WebhookEvaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(we.Interval)
+	defer ticker.Stop()
+	for {
+		if err := we.Evaluate(ctx); err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("evaluating webhooks")
+		}
+		select {
+		case <-ctx.Done():
+			we.deliveries.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Evaluate checks every subscription once, starting deliveries for those that have started matching
+func (we *WebhookEvaluator) Evaluate(ctx context.Context) error {
+	webhooks, err := we.Store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing webhooks: %w", err)
+	}
+	// one lookup per grid cell
+	current := map[Coords]*Weather{}
+	for i := range webhooks {
+		wh := &webhooks[i]
+		cell := Snap(wh.Coords, we.GridSize)
+		weather, ok := current[cell]
+		if !ok {
+			weather, err = we.Service.CurrentIn(ctx, cell.Latitude, cell.Longitude)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("webhook", wh.ID).Msg("getting weather for webhook")
+				continue
+			}
+			current[cell] = weather
+		}
+		matching := wh.Predicate.Matches(weather)
+		if matching == wh.Matching {
+			continue
+		}
+		if err := we.Store.SetMatching(ctx, wh.ID, matching); err != nil {
+			if errors.Is(err, ErrWebhookNotFound) {
+				// deleted since it was listed
+				continue
+			}
+			return fmt.Errorf("updating webhook %s: %w", wh.ID, err)
+		}
+		if matching {
+			e := &WebhookEvent{
+				ID:         we.NewID(),
+				Webhook:    wh,
+				Weather:    weather,
+				OccurredAt: time.Now().UTC(),
+			}
+			we.deliveries.Add(1)
+			go func() {
+				defer we.deliveries.Done()
+				we.deliver(ctx, e)
+			}()
+		}
+	}
+	return nil
+}
+
+// deliver sends an event until it succeeds or runs out of attempts, recording each attempt.
+// Retries still waiting when the context is cancelled are given up on as dead letters, and the webhook is marked
+// as not matching so the next evaluation, after a restart, notifies it again.
+func (we *WebhookEvaluator) deliver(ctx context.Context, e *WebhookEvent) {
+	backoff := we.Backoff
+	attempts := max(we.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		status, err := we.Sender.Send(ctx, e)
+		d := &WebhookDelivery{
+			ID:         we.NewID(),
+			WebhookID:  e.Webhook.ID,
+			EventID:    e.ID,
+			Attempt:    attempt,
+			Outcome:    DeliverySucceeded,
+			StatusCode: status,
+			At:         time.Now().UTC(),
+		}
+		if err != nil {
+			d.Outcome = DeliveryFailed
+			d.Error = err.Error()
+			if attempt == attempts || ctx.Err() != nil {
+				d.Outcome = DeliveryDeadLetter
+			}
+		}
+		// the history is recorded even when shutting down
+		if err := we.Store.AddDelivery(context.WithoutCancel(ctx), d); err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("webhook", e.Webhook.ID).Msg("recording webhook delivery")
+		}
+		if d.Outcome == DeliveryDeadLetter && ctx.Err() != nil {
+			err := we.Store.SetMatching(context.WithoutCancel(ctx), e.Webhook.ID, false)
+			if err != nil && !errors.Is(err, ErrWebhookNotFound) {
+				log.Ctx(ctx).Error().Err(err).Str("webhook", e.Webhook.ID).Msg("resetting interrupted webhook")
+			}
+		}
+		if d.Outcome != DeliveryFailed {
+			return
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
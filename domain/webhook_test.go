@@ -0,0 +1,218 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/store"
+)
+
+// flakySender fails the first few sends
+type flakySender struct {
+	mu       sync.Mutex
+	failures int
+}
+
+func (fs *flakySender) Send(ctx context.Context, e *domain.WebhookEvent) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.failures > 0 {
+		fs.failures--
+		return 500, errors.New("callback unavailable")
+	}
+	return 204, nil
+}
+
+func TestPredicate(t *testing.T) {
+	snow := &domain.Weather{States: []string{"Light Snow"}, Temperature: domain.TempCold}
+	tests := []struct {
+		name      string
+		predicate domain.Predicate
+		matches   bool
+		err       error
+	}{
+		{"temperature", domain.Predicate{Temperature: domain.TempCold}, true, nil},
+		{"other-temperature", domain.Predicate{Temperature: domain.TempHot}, false, nil},
+		{"condition", domain.Predicate{ConditionContains: "snow"}, true, nil},
+		{"both", domain.Predicate{Temperature: domain.TempHot, ConditionContains: "snow"}, false, nil},
+		{"empty", domain.Predicate{}, true, domain.ErrInvalidWebhook},
+		{"unknown-temperature", domain.Predicate{Temperature: "tepid"}, false, domain.ErrInvalidWebhook},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.predicate.Validate(); !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if test.err != nil {
+				return
+			}
+			if got := test.predicate.Matches(snow); got != test.matches {
+				t.Errorf("expected '%v' got '%v'", test.matches, got)
+			}
+		})
+	}
+}
+
+func TestWebhookEvaluator(t *testing.T) {
+	ctx := context.Background()
+	svc := &changingService{degrees: 50}
+	s := store.NewMemory()
+	hooks := []domain.Webhook{
+		{ID: "flaky", Coords: domain.Coords{Latitude: 1, Longitude: 1}, Predicate: domain.Predicate{ConditionContains: "clear"}},
+		{ID: "dead", Coords: domain.Coords{Latitude: 1, Longitude: 1}, Predicate: domain.Predicate{ConditionContains: "clear"}},
+		{ID: "never", Coords: domain.Coords{Latitude: 1, Longitude: 1}, Predicate: domain.Predicate{ConditionContains: "snow"}},
+	}
+	for _, h := range hooks {
+		if err := s.Create(ctx, &h); err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+	}
+	var ids atomic.Int64
+	we := &domain.WebhookEvaluator{
+		Service:     svc,
+		Store:       s,
+		Sender:      &perHookSender{senders: map[string]*flakySender{"flaky": {failures: 1}, "dead": {failures: 5}}, fallback: &flakySender{}},
+		Interval:    time.Hour,
+		GridSize:    1,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		NewID: func() string {
+			return fmt.Sprintf("id-%d", ids.Add(1))
+		},
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		we.Run(runCtx)
+		close(done)
+	}()
+	// wait for the first evaluation's deliveries
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	if calls := svc.callCount(); calls != 1 {
+		t.Errorf("expected webhooks in the same cell to share a lookup, got '%v' calls", calls)
+	}
+	tests := []struct {
+		id       string
+		outcomes []string
+	}{
+		{"flaky", []string{domain.DeliveryFailed, domain.DeliverySucceeded}},
+		{"dead", []string{domain.DeliveryFailed, domain.DeliveryFailed, domain.DeliveryDeadLetter}},
+		{"never", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.id, func(t *testing.T) {
+			history, err := s.Deliveries(ctx, test.id)
+			if err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if len(history) != len(test.outcomes) {
+				t.Fatalf("expected '%v' got '%v'", test.outcomes, history)
+			}
+			for i, d := range history {
+				if d.Outcome != test.outcomes[i] || d.Attempt != i+1 || d.EventID != history[0].EventID {
+					t.Errorf("expected attempt %d to be '%v' got '%v'", i+1, test.outcomes[i], d)
+				}
+			}
+		})
+	}
+
+	// still matching, so nothing more is sent
+	if err := we.Evaluate(ctx); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if history, _ := s.Deliveries(ctx, "flaky"); len(history) != 2 {
+		t.Errorf("expected no new deliveries got '%v'", history)
+	}
+	got, _ := s.Get(ctx, "flaky")
+	if !got.Matching {
+		t.Errorf("expected the match to be recorded")
+	}
+}
+
+// perHookSender routes sends to a sender per webhook
+type perHookSender struct {
+	senders  map[string]*flakySender
+	fallback *flakySender
+}
+
+func (ps *perHookSender) Send(ctx context.Context, e *domain.WebhookEvent) (int, error) {
+	if s, ok := ps.senders[e.Webhook.ID]; ok {
+		return s.Send(ctx, e)
+	}
+	return ps.fallback.Send(ctx, e)
+}
+
+// blockingSender fails once the context is cancelled, like a send cut off by shutdown
+type blockingSender struct {
+	started chan struct{}
+	block   bool
+}
+
+func (bs *blockingSender) Send(ctx context.Context, e *domain.WebhookEvent) (int, error) {
+	if !bs.block {
+		return 204, nil
+	}
+	bs.started <- struct{}{}
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestWebhookEvaluator_interrupted(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	if err := s.Create(ctx, &domain.Webhook{ID: "hook", Coords: domain.Coords{Latitude: 1, Longitude: 1}, Predicate: domain.Predicate{ConditionContains: "clear"}}); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	sender := &blockingSender{started: make(chan struct{}, 1), block: true}
+	var ids atomic.Int64
+	we := &domain.WebhookEvaluator{
+		Service:     &changingService{},
+		Store:       s,
+		Sender:      sender,
+		Interval:    time.Hour,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		NewID: func() string {
+			return fmt.Sprintf("id-%d", ids.Add(1))
+		},
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		we.Run(runCtx)
+		close(done)
+	}()
+	<-sender.started
+	// shut down with the delivery in flight
+	cancel()
+	<-done
+
+	history, _ := s.Deliveries(ctx, "hook")
+	if len(history) != 1 || history[0].Outcome != domain.DeliveryDeadLetter {
+		t.Fatalf("expected a dead letter got '%v'", history)
+	}
+	if got, _ := s.Get(ctx, "hook"); got.Matching {
+		t.Errorf("expected the interrupted webhook not to be left matching")
+	}
+
+	// after a restart it's notified again
+	sender.block = false
+	if err := we.Evaluate(ctx); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	history, _ = s.Deliveries(ctx, "hook")
+	if len(history) != 2 || history[1].Outcome != domain.DeliverySucceeded || history[1].EventID == history[0].EventID {
+		t.Errorf("expected a new delivery got '%v'", history)
+	}
+}
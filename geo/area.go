@@ -0,0 +1,289 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidBBox = errors.New("invalid bounding box")
+
+// Area is a region that can be sampled
+type Area interface {
+	// Bounds is the smallest box holding the whole area
+	Bounds() BBox
+	// Contains reports whether the point is inside the area
+	Contains(p Point) bool
+}
+
+// BBox is a bounding box.  Boxes crossing the antimeridian have a MinLon greater than MaxLon.
+type BBox struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+// ParseBBox reads a bounding box in GeoJSON order, "minLon,minLat,maxLon,maxLat"
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return BBox{}, fmt.Errorf("%w: expected minLon,minLat,maxLon,maxLat", ErrInvalidBBox)
+	}
+	f := [4]float64{}
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("%w: %s is not a number", ErrInvalidBBox, p)
+		}
+		f[i] = v
+	}
+	b := BBox{MinLon: f[0], MinLat: f[1], MaxLon: f[2], MaxLat: f[3]}
+	min := Point{Lon: b.MinLon, Lat: b.MinLat}
+	max := Point{Lon: b.MaxLon, Lat: b.MaxLat}
+	if !min.Valid() || !max.Valid() {
+		return BBox{}, fmt.Errorf("%w: out of range", ErrInvalidBBox)
+	}
+	if b.MinLat > b.MaxLat {
+		return BBox{}, fmt.Errorf("%w: minimum latitude is above the maximum", ErrInvalidBBox)
+	}
+	return b, nil
+}
+
+// String formats the box as "minLon,minLat,maxLon,maxLat"
+func (b BBox) String() string {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return f(b.MinLon) + "," + f(b.MinLat) + "," + f(b.MaxLon) + "," + f(b.MaxLat)
+}
+
+// width is the span of longitudes in the box, allowing for the antimeridian
+func (b BBox) width() float64 {
+	if b.MinLon > b.MaxLon {
+		return b.MaxLon + 360 - b.MinLon
+	}
+	return b.MaxLon - b.MinLon
+}
+
+func (b BBox) Bounds() BBox {
+	return b
+}
+
+func (b BBox) Contains(p Point) bool {
+	if p.Lat < b.MinLat || p.Lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon > b.MaxLon {
+		return p.Lon >= b.MinLon || p.Lon <= b.MaxLon
+	}
+	return p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+}
+
+// Polygon is a GeoJSON polygon, an outer ring followed by any holes
+type Polygon [][]Point
+
+func (pg Polygon) Bounds() BBox {
+	b := BBox{MinLon: 180, MinLat: 90, MaxLon: -180, MaxLat: -90}
+	if len(pg) == 0 {
+		return BBox{}
+	}
+	for _, p := range pg[0] {
+		b.MinLon = math.Min(b.MinLon, p.Lon)
+		b.MinLat = math.Min(b.MinLat, p.Lat)
+		b.MaxLon = math.Max(b.MaxLon, p.Lon)
+		b.MaxLat = math.Max(b.MaxLat, p.Lat)
+	}
+	return b
+}
+
+// Contains uses the even-odd rule, so points inside holes are outside the polygon
+func (pg Polygon) Contains(p Point) bool {
+	inside := false
+	for _, ring := range pg {
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+				p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// MultiPolygon is an area made of several polygons
+type MultiPolygon []Polygon
+
+func (mp MultiPolygon) Bounds() BBox {
+	if len(mp) == 0 {
+		return BBox{}
+	}
+	b := mp[0].Bounds()
+	for _, pg := range mp[1:] {
+		pb := pg.Bounds()
+		b.MinLon = math.Min(b.MinLon, pb.MinLon)
+		b.MinLat = math.Min(b.MinLat, pb.MinLat)
+		b.MaxLon = math.Max(b.MaxLon, pb.MaxLon)
+		b.MaxLat = math.Max(b.MaxLat, pb.MaxLat)
+	}
+	return b
+}
+
+func (mp MultiPolygon) Contains(p Point) bool {
+	for _, pg := range mp {
+		if pg.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Circle is the area within a distance of its center
+type Circle struct {
+	Center   Point
+	RadiusKM float64
+}
+
+// Bounds is approximate, it ignores the poles and the antimeridian
+func (c Circle) Bounds() BBox {
+	dLat := c.RadiusKM / EarthRadiusKM * 180 / math.Pi
+	dLon := dLat / math.Max(math.Cos(c.Center.Lat*math.Pi/180), 0.01)
+	return BBox{
+		MinLon: math.Max(c.Center.Lon-dLon, -180),
+		MinLat: math.Max(c.Center.Lat-dLat, -90),
+		MaxLon: math.Min(c.Center.Lon+dLon, 180),
+		MaxLat: math.Min(c.Center.Lat+dLat, 90),
+	}
+}
+
+func (c Circle) Contains(p Point) bool {
+	return Distance(c.Center, p) <= c.RadiusKM
+}
+
+// Grid samples the area with the centres of a regular grid, using the finest spacing that keeps
+// the number of samples in the area to no more than max
+func Grid(a Area, max int) []Point {
+	if max < 1 {
+		return nil
+	}
+	best := grid(a, max)
+	// polygons only fill part of their bounds, so try finer grids while the samples still fit
+	for cells := max * 2; cells <= max*16 && len(best) < max; cells *= 2 {
+		points := grid(a, cells)
+		if len(points) > max {
+			break
+		}
+		best = points
+	}
+	return best
+}
+
+// grid samples the area with a grid of up to cells points over its bounds
+func grid(a Area, max int) []Point {
+	b := a.Bounds()
+	width := b.width()
+	height := b.MaxLat - b.MinLat
+	cols, rows := 1, 1
+	if width > 0 && height > 0 {
+		step := math.Sqrt(width * height / float64(max))
+		cols = int(math.Max(1, math.Round(width/step)))
+		rows = int(math.Max(1, math.Round(height/step)))
+		for cols*rows > max {
+			if float64(cols)/width > float64(rows)/height {
+				cols--
+			} else {
+				rows--
+			}
+		}
+	} else if width > 0 {
+		cols = max
+	} else if height > 0 {
+		rows = max
+	}
+	points := []Point{}
+	for r := 0; r < rows; r++ {
+		lat := b.MinLat + height*(float64(r)+0.5)/float64(rows)
+		for c := 0; c < cols; c++ {
+			lon := b.MinLon + width*(float64(c)+0.5)/float64(cols)
+			if lon > 180 {
+				lon -= 360
+			}
+			p := Point{Lon: lon, Lat: lat}
+			if a.Contains(p) {
+				points = append(points, p)
+			}
+		}
+	}
+	return points
+}
+
+// Area returns the Polygon and MultiPolygon geometries in the object as a single area.
+// Features and collections are searched, other geometry types are unsupported.
+func (o *Object) Area() (MultiPolygon, error) {
+	geometries, err := o.geometries()
+	if err != nil {
+		return nil, err
+	}
+	area := MultiPolygon{}
+	for _, g := range geometries {
+		polygons, err := g.polygons()
+		if err != nil {
+			return nil, err
+		}
+		area = append(area, polygons...)
+	}
+	return area, nil
+}
+
+func (g *Geometry) polygons() ([]Polygon, error) {
+	switch g.Type {
+	case TypePolygon:
+		pg := Polygon{}
+		if err := json.Unmarshal(g.Coordinates, &pg); err != nil {
+			return nil, err
+		}
+		if err := pg.validate(); err != nil {
+			return nil, err
+		}
+		return []Polygon{pg}, nil
+	case TypeMultiPolygon:
+		mp := MultiPolygon{}
+		if err := json.Unmarshal(g.Coordinates, &mp); err != nil {
+			return nil, err
+		}
+		for _, pg := range mp {
+			if err := pg.validate(); err != nil {
+				return nil, err
+			}
+		}
+		return mp, nil
+	case TypeGeometryCollection:
+		all := []Polygon{}
+		for i := range g.Geometries {
+			p, err := g.Geometries[i].polygons()
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, p...)
+		}
+		return all, nil
+	}
+	return nil, fmt.Errorf("%w: %s, expected polygons", ErrUnsupportedGeoJSON, g.Type)
+}
+
+// validate checks the rings are closed, with at least four positions
+func (pg Polygon) validate() error {
+	if len(pg) == 0 {
+		return fmt.Errorf("%w: polygon has no rings", ErrInvalidGeoJSON)
+	}
+	for _, ring := range pg {
+		if len(ring) < 4 || ring[0] != ring[len(ring)-1] {
+			return fmt.Errorf("%w: polygon rings must be closed with at least four positions", ErrInvalidGeoJSON)
+		}
+	}
+	return nil
+}
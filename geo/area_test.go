@@ -0,0 +1,130 @@
+package geo_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/broganross/weather-exercise/geo"
+)
+
+func TestParseBBox(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want geo.BBox
+		err  error
+	}{
+		{"valid", "-10,-5,10.5,5", geo.BBox{MinLon: -10, MinLat: -5, MaxLon: 10.5, MaxLat: 5}, nil},
+		{"antimeridian", "170,-5,-170,5", geo.BBox{MinLon: 170, MinLat: -5, MaxLon: -170, MaxLat: 5}, nil},
+		{"too-few", "1,2,3", geo.BBox{}, geo.ErrInvalidBBox},
+		{"not-a-number", "1,2,3,north", geo.BBox{}, geo.ErrInvalidBBox},
+		{"out-of-range", "1,2,3,95", geo.BBox{}, geo.ErrInvalidBBox},
+		{"inverted-latitude", "1,5,3,2", geo.BBox{}, geo.ErrInvalidBBox},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := geo.ParseBBox(test.in)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if got != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
+
+func TestPolygon_Contains(t *testing.T) {
+	square := geo.Polygon{
+		{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 10, Lat: 10}, {Lon: 0, Lat: 10}, {Lon: 0, Lat: 0}},
+		{{Lon: 4, Lat: 4}, {Lon: 6, Lat: 4}, {Lon: 6, Lat: 6}, {Lon: 4, Lat: 6}, {Lon: 4, Lat: 4}},
+	}
+	tests := []struct {
+		name string
+		p    geo.Point
+		want bool
+	}{
+		{"inside", geo.Point{Lon: 2, Lat: 2}, true},
+		{"outside", geo.Point{Lon: 12, Lat: 2}, false},
+		{"in-hole", geo.Point{Lon: 5, Lat: 5}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := square.Contains(test.p); got != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCircle_Contains(t *testing.T) {
+	// about 111km per degree of latitude
+	c := geo.Circle{Center: geo.Point{Lon: -97.5, Lat: 35.5}, RadiusKM: 50}
+	tests := []struct {
+		name string
+		p    geo.Point
+		want bool
+	}{
+		{"center", c.Center, true},
+		{"inside", geo.Point{Lon: -97.5, Lat: 35.9}, true},
+		{"outside", geo.Point{Lon: -97.5, Lat: 36}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := c.Contains(test.p); got != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+			if test.want && !c.Bounds().Contains(test.p) {
+				t.Errorf("expected the bounds to hold '%v'", test.p)
+			}
+		})
+	}
+}
+
+func TestGrid(t *testing.T) {
+	triangle := geo.Polygon{{{Lon: 0, Lat: 0}, {Lon: 10, Lat: 0}, {Lon: 0, Lat: 10}, {Lon: 0, Lat: 0}}}
+	tests := []struct {
+		name string
+		area geo.Area
+		max  int
+		want int
+	}{
+		{"square", geo.BBox{MinLon: 0, MinLat: 0, MaxLon: 2, MaxLat: 2}, 16, 16},
+		{"wide", geo.BBox{MinLon: 0, MinLat: 0, MaxLon: 8, MaxLat: 2}, 16, 16},
+		{"capped", geo.BBox{MinLon: 0, MinLat: 0, MaxLon: 3, MaxLat: 1}, 10, 10},
+		{"triangle", triangle, 16, 15},
+		{"single", geo.BBox{MinLon: 1, MinLat: 1, MaxLon: 1, MaxLat: 1}, 16, 1},
+		{"antimeridian", geo.BBox{MinLon: 179, MinLat: 0, MaxLon: -179, MaxLat: 1}, 4, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := geo.Grid(test.area, test.max)
+			if len(got) != test.want {
+				t.Errorf("expected %d points got %d: %v", test.want, len(got), got)
+			}
+			for _, p := range got {
+				if !p.Valid() || !test.area.Contains(p) {
+					t.Errorf("expected '%v' to be inside the area", p)
+				}
+			}
+		})
+	}
+}
+
+func TestObject_Area(t *testing.T) {
+	o, err := geo.Decode([]byte(`{"type":"Feature","properties":{},"geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}}`))
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	area, err := o.Area()
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(area) != 1 || !area.Contains(geo.Point{Lon: 0.75, Lat: 0.25}) {
+		t.Errorf("unexpected area '%v'", area)
+	}
+	o, _ = geo.Decode([]byte(`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1]]]}`))
+	if _, err := o.Area(); !errors.Is(err, geo.ErrInvalidGeoJSON) {
+		t.Errorf("expected '%v' got '%v'", geo.ErrInvalidGeoJSON, err)
+	}
+}
@@ -0,0 +1,186 @@
+// Package geo holds geometry helpers that don't depend on the rest of the service, starting with GeoJSON (RFC 7946).
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// MediaType is the media type of GeoJSON documents
+const MediaType = "application/geo+json"
+
+var (
+	ErrInvalidGeoJSON     = errors.New("invalid geojson")
+	ErrUnsupportedGeoJSON = errors.New("unsupported geojson type")
+)
+
+// GeoJSON object types
+const (
+	TypePoint              = "Point"
+	TypeMultiPoint         = "MultiPoint"
+	TypeLineString         = "LineString"
+	TypePolygon            = "Polygon"
+	TypeMultiPolygon       = "MultiPolygon"
+	TypeGeometryCollection = "GeometryCollection"
+	TypeFeature            = "Feature"
+	TypeFeatureCollection  = "FeatureCollection"
+)
+
+// Point is a position on the globe in degrees.
+// GeoJSON orders positions longitude first, which is easy to get backwards, so positions are only
+// converted to and from Points in this package.
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+// Valid reports whether the point is within the range of longitudes and latitudes
+func (p Point) Valid() bool {
+	return p.Lon >= -180 && p.Lon <= 180 && p.Lat >= -90 && p.Lat <= 90
+}
+
+// MarshalJSON writes the point as a GeoJSON position, to six decimal places
+func (p Point) MarshalJSON() ([]byte, error) {
+	lon := strconv.FormatFloat(p.Lon, 'f', 6, 64)
+	lat := strconv.FormatFloat(p.Lat, 'f', 6, 64)
+	return []byte("[" + lon + "," + lat + "]"), nil
+}
+
+// UnmarshalJSON reads a GeoJSON position, ignoring any altitude
+func (p *Point) UnmarshalJSON(b []byte) error {
+	pos := []float64{}
+	if err := json.Unmarshal(b, &pos); err != nil {
+		return fmt.Errorf("%w: position: %w", ErrInvalidGeoJSON, err)
+	}
+	if len(pos) < 2 {
+		return fmt.Errorf("%w: position needs a longitude and latitude", ErrInvalidGeoJSON)
+	}
+	*p = Point{Lon: pos[0], Lat: pos[1]}
+	if !p.Valid() {
+		return fmt.Errorf("%w: position [%v, %v] is out of range, positions are longitude then latitude", ErrInvalidGeoJSON, pos[0], pos[1])
+	}
+	return nil
+}
+
+// Geometry is a GeoJSON geometry.  Coordinates are kept raw and decoded by type.
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []Geometry      `json:"geometries,omitempty"`
+}
+
+// NewPoint creates a Point geometry
+func NewPoint(p Point) *Geometry {
+	b, _ := p.MarshalJSON()
+	return &Geometry{Type: TypePoint, Coordinates: b}
+}
+
+// Feature is a GeoJSON feature
+type Feature struct {
+	Type       string    `json:"type"`
+	ID         string    `json:"id,omitempty"`
+	Geometry   *Geometry `json:"geometry"`
+	Properties any       `json:"properties"`
+}
+
+// FeatureCollection is a GeoJSON feature collection.
+// Meta is a foreign member carrying details about the collection as a whole.
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+	Meta     any       `json:"meta,omitempty"`
+}
+
+// Object is any GeoJSON object, for decoding documents where the type isn't known in advance
+type Object struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometries  []Geometry      `json:"geometries"`
+	Geometry    *Geometry       `json:"geometry"`
+	Features    []Object        `json:"features"`
+}
+
+// Decode reads a GeoJSON object
+func Decode(b []byte) (*Object, error) {
+	o := &Object{}
+	if err := json.Unmarshal(b, o); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGeoJSON, err)
+	}
+	if o.Type == "" {
+		return nil, fmt.Errorf("%w: missing type", ErrInvalidGeoJSON)
+	}
+	return o, nil
+}
+
+// geometries flattens the object into its geometries
+func (o *Object) geometries() ([]Geometry, error) {
+	switch o.Type {
+	case TypeFeature:
+		if o.Geometry == nil {
+			return nil, nil
+		}
+		return []Geometry{*o.Geometry}, nil
+	case TypeFeatureCollection:
+		all := []Geometry{}
+		for i := range o.Features {
+			if o.Features[i].Type != TypeFeature {
+				return nil, fmt.Errorf("%w: feature collections can only contain features", ErrInvalidGeoJSON)
+			}
+			g, err := o.Features[i].geometries()
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, g...)
+		}
+		return all, nil
+	}
+	return []Geometry{{Type: o.Type, Coordinates: o.Coordinates, Geometries: o.Geometries}}, nil
+}
+
+// Points returns every position of the Point and MultiPoint geometries in the object, in order.
+// Features and collections are searched, other geometry types are unsupported.
+func (o *Object) Points() ([]Point, error) {
+	geometries, err := o.geometries()
+	if err != nil {
+		return nil, err
+	}
+	points := []Point{}
+	for _, g := range geometries {
+		p, err := g.points()
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p...)
+	}
+	return points, nil
+}
+
+func (g *Geometry) points() ([]Point, error) {
+	switch g.Type {
+	case TypePoint:
+		p := Point{}
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return nil, err
+		}
+		return []Point{p}, nil
+	case TypeMultiPoint:
+		p := []Point{}
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	case TypeGeometryCollection:
+		all := []Point{}
+		for i := range g.Geometries {
+			p, err := g.Geometries[i].points()
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, p...)
+		}
+		return all, nil
+	}
+	return nil, fmt.Errorf("%w: %s, expected points", ErrUnsupportedGeoJSON, g.Type)
+}
@@ -0,0 +1,102 @@
+package geo_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/broganross/weather-exercise/geo"
+)
+
+func TestObject_Points(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []geo.Point
+		err  error
+	}{
+		{
+			"point",
+			`{"type":"Point","coordinates":[-122.4,37.8,12]}`,
+			[]geo.Point{{Lon: -122.4, Lat: 37.8}},
+			nil,
+		},
+		{
+			"multipoint",
+			`{"type":"MultiPoint","coordinates":[[1,2],[3,4]]}`,
+			[]geo.Point{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}},
+			nil,
+		},
+		{
+			"feature-collection",
+			`{"type":"FeatureCollection","features":[
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[1,2]},"properties":{"name":"a"}},
+				{"type":"Feature","geometry":{"type":"MultiPoint","coordinates":[[3,4]]},"properties":null},
+				{"type":"Feature","geometry":null,"properties":null}
+			]}`,
+			[]geo.Point{{Lon: 1, Lat: 2}, {Lon: 3, Lat: 4}},
+			nil,
+		},
+		{
+			"geometry-collection",
+			`{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[5,6]}]}`,
+			[]geo.Point{{Lon: 5, Lat: 6}},
+			nil,
+		},
+		{
+			"latitude-first",
+			`{"type":"Point","coordinates":[37.8,-122.4]}`,
+			nil,
+			geo.ErrInvalidGeoJSON,
+		},
+		{
+			"short-position",
+			`{"type":"Point","coordinates":[1]}`,
+			nil,
+			geo.ErrInvalidGeoJSON,
+		},
+		{
+			"unsupported",
+			`{"type":"LineString","coordinates":[[1,2],[3,4]]}`,
+			nil,
+			geo.ErrUnsupportedGeoJSON,
+		},
+		{
+			"missing-type",
+			`{"coordinates":[1,2]}`,
+			nil,
+			geo.ErrInvalidGeoJSON,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o, err := geo.Decode([]byte(test.in))
+			var got []geo.Point
+			if err == nil {
+				got, err = o.Points()
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if test.err == nil && !reflect.DeepEqual(test.want, got) {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
+
+func TestNewPoint(t *testing.T) {
+	f := geo.Feature{
+		Type:     geo.TypeFeature,
+		Geometry: geo.NewPoint(geo.Point{Lon: -122.4, Lat: 37.8}),
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	want := `{"type":"Feature","geometry":{"type":"Point","coordinates":[-122.400000,37.800000]},"properties":null}`
+	if string(b) != want {
+		t.Errorf("expected '%v' got '%v'", want, string(b))
+	}
+}
@@ -0,0 +1,141 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// EarthRadiusKM is the mean radius of the earth
+const EarthRadiusKM = 6371.0088
+
+var ErrInvalidPolyline = errors.New("invalid polyline")
+
+// LineString is a path through two or more points
+type LineString []Point
+
+// DecodePolyline reads an encoded polyline (https://developers.google.com/maps/documentation/utilities/polylinealgorithm).
+// Precision is the number of decimal places the coordinates were encoded with, zero uses the usual 5.
+func DecodePolyline(s string, precision int) (LineString, error) {
+	if precision == 0 {
+		precision = 5
+	}
+	if precision < 0 || precision > 10 {
+		return nil, fmt.Errorf("%w: precision %d", ErrInvalidPolyline, precision)
+	}
+	factor := math.Pow10(precision)
+	line := LineString{}
+	var lat, lon int64
+	i := 0
+	next := func() (int64, error) {
+		var result int64
+		shift := uint(0)
+		for {
+			if i >= len(s) {
+				return 0, fmt.Errorf("%w: truncated at %d", ErrInvalidPolyline, i)
+			}
+			b := int64(s[i]) - 63
+			if b < 0 || b > 63 || shift > 60 {
+				return 0, fmt.Errorf("%w: unexpected character at %d", ErrInvalidPolyline, i)
+			}
+			i++
+			result |= (b & 0x1f) << shift
+			shift += 5
+			if b < 0x20 {
+				break
+			}
+		}
+		if result&1 == 1 {
+			return ^(result >> 1), nil
+		}
+		return result >> 1, nil
+	}
+	for i < len(s) {
+		dLat, err := next()
+		if err != nil {
+			return nil, err
+		}
+		dLon, err := next()
+		if err != nil {
+			return nil, err
+		}
+		lat += dLat
+		lon += dLon
+		p := Point{Lon: float64(lon) / factor, Lat: float64(lat) / factor}
+		if !p.Valid() {
+			return nil, fmt.Errorf("%w: position %d is out of range", ErrInvalidPolyline, len(line))
+		}
+		line = append(line, p)
+	}
+	if len(line) < 2 {
+		return nil, fmt.Errorf("%w: expected at least two positions", ErrInvalidPolyline)
+	}
+	return line, nil
+}
+
+// Distance is the great circle distance between two points in kilometres
+func Distance(a Point, b Point) float64 {
+	rad := math.Pi / 180
+	dLat := (b.Lat - a.Lat) * rad
+	dLon := (b.Lon - a.Lon) * rad
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * EarthRadiusKM * math.Asin(math.Sqrt(min(h, 1)))
+}
+
+// Length is the distance along the line in kilometres
+func (l LineString) Length() float64 {
+	total := 0.0
+	for i := 1; i < len(l); i++ {
+		total += Distance(l[i-1], l[i])
+	}
+	return total
+}
+
+// At returns the point a distance in kilometres along the line, clamped to its ends.
+// Positions between vertices are interpolated linearly, which is close enough for the
+// short segments of a route.
+func (l LineString) At(km float64) Point {
+	if len(l) == 0 {
+		return Point{}
+	}
+	if km <= 0 {
+		return l[0]
+	}
+	for i := 1; i < len(l); i++ {
+		d := Distance(l[i-1], l[i])
+		if km <= d {
+			f := km / d
+			return Point{
+				Lon: l[i-1].Lon + (l[i].Lon-l[i-1].Lon)*f,
+				Lat: l[i-1].Lat + (l[i].Lat-l[i-1].Lat)*f,
+			}
+		}
+		km -= d
+	}
+	return l[len(l)-1]
+}
+
+// LineString returns the single LineString geometry in the object.
+// Features and collections are searched, other geometry types are unsupported.
+func (o *Object) LineString() (LineString, error) {
+	geometries, err := o.geometries()
+	if err != nil {
+		return nil, err
+	}
+	if len(geometries) != 1 {
+		return nil, fmt.Errorf("%w: expected a single LineString, got %d geometries", ErrInvalidGeoJSON, len(geometries))
+	}
+	g := geometries[0]
+	if g.Type != TypeLineString {
+		return nil, fmt.Errorf("%w: %s, expected a LineString", ErrUnsupportedGeoJSON, g.Type)
+	}
+	line := LineString{}
+	if err := json.Unmarshal(g.Coordinates, &line); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidGeoJSON, err)
+	}
+	if len(line) < 2 {
+		return nil, fmt.Errorf("%w: LineString needs at least two positions", ErrInvalidGeoJSON)
+	}
+	return line, nil
+}
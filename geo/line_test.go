@@ -0,0 +1,123 @@
+package geo_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/broganross/weather-exercise/geo"
+)
+
+func TestDecodePolyline(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        string
+		precision int
+		want      geo.LineString
+		err       error
+	}{
+		{
+			"reference",
+			"_p~iF~ps|U_ulLnnqC_mqNvxq`@",
+			0,
+			geo.LineString{{Lon: -120.2, Lat: 38.5}, {Lon: -120.95, Lat: 40.7}, {Lon: -126.453, Lat: 43.252}},
+			nil,
+		},
+		{
+			"precision-6",
+			"_izlhA~rlgdF_{geC~ywl@",
+			6,
+			geo.LineString{{Lon: -120.2, Lat: 38.5}, {Lon: -120.95, Lat: 40.7}},
+			nil,
+		},
+		{"truncated", "_p~iF~ps|U_ulL", 0, nil, geo.ErrInvalidPolyline},
+		{"single-position", "_p~iF~ps|U", 0, nil, geo.ErrInvalidPolyline},
+		{"bad-character", "_p~iF~ps|U _ulLnnqC", 0, nil, geo.ErrInvalidPolyline},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := geo.DecodePolyline(test.in, test.precision)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("expected '%v' got '%v'", test.want, got)
+			}
+			for i := range got {
+				if math.Abs(got[i].Lat-test.want[i].Lat) > 1e-9 || math.Abs(got[i].Lon-test.want[i].Lon) > 1e-9 {
+					t.Errorf("expected '%v' got '%v'", test.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// one degree of latitude
+	got := geo.Distance(geo.Point{Lon: 0, Lat: 0}, geo.Point{Lon: 0, Lat: 1})
+	if math.Abs(got-111.195) > 0.01 {
+		t.Errorf("expected '%v' got '%v'", 111.195, got)
+	}
+	// London to Paris
+	got = geo.Distance(geo.Point{Lon: -0.1278, Lat: 51.5074}, geo.Point{Lon: 2.3522, Lat: 48.8566})
+	if math.Abs(got-343.5) > 1 {
+		t.Errorf("expected '%v' got '%v'", 343.5, got)
+	}
+}
+
+func TestLineString_At(t *testing.T) {
+	line := geo.LineString{{Lon: 0, Lat: 0}, {Lon: 0, Lat: 1}, {Lon: 0, Lat: 2}}
+	length := line.Length()
+	tests := []struct {
+		name string
+		km   float64
+		want geo.Point
+	}{
+		{"start", 0, geo.Point{Lon: 0, Lat: 0}},
+		{"before-start", -5, geo.Point{Lon: 0, Lat: 0}},
+		{"middle-of-first", length / 4, geo.Point{Lon: 0, Lat: 0.5}},
+		{"second-vertex", length / 2, geo.Point{Lon: 0, Lat: 1}},
+		{"past-end", length + 5, geo.Point{Lon: 0, Lat: 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := line.At(test.km)
+			if math.Abs(got.Lat-test.want.Lat) > 1e-9 || math.Abs(got.Lon-test.want.Lon) > 1e-9 {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
+
+func TestObject_LineString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want int
+		err  error
+	}{
+		{"geometry", `{"type":"LineString","coordinates":[[0,0],[1,1],[2,2]]}`, 3, nil},
+		{"feature", `{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]},"properties":null}`, 2, nil},
+		{"point", `{"type":"Point","coordinates":[0,0]}`, 0, geo.ErrUnsupportedGeoJSON},
+		{"too-short", `{"type":"LineString","coordinates":[[0,0]]}`, 0, geo.ErrInvalidGeoJSON},
+		{"two-lines", `{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}},
+			{"type":"Feature","geometry":{"type":"LineString","coordinates":[[0,0],[1,1]]}}
+		]}`, 0, geo.ErrInvalidGeoJSON},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o, err := geo.Decode([]byte(test.in))
+			if err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			got, err := o.LineString()
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if len(got) != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, len(got))
+			}
+		})
+	}
+}
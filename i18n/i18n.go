@@ -0,0 +1,192 @@
+// Package i18n translates the text in responses, from message catalogs embedded in the binary, and picks the
+// language for a request.  Catalogs are per language rather than region, so pt-BR and pt-PT both get pt.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the language used when there's no catalog for any the client asked for
+const Default = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// Catalog is the translated text for a language.  Missing messages fall back to the English text
+// they're looked up with.
+type Catalog struct {
+	// Language is the tag the catalog is for
+	Language string `json:"-"`
+	// Conditions are keyed by phenomenon code
+	Conditions map[string]string `json:"conditions"`
+	// Temperatures are keyed by temperature class
+	Temperatures map[string]string `json:"temperatures"`
+	// Errors are keyed by the English message
+	Errors map[string]string `json:"errors"`
+}
+
+var loadCatalogs = sync.OnceValues(func() (map[string]*Catalog, error) {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		return nil, fmt.Errorf("reading locales: %w", err)
+	}
+	catalogs := make(map[string]*Catalog, len(files))
+	for _, f := range files {
+		b, err := locales.ReadFile(path.Join("locales", f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("reading locale %s: %w", f.Name(), err)
+		}
+		c := &Catalog{Language: strings.TrimSuffix(f.Name(), ".json")}
+		if err := json.Unmarshal(b, c); err != nil {
+			return nil, fmt.Errorf("decoding locale %s: %w", f.Name(), err)
+		}
+		catalogs[strings.ToLower(c.Language)] = c
+	}
+	return catalogs, nil
+})
+
+// Languages are the tags there are catalogs for, in order
+func Languages() []string {
+	catalogs, _ := loadCatalogs()
+	languages := make([]string, 0, len(catalogs))
+	for _, c := range catalogs {
+		languages = append(languages, c.Language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// For is the catalog for a language, or the default's when there isn't one.  It's only nil when the catalogs
+// are broken, which the tests catch, and a nil catalog leaves everything in English.
+func For(language string) *Catalog {
+	// the catalogs are embedded, so they only fail to load from a broken build
+	catalogs, _ := loadCatalogs()
+	if c, ok := catalogs[strings.ToLower(language)]; ok {
+		return c
+	}
+	return catalogs[Default]
+}
+
+// Condition is the name of a phenomenon, or fallback when it isn't translated
+func (c *Catalog) Condition(code string, fallback string) string {
+	if c == nil {
+		return fallback
+	}
+	return orFallback(c.Conditions[code], fallback)
+}
+
+// Temperature is the label for a temperature class, which is its own fallback
+func (c *Catalog) Temperature(class string) string {
+	if c == nil {
+		return class
+	}
+	return orFallback(c.Temperatures[class], class)
+}
+
+// Error is an English error message translated, or as it is when it isn't
+func (c *Catalog) Error(message string) string {
+	if c == nil {
+		return message
+	}
+	return orFallback(c.Errors[message], message)
+}
+
+func orFallback(message string, fallback string) string {
+	if message == "" {
+		return fallback
+	}
+	return message
+}
+
+// Match picks the language for a request: the lang parameter when there's a catalog for it, otherwise the
+// most preferred in the Accept-Language headers that there's one for, otherwise Default.  Tags match a catalog
+// exactly or by dropping subtags from the end, so de-AT gets de.
+func Match(lang string, acceptLanguage []string) string {
+	if language, ok := match(lang); ok {
+		return language
+	}
+	for _, tag := range preferences(acceptLanguage) {
+		if tag == "*" {
+			return Default
+		}
+		if language, ok := match(tag); ok {
+			return language
+		}
+	}
+	return Default
+}
+
+func match(tag string) (string, bool) {
+	catalogs, _ := loadCatalogs()
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	for tag != "" {
+		if c, ok := catalogs[tag]; ok {
+			return c.Language, true
+		}
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return "", false
+}
+
+// preferences are the language ranges in Accept-Language headers, most preferred first.
+// Ranges with a quality of zero aren't acceptable, so they're left out.
+func preferences(headers []string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, h := range headers {
+		for _, part := range strings.Split(h, ",") {
+			params := strings.Split(part, ";")
+			r := weighted{tag: strings.TrimSpace(params[0]), q: 1}
+			if r.tag == "" {
+				continue
+			}
+			for _, p := range params[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+				if strings.EqualFold(k, "q") {
+					if q, err := strconv.ParseFloat(v, 64); err == nil {
+						r.q = q
+					}
+				}
+			}
+			if r.q > 0 {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the language
+func NewContext(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, contextKey{}, language)
+}
+
+// FromContext returns the language stored in ctx, or Default
+func FromContext(ctx context.Context) string {
+	if language, ok := ctx.Value(contextKey{}).(string); ok {
+		return language
+	}
+	return Default
+}
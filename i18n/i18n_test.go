@@ -0,0 +1,145 @@
+package i18n_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+	"github.com/broganross/weather-exercise/server"
+)
+
+func TestLanguages(t *testing.T) {
+	expected := []string{"de", "en", "es", "fr", "it", "ja", "pt", "zh"}
+	if got := i18n.Languages(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected '%v' got '%v'", expected, got)
+	}
+}
+
+func keys(m map[string]string) []string {
+	k := make([]string, 0, len(m))
+	for key := range m {
+		k = append(k, key)
+	}
+	sort.Strings(k)
+	return k
+}
+
+func TestCatalogs_complete(t *testing.T) {
+	en := i18n.For(i18n.Default)
+	if en == nil {
+		t.Fatalf("expected the default catalog to load")
+	}
+	for _, language := range i18n.Languages() {
+		t.Run(language, func(t *testing.T) {
+			c := i18n.For(language)
+			if c.Language != language {
+				t.Fatalf("expected '%v' got '%v'", language, c.Language)
+			}
+			if !reflect.DeepEqual(keys(c.Conditions), keys(en.Conditions)) {
+				t.Errorf("expected conditions '%v' got '%v'", keys(en.Conditions), keys(c.Conditions))
+			}
+			if !reflect.DeepEqual(keys(c.Temperatures), keys(en.Temperatures)) {
+				t.Errorf("expected temperatures '%v' got '%v'", keys(en.Temperatures), keys(c.Temperatures))
+			}
+			if !reflect.DeepEqual(keys(c.Errors), keys(en.Errors)) {
+				t.Errorf("expected errors '%v' got '%v'", keys(en.Errors), keys(c.Errors))
+			}
+		})
+	}
+	for _, class := range []domain.Temperature{domain.TempHot, domain.TempCold, domain.TempMod, domain.TempUnknown} {
+		if _, ok := en.Temperatures[string(class)]; !ok {
+			t.Errorf("expected a label for '%v'", class)
+		}
+	}
+	for _, err := range []error{
+		server.ErrMissingParam, server.ErrInvalidFloat, server.ErrUnsupportedInclude, server.ErrNotAcceptable,
+		server.ErrInvalidTime, server.ErrInvalidBody, server.ErrTooManyPoints, server.ErrInvalidCallback,
+		server.ErrSubscriptionLimit, server.ErrNotSubscribed, server.ErrSubscribed, server.ErrUnknownMessage,
+		domain.ErrNoSamples, domain.ErrBatchTooLarge, domain.ErrNoHistory, domain.ErrFutureHistory,
+		domain.ErrInvalidRoute, domain.ErrBeyondForecast, domain.ErrWebhookNotFound, domain.ErrInvalidWebhook,
+	} {
+		if _, ok := en.Errors[err.Error()]; !ok {
+			t.Errorf("expected a message for '%v'", err)
+		}
+	}
+}
+
+func TestCatalog_Condition(t *testing.T) {
+	tests := []struct {
+		language string
+		code     string
+		expected string
+	}{
+		{"en", "heavy_rain", "heavy rain"},
+		{"de", "heavy_rain", "starker Regen"},
+		{"ja", "clear", "快晴"},
+		{"fr", "frogs", "raining frogs"},
+		{"tlh", "heavy_rain", "heavy rain"},
+	}
+	for _, test := range tests {
+		if got := i18n.For(test.language).Condition(test.code, "raining frogs"); got != test.expected {
+			t.Errorf("%v %v: expected '%v' got '%v'", test.language, test.code, test.expected, got)
+		}
+	}
+	// the English names are the phenomena's own descriptions
+	for code, name := range i18n.For(i18n.Default).Conditions {
+		p, ok := domain.LookupPhenomenon(code)
+		if !ok || p.Description != name {
+			t.Errorf("%v: expected '%v' got '%v'", code, p.Description, name)
+		}
+	}
+	var missing *i18n.Catalog
+	if got := missing.Temperature("hot"); got != "hot" {
+		t.Errorf("expected 'hot' got '%v'", got)
+	}
+}
+
+func TestCatalog_Error(t *testing.T) {
+	if got := i18n.For("es").Error("invalid float"); got != "número no válido" {
+		t.Errorf("expected 'número no válido' got '%v'", got)
+	}
+	if got := i18n.For("es").Error("boom"); got != "boom" {
+		t.Errorf("expected 'boom' got '%v'", got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		lang           string
+		acceptLanguage []string
+		expected       string
+	}{
+		{"nothing", "", nil, "en"},
+		{"lang", "fr", []string{"de"}, "fr"},
+		{"lang-region", "pt_BR", nil, "pt"},
+		{"lang-unsupported", "tlh", []string{"de"}, "de"},
+		{"header", "", []string{"es-MX,es;q=0.9,en;q=0.8"}, "es"},
+		{"quality", "", []string{"en;q=0.5, ja"}, "ja"},
+		{"script", "", []string{"zh-Hant-TW"}, "zh"},
+		{"case", "", []string{"DE-at"}, "de"},
+		{"fallback", "", []string{"tlh, nl;q=0.9, it;q=0.1"}, "it"},
+		{"not-acceptable", "", []string{"fr;q=0, ko"}, "en"},
+		{"wildcard", "", []string{"ko, *;q=0.5, de;q=0.1"}, "en"},
+		{"several-headers", "", []string{"ko", "it"}, "it"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := i18n.Match(test.lang, test.acceptLanguage); got != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if got := i18n.FromContext(context.Background()); got != i18n.Default {
+		t.Errorf("expected '%v' got '%v'", i18n.Default, got)
+	}
+	if got := i18n.FromContext(i18n.NewContext(context.Background(), "ja")); got != "ja" {
+		t.Errorf("expected 'ja' got '%v'", got)
+	}
+}
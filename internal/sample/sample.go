@@ -0,0 +1,15 @@
+// Package sample decides which events are kept when only some of them are logged.
+package sample
+
+import "math/rand"
+
+// Keep reports whether an event should be kept for the given rate (0 to 1)
+func Keep(rate float64) bool {
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	}
+	return rand.Float64() < rate
+}
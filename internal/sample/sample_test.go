@@ -0,0 +1,29 @@
+package sample_test
+
+import (
+	"testing"
+
+	"github.com/broganross/weather-exercise/internal/sample"
+)
+
+func TestKeep(t *testing.T) {
+	tests := []struct {
+		name string
+		rate float64
+		want bool
+	}{
+		{"all", 1, true},
+		{"over", 2, true},
+		{"none", 0, false},
+		{"under", -1, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if got := sample.Keep(test.rate); got != test.want {
+					t.Fatalf("expected '%v' got '%v'", test.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// Package metrics exposes Prometheus instrumentation for the service.
+// Instrumentation is added by wrapping the HTTP handlers and domain interfaces,
+// rather than recording metrics from inside them.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weather"
+
+var (
+	httpRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Count of handled HTTP requests by route template, method and status code.",
+		},
+		[]string{"route", "method", "status"},
+	)
+	httpDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of handled HTTP requests by route template, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "method", "status"},
+	)
+	upstreamCalls = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "upstream",
+			Name:      "calls_total",
+			Help:      "Count of calls to weather providers by provider and outcome.",
+		},
+		[]string{"provider", "outcome"},
+	)
+	upstreamDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "upstream",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of calls to weather providers by provider and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"provider", "outcome"},
+	)
+	domainCalls = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "domain",
+			Name:      "calls_total",
+			Help:      "Count of domain service calls by operation and outcome.",
+		},
+		[]string{"operation", "outcome"},
+	)
+	domainDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "domain",
+			Name:      "call_duration_seconds",
+			Help:      "Latency of domain service calls by operation and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"operation", "outcome"},
+	)
+	temperatures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "domain",
+			Name:      "temperature_classifications_total",
+			Help:      "Count of current weather results by temperature classification.",
+		},
+		[]string{"classification"},
+	)
+)
+
+// Outcomes of upstream calls
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeTimeout = "timeout"
+)
+
+// Handler serves the collected metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveRequest records a handled HTTP request
+func ObserveRequest(route string, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, method, code).Inc()
+	httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// Repo instruments calls to a weather provider
+type Repo struct {
+	Next     domain.Repo
+	Provider string
+}
+
+func (r *Repo) GetByCoords(ctx context.Context, latitude float32, longitude float32) (*domain.RepoWeather, error) {
+	start := time.Now()
+	w, err := r.Next.GetByCoords(ctx, latitude, longitude)
+	r.observe(start, err)
+	return w, err
+}
+
+func (r *Repo) GetForecastByCoords(ctx context.Context, latitude float32, longitude float32) ([]domain.RepoWeather, error) {
+	start := time.Now()
+	ws, err := r.Next.GetForecastByCoords(ctx, latitude, longitude)
+	r.observe(start, err)
+	return ws, err
+}
+
+func (r *Repo) GetAlertsByCoords(ctx context.Context, latitude float32, longitude float32) ([]domain.Alert, error) {
+	start := time.Now()
+	alerts, err := r.Next.GetAlertsByCoords(ctx, latitude, longitude)
+	r.observe(start, err)
+	return alerts, err
+}
+
+func (r *Repo) observe(start time.Time, err error) {
+	observeUpstream(r.Provider, start, err)
+}
+
+// AlertSource instruments calls to a provider that only has alerts
+type AlertSource struct {
+	Next     domain.AlertSource
+	Provider string
+}
+
+func (as *AlertSource) GetAlertsByCoords(ctx context.Context, latitude float32, longitude float32) ([]domain.Alert, error) {
+	start := time.Now()
+	alerts, err := as.Next.GetAlertsByCoords(ctx, latitude, longitude)
+	observeUpstream(as.Provider, start, err)
+	return alerts, err
+}
+
+// AirQualitySource instruments calls to an air pollution provider
+type AirQualitySource struct {
+	Next     domain.AirQualitySource
+	Provider string
+}
+
+func (as *AirQualitySource) GetAirQualityByCoords(ctx context.Context, latitude float32, longitude float32) (*domain.RepoAirQuality, error) {
+	start := time.Now()
+	aq, err := as.Next.GetAirQualityByCoords(ctx, latitude, longitude)
+	observeUpstream(as.Provider, start, err)
+	return aq, err
+}
+
+func (as *AirQualitySource) GetAirQualityForecastByCoords(ctx context.Context, latitude float32, longitude float32) ([]domain.RepoAirQuality, error) {
+	start := time.Now()
+	aqs, err := as.Next.GetAirQualityForecastByCoords(ctx, latitude, longitude)
+	observeUpstream(as.Provider, start, err)
+	return aqs, err
+}
+
+// UVSource instruments calls to a UV index provider
+type UVSource struct {
+	Next     domain.UVSource
+	Provider string
+}
+
+func (us *UVSource) GetUVByCoords(ctx context.Context, latitude float32, longitude float32) ([]domain.UVReading, error) {
+	start := time.Now()
+	readings, err := us.Next.GetUVByCoords(ctx, latitude, longitude)
+	observeUpstream(us.Provider, start, err)
+	return readings, err
+}
+
+// HistorySource instruments calls to a weather history provider
+type HistorySource struct {
+	Next     domain.HistorySource
+	Provider string
+}
+
+func (hs *HistorySource) GetHistoryByCoords(ctx context.Context, latitude float32, longitude float32, at time.Time) (*domain.RepoWeather, error) {
+	start := time.Now()
+	w, err := hs.Next.GetHistoryByCoords(ctx, latitude, longitude, at)
+	observeUpstream(hs.Provider, start, err)
+	return w, err
+}
+
+func observeUpstream(provider string, start time.Time, err error) {
+	outcome := outcomeOf(err)
+	upstreamCalls.WithLabelValues(provider, outcome).Inc()
+	upstreamDuration.WithLabelValues(provider, outcome).Observe(time.Since(start).Seconds())
+}
+
+func outcomeOf(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	}
+	return OutcomeError
+}
+
+// Service instruments the results of the domain service
+type Service struct {
+	Next domain.Service
+}
+
+func (s *Service) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	start := time.Now()
+	w, err := s.Next.CurrentIn(ctx, lat, lon)
+	observeDomain("current", start, err)
+	if err == nil {
+		temperatures.WithLabelValues(string(w.Temperature)).Inc()
+	}
+	return w, err
+}
+
+// Forecaster instruments the domain's forecasts
+type Forecaster struct {
+	Next domain.Forecaster
+}
+
+func (f *Forecaster) ForecastIn(ctx context.Context, lat float32, lon float32) ([]domain.Weather, error) {
+	start := time.Now()
+	ws, err := f.Next.ForecastIn(ctx, lat, lon)
+	observeDomain("forecast", start, err)
+	return ws, err
+}
+
+// Alerter instruments the domain's alerts
+type Alerter struct {
+	Next domain.Alerter
+}
+
+func (a *Alerter) AlertsIn(ctx context.Context, lat float32, lon float32) ([]domain.Alert, error) {
+	start := time.Now()
+	alerts, err := a.Next.AlertsIn(ctx, lat, lon)
+	observeDomain("alerts", start, err)
+	return alerts, err
+}
+
+func observeDomain(operation string, start time.Time, err error) {
+	outcome := outcomeOf(err)
+	domainCalls.WithLabelValues(operation, outcome).Inc()
+	domainDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+}
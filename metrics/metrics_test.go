@@ -0,0 +1,84 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/metrics"
+)
+
+type mockRepo struct {
+	err error
+}
+
+func (mr *mockRepo) GetByCoords(ctx context.Context, lat float32, lon float32) (*domain.RepoWeather, error) {
+	if mr.err != nil {
+		return nil, mr.err
+	}
+	return &domain.RepoWeather{Temperature: 305}, nil
+}
+
+func (mr *mockRepo) GetForecastByCoords(ctx context.Context, lat float32, lon float32) ([]domain.RepoWeather, error) {
+	if mr.err != nil {
+		return nil, mr.err
+	}
+	return []domain.RepoWeather{{Temperature: 305}}, nil
+}
+
+func (mr *mockRepo) GetAlertsByCoords(ctx context.Context, lat float32, lon float32) ([]domain.Alert, error) {
+	if mr.err != nil {
+		return nil, mr.err
+	}
+	return []domain.Alert{}, nil
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	ok := &metrics.Repo{Next: &mockRepo{}, Provider: "test"}
+	failing := &metrics.Repo{Next: &mockRepo{err: errors.New("boom")}, Provider: "test"}
+	slow := &metrics.Repo{Next: &mockRepo{err: fmt.Errorf("waiting: %w", context.DeadlineExceeded)}, Provider: "test"}
+	ok.GetByCoords(ctx, 1, 2)
+	failing.GetByCoords(ctx, 1, 2)
+	slow.GetByCoords(ctx, 1, 2)
+	svc := &metrics.Service{Next: &domain.WeatherService{Source: ok}}
+	if _, err := svc.CurrentIn(ctx, 1, 2); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	forecaster := &metrics.Forecaster{Next: &domain.WeatherService{Source: &mockRepo{}}}
+	if _, err := forecaster.ForecastIn(ctx, 1, 2); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	alerter := &metrics.Alerter{Next: &domain.WeatherService{Source: &mockRepo{err: errors.New("boom")}, SourceAlerts: true}}
+	if _, err := alerter.AlertsIn(ctx, 1, 2); err == nil {
+		t.Fatalf("expected an error")
+	}
+	metrics.ObserveRequest("/things/{id}", http.MethodGet, http.StatusOK, time.Millisecond)
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`weather_upstream_calls_total{outcome="success",provider="test"} 2`,
+		`weather_upstream_calls_total{outcome="error",provider="test"} 1`,
+		`weather_upstream_calls_total{outcome="timeout",provider="test"} 1`,
+		`weather_upstream_call_duration_seconds_count{outcome="success",provider="test"} 2`,
+		`weather_domain_temperature_classifications_total{classification="hot"} 1`,
+		`weather_domain_calls_total{operation="current",outcome="success"} 1`,
+		`weather_domain_calls_total{operation="forecast",outcome="success"} 1`,
+		`weather_domain_calls_total{operation="alerts",outcome="error"} 1`,
+		`weather_domain_call_duration_seconds_count{operation="current",outcome="success"} 1`,
+		`weather_http_requests_total{method="GET",route="/things/{id}",status="200"} 1`,
+		`weather_http_request_duration_seconds_count{method="GET",route="/things/{id}",status="200"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain '%v'", want)
+		}
+	}
+}
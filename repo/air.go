@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetAirQualityByCoords retrieves the current air pollution for a set of coordinates
+func (ow *OpenWeather) GetAirQualityByCoords(ctx context.Context, lat float32, lon float32) (aq *domain.RepoAirQuality, err error) {
+	ctx, span := tracer.Start(ctx, "openweather.GetAirQualityByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting air quality")
+		}
+		span.End()
+	}()
+	item := airPollutionResponse{}
+	if err := ow.get(ctx, "air_pollution", lat, lon, &item); err != nil {
+		return nil, fmt.Errorf("air quality by coordinates: %w", err)
+	}
+	steps := fromAirPollution(&item)
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("air quality by coordinates: no measurements")
+	}
+	return &steps[0], nil
+}
+
+// GetAirQualityForecastByCoords retrieves the hourly air pollution forecast for a set of coordinates, in time order
+func (ow *OpenWeather) GetAirQualityForecastByCoords(ctx context.Context, lat float32, lon float32) (aqs []domain.RepoAirQuality, err error) {
+	ctx, span := tracer.Start(ctx, "openweather.GetAirQualityForecastByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting air quality forecast")
+		}
+		span.End()
+	}()
+	item := airPollutionResponse{}
+	if err := ow.get(ctx, "air_pollution/forecast", lat, lon, &item); err != nil {
+		return nil, fmt.Errorf("air quality forecast by coordinates: %w", err)
+	}
+	return fromAirPollution(&item), nil
+}
+
+func fromAirPollution(item *airPollutionResponse) []domain.RepoAirQuality {
+	fetched := time.Now().UTC()
+	steps := make([]domain.RepoAirQuality, len(item.List))
+	for i, step := range item.List {
+		c := step.Components
+		steps[i] = domain.RepoAirQuality{
+			Coords: domain.Coords{
+				Latitude:  item.Coord.Lat,
+				Longitude: item.Coord.Lon,
+			},
+			Pollutants: domain.Pollutants{
+				CO:   c.CO,
+				NO:   c.NO,
+				NO2:  c.NO2,
+				O3:   c.O3,
+				SO2:  c.SO2,
+				PM25: c.PM25,
+				PM10: c.PM10,
+				NH3:  c.NH3,
+			},
+			ProviderIndex: step.Main.AQI,
+			ObservedAt:    time.Unix(step.DateTime, 0).UTC(),
+			Source: domain.Source{
+				Provider:  Provider,
+				FetchedAt: fetched,
+			},
+		}
+	}
+	return steps
+}
@@ -0,0 +1,74 @@
+package repo_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/repo"
+)
+
+const airPollution = `{
+  "coord": {"lon": 50, "lat": 50},
+  "list": [
+    {"dt": 1606147200, "main": {"aqi": 4}, "components": {"co": 203.609, "no": 0, "no2": 0.396, "o3": 75.102, "so2": 0.648, "pm2_5": 23.253, "pm10": 92.214, "nh3": 0.117}},
+    {"dt": 1606150800, "main": {"aqi": 2}, "components": {"co": 201.94, "no": 0.01, "no2": 0.77, "o3": 68.66, "so2": 0.64, "pm2_5": 3.5, "pm10": 4.1, "nh3": 0.12}}
+  ]
+}`
+
+func TestOpenWeather_GetAirQualityByCoords(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			if r.URL.Query().Get("lat") == "" {
+				t.Errorf("expected coordinates got '%v'", r.URL.Query())
+			}
+			w.Write([]byte(airPollution))
+		}))
+	defer server.Close()
+	ow := repo.OpenWeather{
+		BaseURL: server.URL,
+		Client:  http.DefaultClient,
+		APIid:   "API",
+		Timeout: 5 * time.Second,
+	}
+	ctx := context.Background()
+	got, err := ow.GetAirQualityByCoords(ctx, 50, 50)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	want := domain.Pollutants{CO: 203.609, NO: 0, NO2: 0.396, O3: 75.102, SO2: 0.648, PM25: 23.253, PM10: 92.214, NH3: 0.117}
+	if got.Pollutants != want {
+		t.Errorf("expected '%v' got '%v'", want, got.Pollutants)
+	}
+	if got.ProviderIndex != 4 || !got.ObservedAt.Equal(time.Unix(1606147200, 0)) || got.Source.Provider != "openweather" {
+		t.Errorf("expected the index, time and provider to be read got '%v'", got)
+	}
+
+	forecast, err := ow.GetAirQualityForecastByCoords(ctx, 50, 50)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(forecast) != 2 || forecast[1].ProviderIndex != 2 {
+		t.Errorf("expected both forecast steps got '%v'", forecast)
+	}
+	if len(paths) != 2 || paths[0] != "/air_pollution" || paths[1] != "/air_pollution/forecast" {
+		t.Errorf("expected the air pollution endpoints got '%v'", paths)
+	}
+}
+
+func TestOpenWeather_GetAirQualityByCoords_Empty(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"coord": {"lon": 50, "lat": 50}, "list": []}`))
+		}))
+	defer server.Close()
+	ow := repo.OpenWeather{BaseURL: server.URL, Client: http.DefaultClient, Timeout: 5 * time.Second}
+	if _, err := ow.GetAirQualityByCoords(context.Background(), 50, 50); err == nil {
+		t.Errorf("expected an error for no measurements")
+	}
+}
@@ -0,0 +1,65 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetHistoryByCoords retrieves the weather at a past time for a set of coordinates from One Call's time machine.
+// It has no place name, and the offset it reports is for the time asked about rather than now, so it's left out.
+func (ow *OpenWeather) GetHistoryByCoords(ctx context.Context, lat float32, lon float32, at time.Time) (w *domain.RepoWeather, err error) {
+	ctx, span := tracer.Start(ctx, "openweather.GetHistoryByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting weather history")
+		}
+		span.End()
+	}()
+	item := timeMachineResponse{}
+	u := ow.OneCallURL + "/timemachine?dt=" + strconv.FormatInt(at.Unix(), 10)
+	if err := ow.fetch(ctx, u, lat, lon, &item); err != nil {
+		return nil, fmt.Errorf("weather history by coordinates: %w", err)
+	}
+	if len(item.Data) == 0 {
+		return nil, fmt.Errorf("weather history by coordinates: %w", domain.ErrNoHistory)
+	}
+	data := item.Data[0]
+	states := make([]string, len(data.Weather))
+	phenomena := make([]domain.Phenomenon, 0, len(data.Weather))
+	for index, w := range data.Weather {
+		states[index] = w.Main
+		if p, ok := phenomenonOf(w.ID); ok {
+			phenomena = append(phenomena, p)
+		}
+	}
+	return &domain.RepoWeather{
+		Coords: domain.Coords{
+			Latitude:  item.Lat,
+			Longitude: item.Lon,
+		},
+		States:      states,
+		Phenomena:   phenomena,
+		Temperature: data.Temp,
+		ObservedAt:  time.Unix(data.DateTime, 0).UTC(),
+		Sunrise:     unixOrZero(data.Sunrise),
+		Sunset:      unixOrZero(data.Sunset),
+		Conditions: &domain.Conditions{
+			Humidity:      data.Humidity,
+			WindSpeed:     data.WindSpeed,
+			WindDegrees:   data.WindDeg,
+			Visibility:    metresOrNil(data.Visibility),
+			Precipitation: data.Rain.OneHour + data.Snow.OneHour,
+		},
+		Source: domain.Source{
+			Provider:  Provider,
+			FetchedAt: time.Now().UTC(),
+		},
+	}, nil
+}
@@ -0,0 +1,82 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/repo"
+)
+
+func TestOpenWeather_GetHistoryByCoords(t *testing.T) {
+	visibility := float32(3000)
+	tests := []struct {
+		name     string
+		body     string
+		expected *domain.RepoWeather
+		err      error
+	}{
+		{
+			"observed",
+			`{
+				"lat": 52.2297, "lon": 21.0122, "timezone": "Europe/Warsaw", "timezone_offset": 3600,
+				"data": [{"dt": 1645888976, "sunrise": 1645853361, "sunset": 1645891727, "temp": 38.5, "humidity": 85, "visibility": 3000, "wind_speed": 12, "wind_deg": 80, "snow": {"1h": 0.6}, "weather": [{"id": 699, "main": "Snow"}, {"id": 951, "main": "Calm"}]}]
+			}`,
+			&domain.RepoWeather{
+				Coords: domain.Coords{Latitude: 52.2297, Longitude: 21.0122},
+				States: []string{"Snow", "Calm"},
+				// an ID Open Weather added later is its group's, and one outside the groups is left out
+				Phenomena:   lookupPhenomena("snow"),
+				Temperature: 38.5,
+				ObservedAt:  time.Unix(1645888976, 0).UTC(),
+				Sunrise:     time.Unix(1645853361, 0).UTC(),
+				Sunset:      time.Unix(1645891727, 0).UTC(),
+				Conditions:  &domain.Conditions{Humidity: 85, WindSpeed: 12, WindDegrees: 80, Visibility: &visibility, Precipitation: 0.6},
+				Source:      domain.Source{Provider: "openweather"},
+			},
+			nil,
+		},
+		{"empty", `{"lat": 52.2297, "lon": 21.0122, "data": []}`, nil, domain.ErrNoHistory},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var path string
+			var query url.Values
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					path, query = r.URL.Path, r.URL.Query()
+					w.Write([]byte(test.body))
+				}))
+			defer server.Close()
+			ow := repo.OpenWeather{
+				OneCallURL: server.URL + "/data/3.0/onecall",
+				Client:     http.DefaultClient,
+				APIid:      "API",
+				Timeout:    5 * time.Second,
+			}
+			got, err := ow.GetHistoryByCoords(context.Background(), 52.2297, 21.0122, time.Unix(1645888976, 0))
+			if path != "/data/3.0/onecall/timemachine" || query.Get("dt") != "1645888976" || query.Get("lat") == "" {
+				t.Errorf("expected the time machine at dt 1645888976 got '%v?%v'", path, query)
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if got == nil {
+				return
+			}
+			if got.Source.FetchedAt.IsZero() {
+				t.Errorf("expected fetched at time to be set")
+			}
+			got.Source.FetchedAt = time.Time{}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NWSProvider is the name National Weather Service data is attributed to
+const NWSProvider = "nws"
+
+// NWS gets alerts from the US National Weather Service API (https://www.weather.gov/documentation/services-web-api).
+// It only covers the US and its territories, elsewhere there are never any alerts.
+type NWS struct {
+	BaseURL string
+	Client  *http.Client
+	// UserAgent identifies the application to the NWS, which requires one with contact details
+	UserAgent string
+	Timeout   time.Duration
+}
+
+// GetAlertsByCoords retrieves the active alerts for a set of coordinates.
+// The alert expires when the hazard is expected to end, if the NWS says, otherwise when the message does.
+func (n *NWS) GetAlertsByCoords(ctx context.Context, lat float32, lon float32) (alerts []domain.Alert, err error) {
+	ctx, span := tracer.Start(ctx, "nws.GetAlertsByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting alerts")
+		}
+		span.End()
+	}()
+	ctx, cancel := context.WithTimeout(ctx, n.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.BaseURL+"/alerts/active", nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating nws request: %w", err)
+	}
+	req.Header.Set("Accept", "application/geo+json")
+	req.Header.Set("User-Agent", n.UserAgent)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	q := req.URL.Query()
+	// the NWS rejects more than 4 decimal places
+	q.Add("point", fmt.Sprintf("%.4f,%.4f", lat, lon))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("executing request: %w", err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		var body string
+		if b, err := io.ReadAll(resp.Body); err == nil {
+			body = string(b)
+		}
+		return nil, fmt.Errorf("unexpected status (%s): %s", http.StatusText(resp.StatusCode), body)
+	}
+	item := nwsAlertsResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+		return nil, fmt.Errorf("decoding nws alerts response body: %w", err)
+	}
+
+	fetched := time.Now().UTC()
+	alerts = make([]domain.Alert, 0, len(item.Features))
+	for _, f := range item.Features {
+		p := f.Properties
+		// tests and exercises aren't real alerts
+		if p.Status != "" && p.Status != "Actual" {
+			continue
+		}
+		onset := p.Onset
+		if onset == nil {
+			onset = p.Effective
+		}
+		expires := p.Ends
+		if expires == nil {
+			expires = p.Expires
+		}
+		alerts = append(alerts, domain.Alert{
+			ID:          p.ID,
+			Event:       p.Event,
+			Headline:    p.Headline,
+			Description: strings.TrimSpace(p.Description),
+			Instruction: strings.TrimSpace(p.Instruction),
+			Sender:      p.SenderName,
+			Severity:    domain.ParseSeverity(p.Severity),
+			Urgency:     domain.ParseUrgency(p.Urgency),
+			Certainty:   domain.ParseCertainty(p.Certainty),
+			Onset:       utcOrZero(onset),
+			Expires:     utcOrZero(expires),
+			AreaDesc:    p.AreaDesc,
+			Source: domain.Source{
+				Provider:  NWSProvider,
+				FetchedAt: fetched,
+			},
+		})
+	}
+	return alerts, nil
+}
+
+func utcOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
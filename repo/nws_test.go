@@ -0,0 +1,92 @@
+package repo_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/repo"
+)
+
+func TestNWS_GetAlertsByCoords(t *testing.T) {
+	var point, agent string
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			point = r.URL.Query().Get("point")
+			agent = r.Header.Get("User-Agent")
+			w.Header().Set("Content-Type", "application/geo+json")
+			w.Write([]byte(`{
+				"type": "FeatureCollection",
+				"features": [
+				  {"properties": {
+					"id": "urn:oid:2.49.0.1.840.0.1",
+					"status": "Actual",
+					"event": "Tornado Warning",
+					"headline": "Tornado Warning issued by NWS Norman",
+					"description": "A tornado was observed.",
+					"instruction": "TAKE COVER NOW!",
+					"senderName": "NWS Norman OK",
+					"severity": "Extreme",
+					"urgency": "Immediate",
+					"effective": "2024-05-06T22:01:00-05:00",
+					"onset": null,
+					"expires": "2024-05-06T22:45:00-05:00",
+					"ends": "2024-05-06T22:30:00-05:00"
+				  }},
+				  {"properties": {
+					"id": "urn:oid:2.49.0.1.840.0.2",
+					"status": "Test",
+					"event": "Test Message",
+					"severity": "Unknown",
+					"urgency": "Unknown"
+				  }},
+				  {"properties": {
+					"id": "urn:oid:2.49.0.1.840.0.3",
+					"status": "Actual",
+					"event": "Flood Watch",
+					"severity": "Moderate",
+					"urgency": "Future",
+					"onset": "2024-05-07T06:00:00-05:00",
+					"expires": "2024-05-07T18:00:00-05:00",
+					"ends": null
+				  }}
+				]
+			  }`))
+		}))
+	defer server.Close()
+	n := repo.NWS{
+		BaseURL:   server.URL,
+		Client:    http.DefaultClient,
+		UserAgent: "weather-exercise (ops@example.com)",
+		Timeout:   5 * time.Second,
+	}
+	got, err := n.GetAlertsByCoords(context.Background(), 35.4676, -97.5164)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if point != "35.4676,-97.5164" {
+		t.Errorf("expected '35.4676,-97.5164' got '%v'", point)
+	}
+	if agent != n.UserAgent {
+		t.Errorf("expected '%v' got '%v'", n.UserAgent, agent)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected the test message to be skipped got '%v'", got)
+	}
+	tornado := got[0]
+	if tornado.Severity != domain.SeverityExtreme || tornado.Urgency != domain.UrgencyImmediate || tornado.Source.Provider != "nws" {
+		t.Errorf("expected an extreme, immediate, nws alert got '%v'", tornado)
+	}
+	if want := time.Date(2024, 5, 7, 3, 1, 0, 0, time.UTC); !tornado.Onset.Equal(want) {
+		t.Errorf("expected onset to fall back to effective '%v' got '%v'", want, tornado.Onset)
+	}
+	if want := time.Date(2024, 5, 7, 3, 30, 0, 0, time.UTC); !tornado.Expires.Equal(want) {
+		t.Errorf("expected expiry to be when it ends '%v' got '%v'", want, tornado.Expires)
+	}
+	if want := time.Date(2024, 5, 7, 23, 0, 0, 0, time.UTC); !got[1].Expires.Equal(want) {
+		t.Errorf("expected expiry to fall back to expires '%v' got '%v'", want, got[1].Expires)
+	}
+}
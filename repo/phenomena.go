@@ -0,0 +1,87 @@
+package repo
+
+import "github.com/broganross/weather-exercise/domain"
+
+// phenomenonCodes maps Open Weather's condition IDs to the domain's phenomena
+var phenomenonCodes = map[int]string{
+	200: "thunderstorm_light_rain",
+	201: "thunderstorm_rain",
+	202: "thunderstorm_heavy_rain",
+	210: "light_thunderstorm",
+	211: "thunderstorm",
+	212: "heavy_thunderstorm",
+	221: "ragged_thunderstorm",
+	230: "thunderstorm_light_drizzle",
+	231: "thunderstorm_drizzle",
+	232: "thunderstorm_heavy_drizzle",
+
+	300: "light_drizzle",
+	301: "drizzle",
+	302: "heavy_drizzle",
+	310: "light_drizzle_rain",
+	311: "drizzle_rain",
+	312: "heavy_drizzle_rain",
+	313: "drizzle_rain_showers",
+	314: "heavy_drizzle_rain_showers",
+	321: "drizzle_showers",
+
+	500: "light_rain",
+	501: "moderate_rain",
+	502: "heavy_rain",
+	503: "very_heavy_rain",
+	504: "extreme_rain",
+	511: "freezing_rain",
+	520: "light_rain_showers",
+	521: "rain_showers",
+	522: "heavy_rain_showers",
+	531: "ragged_rain_showers",
+
+	600: "light_snow",
+	601: "snow",
+	602: "heavy_snow",
+	611: "sleet",
+	612: "light_sleet_showers",
+	613: "sleet_showers",
+	615: "light_rain_snow",
+	616: "rain_snow",
+	620: "light_snow_showers",
+	621: "snow_showers",
+	622: "heavy_snow_showers",
+
+	701: "mist",
+	711: "smoke",
+	721: "haze",
+	731: "dust_whirls",
+	741: "fog",
+	751: "sand",
+	761: "dust",
+	762: "volcanic_ash",
+	771: "squalls",
+	781: "tornado",
+
+	800: "clear",
+	801: "few_clouds",
+	802: "scattered_clouds",
+	803: "broken_clouds",
+	804: "overcast_clouds",
+}
+
+// groupCodes are the phenomena for IDs Open Weather adds later, by their group
+var groupCodes = map[int]string{
+	2: "thunderstorm",
+	3: "drizzle",
+	5: "moderate_rain",
+	6: "snow",
+	7: "mist",
+	8: "overcast_clouds",
+}
+
+// phenomenonOf is the phenomenon for an Open Weather condition ID, falling back to its group's
+// when it's new.  It's false for IDs outside the groups.
+func phenomenonOf(id int) (domain.Phenomenon, bool) {
+	code, ok := phenomenonCodes[id]
+	if !ok {
+		code = groupCodes[id/100]
+	}
+	return domain.LookupPhenomenon(code)
+}
@@ -2,15 +2,26 @@ package repo
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/broganross/weather-exercise/repo")
+
 type WeatherState struct {
 	ID          int
 	Name        string
@@ -34,58 +45,301 @@ type Weather struct {
 	Temperature temperature
 }
 
+// Provider is the name Open Weather data is attributed to
+const Provider = "openweather"
+
 type OpenWeather struct {
 	BaseURL string
-	Client  *http.Client
-	APIid   string
-	Timeout time.Duration
+	// OneCallURL is the One Call endpoint alerts come from, it's versioned separately from BaseURL
+	OneCallURL string
+	Client     *http.Client
+	APIid      string
+	Timeout    time.Duration
 }
 
 // GetByCoords retrieves current weather data for a set of coordinates
-func (ow *OpenWeather) GetByCoords(ctx context.Context, lat float32, lon float32) (*domain.RepoWeather, error) {
-	u := fmt.Sprintf("%s/weather", ow.BaseURL)
+func (ow *OpenWeather) GetByCoords(ctx context.Context, lat float32, lon float32) (w *domain.RepoWeather, err error) {
+	ctx, span := tracer.Start(ctx, "openweather.GetByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting current weather")
+		}
+		span.End()
+	}()
+	item := currentWeatherResponse{}
+	if err := ow.get(ctx, "weather", lat, lon, &item); err != nil {
+		return nil, fmt.Errorf("current weather by coordinates: %w", err)
+	}
+	states := make([]string, len(item.Weather))
+	phenomena := make([]domain.Phenomenon, 0, len(item.Weather))
+	for index, w := range item.Weather {
+		states[index] = w.Main
+		if p, ok := phenomenonOf(w.ID); ok {
+			phenomena = append(phenomena, p)
+		}
+	}
+	// this only exists because the domain only converts states, and temperature.
+	w = &domain.RepoWeather{
+		Coords: domain.Coords{
+			Latitude:  item.Coord.Lat,
+			Longitude: item.Coord.Lon,
+		},
+		Place: domain.Place{
+			Name:    item.Name,
+			Country: item.Sys.Country,
+		},
+		States:      states,
+		Phenomena:   phenomena,
+		Temperature: item.Main.Temp,
+		ObservedAt:  time.Unix(item.DateTime, 0).UTC(),
+		Sunrise:     unixOrZero(item.Sys.Sunrise),
+		Sunset:      unixOrZero(item.Sys.Sunset),
+		UTCOffset:   offsetOrNil(item.Timezone),
+		Conditions: &domain.Conditions{
+			Humidity:      float32(item.Main.Humidity),
+			WindSpeed:     item.Wind.Speed,
+			WindDegrees:   float32(item.Wind.Degrees),
+			Visibility:    metresOrNil(item.Visibility),
+			Precipitation: item.Rain.OneHour + item.Snow.OneHour,
+		},
+		Source: domain.Source{
+			Provider:  Provider,
+			FetchedAt: time.Now().UTC(),
+		},
+	}
+	return w, nil
+}
+
+// GetForecastByCoords retrieves the 5 day forecast, in 3 hour steps, for a set of coordinates.
+// ObservedAt is the time each step is forecast for.
+func (ow *OpenWeather) GetForecastByCoords(ctx context.Context, lat float32, lon float32) (ws []domain.RepoWeather, err error) {
+	ctx, span := tracer.Start(ctx, "openweather.GetForecastByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting forecast")
+		}
+		span.End()
+	}()
+	item := forecastResponse{}
+	if err := ow.get(ctx, "forecast", lat, lon, &item); err != nil {
+		return nil, fmt.Errorf("forecast by coordinates: %w", err)
+	}
+	fetched := time.Now().UTC()
+	ws = make([]domain.RepoWeather, len(item.List))
+	for i, step := range item.List {
+		states := make([]string, len(step.Weather))
+		phenomena := make([]domain.Phenomenon, 0, len(step.Weather))
+		for index, w := range step.Weather {
+			states[index] = w.Main
+			if p, ok := phenomenonOf(w.ID); ok {
+				phenomena = append(phenomena, p)
+			}
+		}
+		ws[i] = domain.RepoWeather{
+			Coords: domain.Coords{
+				Latitude:  item.City.Coord.Lat,
+				Longitude: item.City.Coord.Lon,
+			},
+			Place: domain.Place{
+				Name:    item.City.Name,
+				Country: item.City.Country,
+			},
+			States:      states,
+			Phenomena:   phenomena,
+			Temperature: step.Main.Temp,
+			ObservedAt:  time.Unix(step.DateTime, 0).UTC(),
+			// only today's are given, the domain moves them to each step's day
+			Sunrise:   unixOrZero(item.City.Sunrise),
+			Sunset:    unixOrZero(item.City.Sunset),
+			UTCOffset: offsetOrNil(item.City.Timezone),
+			Source: domain.Source{
+				Provider:  Provider,
+				FetchedAt: fetched,
+			},
+		}
+	}
+	return ws, nil
+}
+
+// GetAlertsByCoords retrieves the government weather alerts for a set of coordinates from One Call.
+// Open Weather only passes along the event name, so severity is guessed from the usual naming of
+// warnings, watches and advisories, and urgency from when the alert starts.
+func (ow *OpenWeather) GetAlertsByCoords(ctx context.Context, lat float32, lon float32) (alerts []domain.Alert, err error) {
+	ctx, span := tracer.Start(ctx, "openweather.GetAlertsByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting alerts")
+		}
+		span.End()
+	}()
+	item := oneCallResponse{}
+	// only the alerts are needed
+	u := ow.OneCallURL + "?exclude=current,minutely,hourly,daily"
+	if err := ow.fetch(ctx, u, lat, lon, &item); err != nil {
+		return nil, fmt.Errorf("alerts by coordinates: %w", err)
+	}
+	now := time.Now().UTC()
+	alerts = make([]domain.Alert, len(item.Alerts))
+	for i, a := range item.Alerts {
+		onset := time.Unix(a.Start, 0).UTC()
+		// there's no ID, but the sender only issues an event once from a given start
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d", a.SenderName, a.Event, a.Start)))
+		alerts[i] = domain.Alert{
+			ID:          hex.EncodeToString(sum[:8]),
+			Event:       a.Event,
+			Headline:    a.Event,
+			Description: strings.TrimSpace(a.Description),
+			Sender:      a.SenderName,
+			Severity:    severityOfEvent(a.Event),
+			Urgency:     urgencyOfOnset(onset, now),
+			Certainty:   domain.CertaintyUnknown,
+			Onset:       onset,
+			Expires:     time.Unix(a.End, 0).UTC(),
+			Source: domain.Source{
+				Provider:  Provider,
+				FetchedAt: now,
+			},
+		}
+	}
+	return alerts, nil
+}
+
+// GetUVByCoords retrieves the current UV index, and the next 48 hours of it, for a set of coordinates from One Call
+func (ow *OpenWeather) GetUVByCoords(ctx context.Context, lat float32, lon float32) (readings []domain.UVReading, err error) {
+	ctx, span := tracer.Start(ctx, "openweather.GetUVByCoords", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "getting uv index")
+		}
+		span.End()
+	}()
+	item := oneCallResponse{}
+	u := ow.OneCallURL + "?exclude=minutely,daily,alerts"
+	if err := ow.fetch(ctx, u, lat, lon, &item); err != nil {
+		return nil, fmt.Errorf("uv index by coordinates: %w", err)
+	}
+	readings = make([]domain.UVReading, 0, len(item.Hourly)+1)
+	readings = append(readings, domain.UVReading{At: time.Unix(item.Current.DateTime, 0).UTC(), Index: item.Current.UVI})
+	for _, h := range item.Hourly {
+		// the first hour is the one current is in
+		if h.DateTime <= item.Current.DateTime {
+			continue
+		}
+		readings = append(readings, domain.UVReading{At: time.Unix(h.DateTime, 0).UTC(), Index: h.UVI})
+	}
+	return readings, nil
+}
+
+// unixOrZero converts a unix time, leaving zero, which the provider uses for unknown, as the zero time
+func unixOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// offsetOrNil converts an offset from UTC in seconds, leaving it nil when the provider left it out
+func offsetOrNil(sec *int) *time.Duration {
+	if sec == nil {
+		return nil
+	}
+	offset := time.Duration(*sec) * time.Second
+	return &offset
+}
+
+// metresOrNil converts a distance, leaving it nil when the provider left it out
+func metresOrNil(m *int) *float32 {
+	if m == nil {
+		return nil
+	}
+	f := float32(*m)
+	return &f
+}
+
+// severityOfEvent guesses the severity from the event name, following the NWS naming most services share
+func severityOfEvent(event string) domain.AlertSeverity {
+	e := strings.ToLower(event)
+	switch {
+	case strings.Contains(e, "emergency"):
+		return domain.SeverityExtreme
+	case strings.Contains(e, "warning"):
+		return domain.SeveritySevere
+	case strings.Contains(e, "watch"):
+		return domain.SeverityModerate
+	case strings.Contains(e, "advisory"), strings.Contains(e, "statement"):
+		return domain.SeverityMinor
+	}
+	return domain.SeverityUnknown
+}
+
+// urgencyOfOnset is immediate for alerts that have started, expected for those starting within the hour, otherwise future
+func urgencyOfOnset(onset time.Time, now time.Time) domain.AlertUrgency {
+	switch {
+	case !onset.After(now):
+		return domain.UrgencyImmediate
+	case onset.Sub(now) <= time.Hour:
+		return domain.UrgencyExpected
+	}
+	return domain.UrgencyFuture
+}
+
+// get requests an Open Weather endpoint for a set of coordinates, decoding the response into v
+func (ow *OpenWeather) get(ctx context.Context, endpoint string, lat float32, lon float32, v any) error {
+	return ow.fetch(ctx, fmt.Sprintf("%s/%s", ow.BaseURL, endpoint), lat, lon, v)
+}
+
+// openWeatherLanguages are Open Weather's codes for the languages responses are translated into,
+// it translates place names and descriptions.  English is its default.
+var openWeatherLanguages = map[string]string{
+	"de": "de",
+	"es": "es",
+	"fr": "fr",
+	"it": "it",
+	"ja": "ja",
+	"pt": "pt",
+	"zh": "zh_cn",
+}
+
+// fetch requests an Open Weather URL for a set of coordinates, decoding the response into v.
+// Any query parameters already in the URL are kept.
+func (ow *OpenWeather) fetch(ctx context.Context, u string, lat float32, lon float32, v any) error {
+	span := trace.SpanFromContext(ctx)
 	ctx, cancel := context.WithTimeout(ctx, ow.Timeout)
 	defer cancel()
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating open weather request: %w", err)
+		return fmt.Errorf("creating open weather request: %w", err)
 	}
 	req.Header.Add("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 	q := req.URL.Query()
 	q.Add("lat", fmt.Sprintf("%02f", lat))
 	q.Add("lon", fmt.Sprintf("%02f", lon))
 	q.Add("appid", ow.APIid)
+	if lang, ok := openWeatherLanguages[i18n.FromContext(ctx)]; ok {
+		q.Add("lang", lang)
+	}
 	req.URL.RawQuery = q.Encode()
 
 	resp, err := ow.Client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+		return fmt.Errorf("executing request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		var body string
 		if b, err := io.ReadAll(resp.Body); err == nil {
 			body = string(b)
 		}
-		err := fmt.Errorf("current weather by coordinates (%s): %s", http.StatusText(resp.StatusCode), body)
-		return nil, err
+		return fmt.Errorf("unexpected status (%s): %s", http.StatusText(resp.StatusCode), body)
 	}
-	item := currentWeatherResponse{}
-	if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
-		return nil, fmt.Errorf("decoding current weather response body: %w", err)
-	}
-	states := make([]string, len(item.Weather))
-	for index, w := range item.Weather {
-		states[index] = w.Main
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding %s response body: %w", req.URL.Path, err)
 	}
-	// this only exists because the domain only converts states, and temperature.
-	w := &domain.RepoWeather{
-		Coords: domain.Coords{
-			Latitude:  item.Coord.Lat,
-			Longitude: item.Coord.Lon,
-		},
-		States:      states,
-		Temperature: item.Main.Temp,
-	}
-	return w, nil
+	return nil
 }
@@ -2,19 +2,33 @@ package repo_test
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
 	"github.com/broganross/weather-exercise/repo"
 )
 
+// lookupPhenomena finds the phenomena with the codes
+func lookupPhenomena(codes ...string) []domain.Phenomenon {
+	ps := make([]domain.Phenomenon, len(codes))
+	for i, code := range codes {
+		ps[i], _ = domain.LookupPhenomenon(code)
+	}
+	return ps
+}
+
 func TestOpenWeather_GetByCoords(t *testing.T) {
+	var units string
 	server := httptest.NewServer(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			units = r.URL.Query().Get("units")
 			w.Write([]byte(`{
 				"coord": {
 				  "lat": 10.1,
@@ -66,13 +80,34 @@ func TestOpenWeather_GetByCoords(t *testing.T) {
 			  }`))
 		}))
 	defer server.Close()
+	offset := 2 * time.Hour
+	visibility := float32(10000)
 	want := &domain.RepoWeather{
 		Coords: domain.Coords{
 			Latitude:  10.1,
 			Longitude: 22.2,
 		},
+		Place: domain.Place{
+			Name:    "Zocca",
+			Country: "IT",
+		},
 		States:      []string{"Rain"},
+		Phenomena:   lookupPhenomena("moderate_rain"),
 		Temperature: 298.48,
+		ObservedAt:  time.Unix(1661870592, 0).UTC(),
+		Sunrise:     time.Unix(1661834187, 0).UTC(),
+		Sunset:      time.Unix(1661882248, 0).UTC(),
+		UTCOffset:   &offset,
+		Conditions: &domain.Conditions{
+			Humidity:      64,
+			WindSpeed:     0.62,
+			WindDegrees:   349,
+			Visibility:    &visibility,
+			Precipitation: 3.16,
+		},
+		Source: domain.Source{
+			Provider: "openweather",
+		},
 	}
 	ow := repo.OpenWeather{
 		BaseURL: server.URL,
@@ -86,8 +121,212 @@ func TestOpenWeather_GetByCoords(t *testing.T) {
 		t.Errorf("got unexpected error: '%v'", err)
 		return
 	}
+	if got.Source.FetchedAt.IsZero() {
+		t.Errorf("expected fetched at time to be set")
+	}
+	got.Source.FetchedAt = time.Time{}
+	// the domain converts from the provider's standard units
+	if units != "" {
+		t.Errorf("expected no units got '%v'", units)
+	}
 	// Shouldn't actually use DeepEqual
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("expected '%v' got '%v'", want, got)
 	}
 }
+
+func TestOpenWeather_language(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{"unset", context.Background(), ""},
+		{"default", i18n.NewContext(context.Background(), "en"), ""},
+		{"supported", i18n.NewContext(context.Background(), "de"), "de"},
+		{"renamed", i18n.NewContext(context.Background(), "zh"), "zh_cn"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lang string
+			server := httptest.NewServer(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					lang = r.URL.Query().Get("lang")
+					w.Write([]byte(`{"weather": [], "main": {"temp": 50}, "dt": 1661870592}`))
+				}))
+			defer server.Close()
+			ow := repo.OpenWeather{
+				BaseURL: server.URL,
+				Client:  http.DefaultClient,
+				APIid:   "API",
+				Timeout: 5 * time.Second,
+			}
+			if _, err := ow.GetByCoords(test.ctx, 10.1, 22.2); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if lang != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, lang)
+			}
+		})
+	}
+}
+
+func TestOpenWeather_GetForecastByCoords(t *testing.T) {
+	var path string
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.Write([]byte(`{
+				"cod": "200",
+				"list": [
+				  {"dt": 1661871600, "main": {"temp": 71.2}, "weather": [{"id": 500, "main": "Rain"}]},
+				  {"dt": 1661882400, "main": {"temp": 65.4}, "weather": [{"id": 800, "main": "Clear"}]}
+				],
+				"city": {"name": "Zocca", "country": "IT", "coord": {"lat": 44.34, "lon": 10.99}, "sunrise": 1661834187, "sunset": 1661882248, "timezone": 7200}
+			  }`))
+		}))
+	defer server.Close()
+	ow := repo.OpenWeather{
+		BaseURL: server.URL,
+		Client:  http.DefaultClient,
+		APIid:   "API",
+		Timeout: 5 * time.Second,
+	}
+	got, err := ow.GetForecastByCoords(context.Background(), 44.34, 10.99)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if path != "/forecast" {
+		t.Errorf("expected '/forecast' got '%v'", path)
+	}
+	offset := 2 * time.Hour
+	want := []domain.RepoWeather{
+		{
+			Coords:      domain.Coords{Latitude: 44.34, Longitude: 10.99},
+			Place:       domain.Place{Name: "Zocca", Country: "IT"},
+			States:      []string{"Rain"},
+			Phenomena:   lookupPhenomena("light_rain"),
+			Temperature: 71.2,
+			ObservedAt:  time.Unix(1661871600, 0).UTC(),
+			Sunrise:     time.Unix(1661834187, 0).UTC(),
+			Sunset:      time.Unix(1661882248, 0).UTC(),
+			UTCOffset:   &offset,
+			Source:      domain.Source{Provider: "openweather"},
+		},
+		{
+			Coords:      domain.Coords{Latitude: 44.34, Longitude: 10.99},
+			Place:       domain.Place{Name: "Zocca", Country: "IT"},
+			States:      []string{"Clear"},
+			Phenomena:   lookupPhenomena("clear"),
+			Temperature: 65.4,
+			ObservedAt:  time.Unix(1661882400, 0).UTC(),
+			Sunrise:     time.Unix(1661834187, 0).UTC(),
+			Sunset:      time.Unix(1661882248, 0).UTC(),
+			UTCOffset:   &offset,
+			Source:      domain.Source{Provider: "openweather"},
+		},
+	}
+	for i := range got {
+		got[i].Source.FetchedAt = time.Time{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected '%v' got '%v'", want, got)
+	}
+}
+
+func TestOpenWeather_GetAlertsByCoords(t *testing.T) {
+	var query url.Values
+	start := time.Now().Add(-time.Hour).Unix()
+	later := time.Now().Add(3 * time.Hour).Unix()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			fmt.Fprintf(w, `{
+				"lat": 33.44,
+				"lon": -94.04,
+				"alerts": [
+				  {"sender_name": "NWS Shreveport", "event": "Severe Thunderstorm Warning", "start": %d, "end": %d, "description": " Damaging winds. \n", "tags": ["Thunderstorm"]},
+				  {"sender_name": "NWS Shreveport", "event": "Heat Advisory", "start": %d, "end": %d, "description": "Hot.", "tags": []},
+				  {"sender_name": "NWS Shreveport", "event": "Special Weather Bulletin", "start": %d, "end": %d, "description": "", "tags": []}
+				]
+			  }`, start, later, later, later+3600, start, later)
+		}))
+	defer server.Close()
+	ow := repo.OpenWeather{
+		OneCallURL: server.URL + "/data/3.0/onecall",
+		Client:     http.DefaultClient,
+		APIid:      "API",
+		Timeout:    5 * time.Second,
+	}
+	got, err := ow.GetAlertsByCoords(context.Background(), 33.44, -94.04)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if query.Get("exclude") != "current,minutely,hourly,daily" || query.Get("lat") == "" {
+		t.Errorf("expected only alerts to be requested got '%v'", query)
+	}
+	tests := []struct {
+		event    string
+		severity domain.AlertSeverity
+		urgency  domain.AlertUrgency
+	}{
+		{"Severe Thunderstorm Warning", domain.SeveritySevere, domain.UrgencyImmediate},
+		{"Heat Advisory", domain.SeverityMinor, domain.UrgencyFuture},
+		{"Special Weather Bulletin", domain.SeverityUnknown, domain.UrgencyImmediate},
+	}
+	if len(got) != len(tests) {
+		t.Fatalf("expected '%v' alerts got '%v'", len(tests), got)
+	}
+	for i, test := range tests {
+		a := got[i]
+		if a.Event != test.event || a.Severity != test.severity || a.Urgency != test.urgency {
+			t.Errorf("expected '%v' '%v' '%v' got '%v' '%v' '%v'", test.event, test.severity, test.urgency, a.Event, a.Severity, a.Urgency)
+		}
+		if a.ID == "" || a.Source.Provider != "openweather" {
+			t.Errorf("expected an ID and provider got '%v'", a)
+		}
+	}
+	if got[0].Description != "Damaging winds." || !got[0].Expires.Equal(time.Unix(later, 0)) {
+		t.Errorf("expected the description and expiry to be read got '%v'", got[0])
+	}
+}
+
+func TestOpenWeather_GetUVByCoords(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			w.Write([]byte(`{
+				"lat": 33.44,
+				"lon": -94.04,
+				"current": {"dt": 1684929490, "uvi": 5.2},
+				"hourly": [
+				  {"dt": 1684926000, "uvi": 4.8},
+				  {"dt": 1684929600, "uvi": 5.6},
+				  {"dt": 1684933200, "uvi": 6.1}
+				]
+			  }`))
+		}))
+	defer server.Close()
+	ow := repo.OpenWeather{
+		OneCallURL: server.URL + "/data/3.0/onecall",
+		Client:     http.DefaultClient,
+		APIid:      "API",
+		Timeout:    5 * time.Second,
+	}
+	got, err := ow.GetUVByCoords(context.Background(), 33.44, -94.04)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if query.Get("exclude") != "minutely,daily,alerts" {
+		t.Errorf("expected only current and hourly to be requested got '%v'", query)
+	}
+	want := []domain.UVReading{
+		{At: time.Unix(1684929490, 0).UTC(), Index: 5.2},
+		{At: time.Unix(1684929600, 0).UTC(), Index: 5.6},
+		{At: time.Unix(1684933200, 0).UTC(), Index: 6.1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected '%v' got '%v'", want, got)
+	}
+}
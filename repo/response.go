@@ -1,5 +1,7 @@
 package repo
 
+import "time"
+
 // TODO: extend to parse unix time stamps correctly, instead of using int64
 type currentWeatherResponse struct {
 	Coord struct {
@@ -23,7 +25,7 @@ type currentWeatherResponse struct {
 		SeaLevel    int     `json:"sea_level"`
 		GroundLevel int     `json:"grnd_level"`
 	} `json:"main"`
-	Visibility int `json:"visibility"`
+	Visibility *int `json:"visibility"`
 	Wind       struct {
 		Speed   float32 `json:"speed"`
 		Degrees int     `json:"deg"`
@@ -48,7 +50,120 @@ type currentWeatherResponse struct {
 		Sunrise int64  `json:"sunrise"`
 		Sunset  int64  `json:"sunset"`
 	} `json:"sys"`
-	Timezone int    `json:"timezone"`
+	Timezone *int   `json:"timezone"`
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
 }
+
+type forecastResponse struct {
+	List []struct {
+		DateTime int64 `json:"dt"`
+		Main     struct {
+			Temp float32 `json:"temp"`
+		} `json:"main"`
+		Weather []struct {
+			ID   int    `json:"id"`
+			Main string `json:"main"`
+		} `json:"weather"`
+	} `json:"list"`
+	City struct {
+		Name     string `json:"name"`
+		Country  string `json:"country"`
+		Sunrise  int64  `json:"sunrise"`
+		Sunset   int64  `json:"sunset"`
+		Timezone *int   `json:"timezone"`
+		Coord    struct {
+			Lat float32 `json:"lat"`
+			Lon float32 `json:"lon"`
+		} `json:"coord"`
+	} `json:"city"`
+}
+
+type oneCallResponse struct {
+	Current struct {
+		DateTime int64   `json:"dt"`
+		UVI      float32 `json:"uvi"`
+	} `json:"current"`
+	Hourly []struct {
+		DateTime int64   `json:"dt"`
+		UVI      float32 `json:"uvi"`
+	} `json:"hourly"`
+	Alerts []struct {
+		SenderName  string   `json:"sender_name"`
+		Event       string   `json:"event"`
+		Start       int64    `json:"start"`
+		End         int64    `json:"end"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+	} `json:"alerts"`
+}
+
+type timeMachineResponse struct {
+	Lat  float32 `json:"lat"`
+	Lon  float32 `json:"lon"`
+	Data []struct {
+		DateTime   int64   `json:"dt"`
+		Sunrise    int64   `json:"sunrise"`
+		Sunset     int64   `json:"sunset"`
+		Temp       float32 `json:"temp"`
+		Humidity   float32 `json:"humidity"`
+		Visibility *int    `json:"visibility"`
+		WindSpeed  float32 `json:"wind_speed"`
+		WindDeg    float32 `json:"wind_deg"`
+		Rain       struct {
+			OneHour float32 `json:"1h"`
+		} `json:"rain"`
+		Snow struct {
+			OneHour float32 `json:"1h"`
+		} `json:"snow"`
+		Weather []struct {
+			ID   int    `json:"id"`
+			Main string `json:"main"`
+		} `json:"weather"`
+	} `json:"data"`
+}
+
+type nwsAlertsResponse struct {
+	Features []struct {
+		Properties struct {
+			ID          string     `json:"id"`
+			Status      string     `json:"status"`
+			Event       string     `json:"event"`
+			Headline    string     `json:"headline"`
+			Description string     `json:"description"`
+			Instruction string     `json:"instruction"`
+			SenderName  string     `json:"senderName"`
+			Severity    string     `json:"severity"`
+			Urgency     string     `json:"urgency"`
+			Certainty   string     `json:"certainty"`
+			AreaDesc    string     `json:"areaDesc"`
+			Effective   *time.Time `json:"effective"`
+			Onset       *time.Time `json:"onset"`
+			Expires     *time.Time `json:"expires"`
+			Ends        *time.Time `json:"ends"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+type airPollutionResponse struct {
+	Coord struct {
+		Lat float32 `json:"lat"`
+		Lon float32 `json:"lon"`
+	} `json:"coord"`
+	List []struct {
+		DateTime int64 `json:"dt"`
+		Main     struct {
+			AQI int `json:"aqi"`
+		} `json:"main"`
+		Components struct {
+			CO   float64 `json:"co"`
+			NO   float64 `json:"no"`
+			NO2  float64 `json:"no2"`
+			O3   float64 `json:"o3"`
+			SO2  float64 `json:"so2"`
+			PM25 float64 `json:"pm2_5"`
+			PM10 float64 `json:"pm10"`
+			NH3  float64 `json:"nh3"`
+		} `json:"components"`
+	} `json:"list"`
+}
@@ -0,0 +1,80 @@
+package repo
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/broganross/weather-exercise/internal/sample"
+	"github.com/broganross/weather-exercise/requestid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// LoggingTransport logs outgoing requests using the logger from the request context,
+// so upstream calls are tied to the incoming request ID.
+// Failed calls are always logged, successful ones are sampled at SampleRate (0 to 1).
+type LoggingTransport struct {
+	Next       http.RoundTripper
+	SampleRate float64
+}
+
+func (lt *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := lt.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	duration := time.Since(start)
+
+	failed := err != nil || resp.StatusCode >= http.StatusBadRequest
+	if !failed && !sample.Keep(lt.SampleRate) {
+		return resp, err
+	}
+	l := log.Ctx(req.Context())
+	var event *zerolog.Event
+	if failed {
+		event = l.Warn().Err(err)
+	} else {
+		event = l.Info()
+	}
+	if resp != nil {
+		event.Int("status", resp.StatusCode)
+	}
+	event.
+		Str("method", req.Method).
+		Str("URL", redactURL(req.URL)).
+		Dur("duration", duration).
+		Msg("upstream request")
+	return resp, err
+}
+
+// RequestIDTransport forwards the request ID from the request context to upstream services
+type RequestIDTransport struct {
+	Next http.RoundTripper
+}
+
+func (rt *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := rt.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	if id := requestid.FromContext(req.Context()); id != "" {
+		// RoundTrippers must not modify the original request
+		req = req.Clone(req.Context())
+		req.Header.Set(requestid.Header, id)
+	}
+	return next.RoundTrip(req)
+}
+
+// redactURL hides credentials passed in the query string
+func redactURL(u *url.URL) string {
+	c := *u
+	q := c.Query()
+	if q.Has("appid") {
+		q.Set("appid", "REDACTED")
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
@@ -0,0 +1,67 @@
+package repo_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/repo"
+	"github.com/broganross/weather-exercise/requestid"
+	"github.com/rs/zerolog"
+)
+
+func TestLoggingTransport_RoundTrip(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+	defer server.Close()
+	buf := &bytes.Buffer{}
+	logger := zerolog.New(buf).With().Str("request-id", "abc").Logger()
+	ow := repo.OpenWeather{
+		BaseURL: server.URL,
+		Client:  &http.Client{Transport: &repo.LoggingTransport{}},
+		APIid:   "secret-key",
+		Timeout: 5 * time.Second,
+	}
+	ctx := logger.WithContext(context.Background())
+	if _, err := ow.GetByCoords(ctx, 1.1, 2.2); err == nil {
+		t.Errorf("expected error for failed request")
+	}
+	s := buf.String()
+	if strings.Contains(s, "secret-key") {
+		t.Errorf("expected API key to be redacted got '%v'", s)
+	}
+	for _, want := range []string{`"request-id":"abc"`, `"status":418`, "appid=REDACTED", `"duration"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("expected log to contain '%v' got '%v'", want, s)
+		}
+	}
+}
+
+func TestRequestIDTransport_RoundTrip(t *testing.T) {
+	var got string
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get(requestid.Header)
+			w.Write([]byte(`{}`))
+		}))
+	defer server.Close()
+	ow := repo.OpenWeather{
+		BaseURL: server.URL,
+		Client:  &http.Client{Transport: &repo.RequestIDTransport{}},
+		APIid:   "API",
+		Timeout: 5 * time.Second,
+	}
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+	if _, err := ow.GetByCoords(ctx, 1.1, 2.2); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if got != "abc-123" {
+		t.Errorf("expected forwarded request id 'abc-123' got '%v'", got)
+	}
+}
@@ -0,0 +1,52 @@
+// Package requestid carries the request ID of an incoming request through the context,
+// so that it can be logged, returned to the client and forwarded to upstream services.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header is the HTTP header used to receive and send request IDs
+const Header = "X-Request-ID"
+
+// MaxLength is the longest client supplied request ID that is accepted
+const MaxLength = 128
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or an empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New generates a new request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether a client supplied ID is safe to log and forward.
+// IDs must be non-empty, no longer than MaxLength, and only contain letters, digits, '-', '_', '.' and ':'.
+func Valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
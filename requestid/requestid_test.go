@@ -0,0 +1,32 @@
+package requestid_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/broganross/weather-exercise/requestid"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"uuid", "0b6a3c0e-5d2f-4a59-9a0c-2b0f3b1f8e7d", true},
+		{"punctuation", "trace:abc_123.4", true},
+		{"empty", "", false},
+		{"too-long", strings.Repeat("a", requestid.MaxLength+1), false},
+		{"max-length", strings.Repeat("a", requestid.MaxLength), true},
+		{"newline", "abc\ndef", false},
+		{"quote", `abc"def`, false},
+		{"unicode", "abcé", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := requestid.Valid(test.id); got != test.want {
+				t.Errorf("expected '%v' got '%v'", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/rs/zerolog/log"
+)
+
+type airQualityAttributes struct {
+	Latitude   preciseFloat32       `json:"latitude"`
+	Longitude  preciseFloat32       `json:"longitude"`
+	ObservedAt time.Time            `json:"observed_at"`
+	AQI        airQualityIndex      `json:"aqi"`
+	CAQI       airQualityIndex      `json:"caqi"`
+	Provider   int                  `json:"provider_index"`
+	Pollutants pollutantsAttributes `json:"pollutants"`
+}
+
+type airQualityIndex struct {
+	Value    int    `json:"value"`
+	Category string `json:"category"`
+	Dominant string `json:"dominant_pollutant"`
+}
+
+// pollutantsAttributes are concentrations in μg/m³
+type pollutantsAttributes struct {
+	CO   float64 `json:"co"`
+	NO   float64 `json:"no"`
+	NO2  float64 `json:"no2"`
+	O3   float64 `json:"o3"`
+	SO2  float64 `json:"so2"`
+	PM25 float64 `json:"pm2_5"`
+	PM10 float64 `json:"pm10"`
+	NH3  float64 `json:"nh3"`
+}
+
+// GetAirQuality responds with the current air quality at the coordinates, with the US AQI and European CAQI
+func (h *Handlers) GetAirQuality(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lat, lon, errs := coordsFromRequest(r)
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "required query parameters")
+		return
+	}
+	aq, err := h.AirQuality.AirQualityIn(ctx, float32(lat), float32(lon))
+	if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("retrieving air quality: %w", err)}, "")
+		return
+	}
+	res := airQualityResource(lat, lon, aq)
+	writeDocument(ctx, w, http.StatusOK, &document{
+		Data:  &res,
+		Links: &links{Self: fmt.Sprintf("/v1/air-quality?latitude=%s&longitude=%s", formatCoord(lat), formatCoord(lon))},
+		Meta:  newSourceMeta(aq.Source),
+	})
+}
+
+// GetAirQualityForecast responds with the hourly air quality forecast at the coordinates, in time order
+func (h *Handlers) GetAirQualityForecast(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lat, lon, errs := coordsFromRequest(r)
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "required query parameters")
+		return
+	}
+	forecast, err := h.AirQuality.AirQualityForecastIn(ctx, float32(lat), float32(lon))
+	if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("retrieving air quality forecast: %w", err)}, "")
+		return
+	}
+	data := make([]resource, len(forecast))
+	for i := range forecast {
+		data[i] = airQualityResource(lat, lon, &forecast[i])
+	}
+	doc := &document{
+		Data:  data,
+		Links: &links{Self: fmt.Sprintf("/v1/air-quality/forecast?latitude=%s&longitude=%s", formatCoord(lat), formatCoord(lon))},
+	}
+	if len(forecast) > 0 {
+		doc.Meta = newSourceMeta(forecast[0].Source)
+	}
+	writeDocument(ctx, w, http.StatusOK, doc)
+}
+
+// airQualityAt starts looking up the air quality at the coordinates, while the current weather is,
+// if it was included.  The returned func waits for the resource, which is nil if the lookup failed;
+// like alerts, air quality never fails the weather response.
+func (h *Handlers) airQualityAt(ctx context.Context, lat float64, lon float64, include map[string]bool) func() *resource {
+	if h.AirQuality == nil || !include[includeAirQuality] {
+		return func() *resource { return nil }
+	}
+	done := make(chan *resource, 1)
+	go func() {
+		aq, err := h.AirQuality.AirQualityIn(ctx, float32(lat), float32(lon))
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("including air quality")
+			done <- nil
+			return
+		}
+		res := airQualityResource(lat, lon, aq)
+		done <- &res
+	}()
+	return func() *resource { return <-done }
+}
+
+// withAirQuality relates the air quality to a current weather document, including it
+func withAirQuality(doc *document, aq *resource) *document {
+	if aq == nil {
+		return doc
+	}
+	if res, ok := doc.Data.(*resource); ok {
+		res.Relationships[includeAirQuality] = relationship{Data: &resourceIdentifier{ID: aq.ID, Type: aq.Type}}
+	}
+	doc.Included = append(doc.Included, *aq)
+	return doc
+}
+
+// currentIncludes are the relationships current weather can include
+func (h *Handlers) currentIncludes() []string {
+	if h.AirQuality == nil {
+		return []string{includeLocation}
+	}
+	return []string{includeLocation, includeAirQuality}
+}
+
+func airQualityResource(lat float64, lon float64, aq *domain.AirQuality) resource {
+	p := aq.Pollutants
+	return resource{
+		ID:   fmt.Sprintf("%s:%s,%s:%d", typeAirQuality, formatCoord(lat), formatCoord(lon), aq.ObservedAt.Unix()),
+		Type: typeAirQuality,
+		Attributes: &airQualityAttributes{
+			Latitude:   preciseFloat32(lat),
+			Longitude:  preciseFloat32(lon),
+			ObservedAt: aq.ObservedAt,
+			AQI:        airQualityIndex(aq.AQI),
+			CAQI:       airQualityIndex(aq.CAQI),
+			Provider:   aq.ProviderIndex,
+			Pollutants: pollutantsAttributes{
+				CO:   p.CO,
+				NO:   p.NO,
+				NO2:  p.NO2,
+				O3:   p.O3,
+				SO2:  p.SO2,
+				PM25: p.PM25,
+				PM10: p.PM10,
+				NH3:  p.NH3,
+			},
+		},
+	}
+}
@@ -0,0 +1,170 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+)
+
+type mockAirQuality struct {
+	err error
+}
+
+var moderateAir = domain.AirQuality{
+	Pollutants:    domain.Pollutants{PM25: 35.4},
+	AQI:           domain.AirQualityIndex{Value: 100, Category: "Moderate", Dominant: domain.PollutantPM25},
+	CAQI:          domain.AirQualityIndex{Value: 55, Category: "Medium", Dominant: domain.PollutantPM25},
+	ProviderIndex: 3,
+	ObservedAt:    time.Date(2024, 5, 7, 12, 0, 0, 0, time.UTC),
+	Source:        domain.Source{Provider: "openweather"},
+}
+
+func (m *mockAirQuality) AirQualityIn(ctx context.Context, lat float32, lon float32) (*domain.AirQuality, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	aq := moderateAir
+	return &aq, nil
+}
+
+func (m *mockAirQuality) AirQualityForecastIn(ctx context.Context, lat float32, lon float32) ([]domain.AirQuality, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	later := moderateAir
+	later.ObservedAt = later.ObservedAt.Add(time.Hour)
+	return []domain.AirQuality{moderateAir, later}, nil
+}
+
+type airQualityDocument struct {
+	Data struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Attributes struct {
+			AQI struct {
+				Value    int    `json:"value"`
+				Category string `json:"category"`
+				Dominant string `json:"dominant_pollutant"`
+			} `json:"aqi"`
+			CAQI struct {
+				Value int `json:"value"`
+			} `json:"caqi"`
+			Pollutants map[string]float64 `json:"pollutants"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func TestHandlers_GetAirQuality(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		aq   *mockAirQuality
+		code int
+	}{
+		{"air-quality", "http://localhost/v1/air-quality?latitude=35.4&longitude=-97.5", &mockAirQuality{}, http.StatusOK},
+		{"missing-latitude", "http://localhost/v1/air-quality?longitude=-97.5", &mockAirQuality{}, http.StatusBadRequest},
+		{"failing", "http://localhost/v1/air-quality?latitude=35.4&longitude=-97.5", &mockAirQuality{err: errors.New("boom")}, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := server.Handlers{AirQuality: test.aq}
+			w := httptest.NewRecorder()
+			h.GetAirQuality(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+			doc := airQualityDocument{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			attrs := doc.Data.Attributes
+			if doc.Data.Type != "urn:weather:air-quality" || attrs.AQI.Value != 100 || attrs.AQI.Dominant != "pm2_5" || attrs.CAQI.Value != 55 {
+				t.Errorf("expected the air quality got '%s'", w.Body.String())
+			}
+			if attrs.Pollutants["pm2_5"] != 35.4 {
+				t.Errorf("expected '%v' got '%v'", 35.4, attrs.Pollutants["pm2_5"])
+			}
+		})
+	}
+}
+
+func TestHandlers_GetAirQualityForecast(t *testing.T) {
+	h := server.Handlers{AirQuality: &mockAirQuality{}}
+	w := httptest.NewRecorder()
+	h.GetAirQualityForecast(w, httptest.NewRequest(http.MethodGet, "http://localhost/v1/air-quality/forecast?latitude=35.4&longitude=-97.5", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected code '%v' got '%v': %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	doc := struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(doc.Data) != 2 || doc.Data[0].ID == doc.Data[1].ID {
+		t.Errorf("expected two steps with their own IDs got '%s'", w.Body.String())
+	}
+}
+
+func TestHandlers_GetCurrentByCoords_AirQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		aq       domain.AirQualityReporter
+		code     int
+		included bool
+	}{
+		{"included", "http://localhost/v1/weather/current?latitude=35.4&longitude=-97.5&include=air_quality", &mockAirQuality{}, http.StatusOK, true},
+		{"not-requested", "http://localhost/v1/weather/current?latitude=35.4&longitude=-97.5", &mockAirQuality{}, http.StatusOK, false},
+		{"failing-air-quality", "http://localhost/v1/weather/current?latitude=35.4&longitude=-97.5&include=air_quality", &mockAirQuality{err: errors.New("boom")}, http.StatusOK, false},
+		{"no-air-quality-service", "http://localhost/v1/weather/current?latitude=35.4&longitude=-97.5&include=air_quality", nil, http.StatusBadRequest, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := server.Handlers{Domain: &uniformDomain{}, AirQuality: test.aq}
+			w := httptest.NewRecorder()
+			h.GetCurrentByCoords(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+			doc := struct {
+				Data struct {
+					Relationships map[string]struct {
+						Data struct {
+							ID string `json:"id"`
+						} `json:"data"`
+					} `json:"relationships"`
+				} `json:"data"`
+				Included []struct {
+					ID   string `json:"id"`
+					Type string `json:"type"`
+				} `json:"included"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			rel, related := doc.Data.Relationships["air_quality"]
+			if related != test.included || (len(doc.Included) == 1) != test.included {
+				t.Fatalf("expected included '%v' got '%s'", test.included, w.Body.String())
+			}
+			if test.included && (doc.Included[0].Type != "urn:weather:air-quality" || doc.Included[0].ID != rel.Data.ID) {
+				t.Errorf("expected the relationship to point at the included air quality got '%s'", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/broganross/weather-exercise/capxml"
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+	"github.com/rs/zerolog/log"
+)
+
+type alertAttributes struct {
+	Event       string     `json:"event"`
+	Headline    string     `json:"headline,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Instruction string     `json:"instruction,omitempty"`
+	Sender      string     `json:"sender,omitempty"`
+	Severity    string     `json:"severity"`
+	Urgency     string     `json:"urgency"`
+	Onset       *time.Time `json:"onset,omitempty"`
+	Expires     *time.Time `json:"expires,omitempty"`
+	Provider    string     `json:"provider"`
+}
+
+// capper is implemented by responses that can be written as a CAP message
+type capper interface {
+	capAlert() *capxml.Alert
+}
+
+// alertsDocument is a document of alerts, which can also be written as a CAP message
+type alertsDocument struct {
+	*document
+	message *capxml.Alert
+}
+
+func (d *alertsDocument) capAlert() *capxml.Alert {
+	return d.message
+}
+
+// alertSummary is embedded in current weather, so clients know to look at the alerts
+type alertSummary struct {
+	Count    int      `json:"count"`
+	Severity string   `json:"severity"`
+	Events   []string `json:"events"`
+}
+
+// GetAlerts responds with the active severe weather alerts at the coordinates, most severe first.
+// It can be negotiated as application/cap+xml, a single message with an info per alert.
+func (h *Handlers) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lat, lon, errs := coordsFromRequest(r)
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "required query parameters")
+		return
+	}
+	alerts, err := h.Alerts.AlertsIn(ctx, float32(lat), float32(lon))
+	if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("retrieving alerts: %w", err)}, "")
+		return
+	}
+	data := make([]resource, len(alerts))
+	for i := range alerts {
+		data[i] = alertResource(&alerts[i])
+	}
+	self := fmt.Sprintf("/v1/alerts?latitude=%s&longitude=%s", formatCoord(lat), formatCoord(lon))
+	at := geo.Point{Lon: lon, Lat: lat}
+	writeResponse(ctx, w, http.StatusOK, jsonAPIMediaType, &alertsDocument{
+		document: &document{
+			Data:  data,
+			Links: &links{Self: self},
+			Meta:  newAlertSummary(alerts),
+		},
+		message: capxml.FromDomain(alerts, at, h.capSender(), time.Now()),
+	})
+}
+
+func (h *Handlers) capSender() string {
+	if h.CAPSender == "" {
+		return "weather-exercise"
+	}
+	return h.CAPSender
+}
+
+// alertsAt starts looking up the alerts at the coordinates, while the current weather is.
+// The returned func waits for the summary, which is nil if there's no alert service or the lookup failed;
+// alerts are extra, so they never fail the response.
+func (h *Handlers) alertsAt(ctx context.Context, lat float64, lon float64) func() *alertSummary {
+	if h.Alerts == nil {
+		return func() *alertSummary { return nil }
+	}
+	done := make(chan *alertSummary, 1)
+	go func() {
+		alerts, err := h.Alerts.AlertsIn(ctx, float32(lat), float32(lon))
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("summarizing alerts")
+			done <- nil
+			return
+		}
+		done <- newAlertSummary(alerts)
+	}()
+	return func() *alertSummary { return <-done }
+}
+
+// withAlerts embeds the alert summary in a current weather document
+func withAlerts(doc *document, summary *alertSummary) *document {
+	if res, ok := doc.Data.(*resource); ok {
+		if attrs, ok := res.Attributes.(*currentWeatherAttributes); ok {
+			attrs.Alerts = summary
+		}
+	}
+	return doc
+}
+
+func newAlertSummary(alerts []domain.Alert) *alertSummary {
+	s := domain.Summarize(alerts)
+	return &alertSummary{
+		Count:    s.Count,
+		Severity: string(s.Severity),
+		Events:   s.Events,
+	}
+}
+
+func alertResource(a *domain.Alert) resource {
+	attrs := &alertAttributes{
+		Event:       a.Event,
+		Headline:    a.Headline,
+		Description: a.Description,
+		Instruction: a.Instruction,
+		Sender:      a.Sender,
+		Severity:    string(a.Severity),
+		Urgency:     string(a.Urgency),
+		Provider:    a.Source.Provider,
+	}
+	if !a.Onset.IsZero() {
+		attrs.Onset = &a.Onset
+	}
+	if !a.Expires.IsZero() {
+		attrs.Expires = &a.Expires
+	}
+	return resource{
+		ID:         fmt.Sprintf("%s:%s:%s", typeAlert, a.Source.Provider, a.ID),
+		Type:       typeAlert,
+		Attributes: attrs,
+	}
+}
@@ -0,0 +1,175 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/capxml"
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+)
+
+type mockAlerter struct {
+	alerts []domain.Alert
+	err    error
+}
+
+func (ma *mockAlerter) AlertsIn(ctx context.Context, lat float32, lon float32) ([]domain.Alert, error) {
+	return ma.alerts, ma.err
+}
+
+var tornadoWarning = domain.Alert{
+	ID:       "urn:oid:1",
+	Event:    "Tornado Warning",
+	Severity: domain.SeverityExtreme,
+	Urgency:  domain.UrgencyImmediate,
+	Onset:    time.Date(2024, 5, 7, 3, 1, 0, 0, time.UTC),
+	Source:   domain.Source{Provider: "nws"},
+}
+
+func TestHandlers_GetAlerts(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		alerter *mockAlerter
+		code    int
+		count   int
+	}{
+		{"alerts", "http://localhost/v1/alerts?latitude=35.4&longitude=-97.5", &mockAlerter{alerts: []domain.Alert{tornadoWarning}}, http.StatusOK, 1},
+		{"none", "http://localhost/v1/alerts?latitude=35.4&longitude=-97.5", &mockAlerter{alerts: []domain.Alert{}}, http.StatusOK, 0},
+		{"missing-longitude", "http://localhost/v1/alerts?latitude=35.4", &mockAlerter{}, http.StatusBadRequest, 0},
+		{"failing", "http://localhost/v1/alerts?latitude=35.4&longitude=-97.5", &mockAlerter{err: errors.New("boom")}, http.StatusInternalServerError, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := server.Handlers{Alerts: test.alerter}
+			w := httptest.NewRecorder()
+			h.GetAlerts(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+			doc := struct {
+				Data []struct {
+					ID         string `json:"id"`
+					Attributes struct {
+						Severity string     `json:"severity"`
+						Urgency  string     `json:"urgency"`
+						Onset    *time.Time `json:"onset"`
+						Expires  *time.Time `json:"expires"`
+					} `json:"attributes"`
+				} `json:"data"`
+				Meta struct {
+					Count int `json:"count"`
+				} `json:"meta"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if len(doc.Data) != test.count || doc.Meta.Count != test.count {
+				t.Fatalf("expected '%v' alerts got '%s'", test.count, w.Body.String())
+			}
+			if test.count == 0 {
+				return
+			}
+			a := doc.Data[0]
+			if a.ID != "urn:weather:alert:nws:urn:oid:1" || a.Attributes.Severity != "extreme" || a.Attributes.Urgency != "immediate" {
+				t.Errorf("expected the tornado warning got '%s'", w.Body.String())
+			}
+			if a.Attributes.Onset == nil || a.Attributes.Expires != nil {
+				t.Errorf("expected an onset, and no expiry, got '%s'", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlers_GetAlerts_CAP(t *testing.T) {
+	h := server.Handlers{Alerts: &mockAlerter{alerts: []domain.Alert{tornadoWarning}}, CAPSender: "weather@example.com"}
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+	}{
+		{"accept", "http://localhost/v1/alerts?latitude=35.4&longitude=-97.5", "application/cap+xml"},
+		{"format", "http://localhost/v1/alerts?latitude=35.4&longitude=-97.5&format=cap", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, test.url, nil)
+			req.Header.Set("Accept", test.accept)
+			w := httptest.NewRecorder()
+			server.NegotiateMiddleware(http.HandlerFunc(h.GetAlerts)).ServeHTTP(w, req)
+			if ct := w.Header().Get("Content-Type"); ct != capxml.MediaType {
+				t.Fatalf("expected '%v' got '%v'", capxml.MediaType, ct)
+			}
+			msg, err := capxml.Parse(w.Body)
+			if err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if msg.Sender != "weather@example.com" || len(msg.Infos) != 1 || msg.Infos[0].Event != "Tornado Warning" {
+				t.Errorf("expected the tornado warning got '%v'", msg)
+			}
+		})
+	}
+
+	// other responses can't be negotiated as cap
+	h = server.Handlers{Domain: &uniformDomain{}}
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current?latitude=35.4&longitude=-97.5", nil)
+	req.Header.Set("Accept", "application/cap+xml")
+	w := httptest.NewRecorder()
+	server.NegotiateMiddleware(http.HandlerFunc(h.GetCurrentByCoords)).ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("expected code '%v' got '%v'", http.StatusNotAcceptable, w.Code)
+	}
+}
+
+func TestHandlers_GetCurrentByCoords_Alerts(t *testing.T) {
+	tests := []struct {
+		name     string
+		alerter  domain.Alerter
+		summary  bool
+		severity string
+	}{
+		{"summary", &mockAlerter{alerts: []domain.Alert{tornadoWarning}}, true, "extreme"},
+		{"no-alert-service", nil, false, ""},
+		{"failing-alerts", &mockAlerter{err: errors.New("boom")}, false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := server.Handlers{Domain: &uniformDomain{}, Alerts: test.alerter}
+			w := httptest.NewRecorder()
+			h.GetCurrentByCoords(w, httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current?latitude=35.4&longitude=-97.5", nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected code '%v' got '%v': %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			doc := struct {
+				Data struct {
+					Attributes struct {
+						Alerts *struct {
+							Count    int      `json:"count"`
+							Severity string   `json:"severity"`
+							Events   []string `json:"events"`
+						} `json:"alerts"`
+					} `json:"attributes"`
+				} `json:"data"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			got := doc.Data.Attributes.Alerts
+			if (got != nil) != test.summary {
+				t.Fatalf("expected summary '%v' got '%s'", test.summary, w.Body.String())
+			}
+			if got != nil && (got.Count != 1 || got.Severity != test.severity || got.Events[0] != "Tornado Warning") {
+				t.Errorf("expected the tornado warning to be summarized got '%v'", *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+)
+
+type areaAttributes struct {
+	BBox    []float64 `json:"bbox"`
+	Samples int       `json:"samples"`
+	Failed  int       `json:"failed"`
+	// Number of samples in each temperature class
+	Temperatures   map[string]int `json:"temperatures"`
+	Conditions     []string       `json:"conditions"`
+	TemperatureMin float32        `json:"temperature_min"`
+	TemperatureMax float32        `json:"temperature_max"`
+	Cells          []areaCell     `json:"cells,omitempty"`
+}
+
+type areaCell struct {
+	Latitude    preciseFloat32 `json:"latitude"`
+	Longitude   preciseFloat32 `json:"longitude"`
+	Temperature string         `json:"temperature,omitempty"`
+	Condition   string         `json:"condition,omitempty"`
+	// Degrees is nil when the lookup failed, so 0°F isn't mistaken for no reading
+	Degrees *float32 `json:"degrees,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// GetAreaSummary responds with the weather summarized over a bounding box, given as bbox=minLon,minLat,maxLon,maxLat.
+// The sampled cells are included with grid=true.
+func (h *Handlers) GetAreaSummary(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query().Get("bbox")
+	if q == "" {
+		encodeError(ctx, w, http.StatusBadRequest, []error{fmt.Errorf("%w: bbox", ErrMissingParam)}, "required query parameters")
+		return
+	}
+	bbox, err := geo.ParseBBox(q)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	id := fmt.Sprintf("%s:%s", typeArea, bbox)
+	self := &links{Self: "/v1/weather/area?bbox=" + bbox.String()}
+	h.writeAreaSummary(w, r, id, bbox, self)
+}
+
+// GetAreaSummaryByGeoJSON responds with the weather summarized over the polygons in a GeoJSON body
+func (h *Handlers) GetAreaSummaryByGeoJSON(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	body, err := readBody(w, r)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	o, err := geo.Decode(body)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{fmt.Errorf("%w: %w", ErrInvalidBody, err)}, "")
+		return
+	}
+	area, err := o.Area()
+	if err == nil && len(area) == 0 {
+		err = errors.New("no polygons")
+	}
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{fmt.Errorf("%w: %w", ErrInvalidBody, err)}, "")
+		return
+	}
+	// polygons don't have a natural ID, so use a digest of the shape
+	b, _ := json.Marshal(area)
+	sum := sha256.Sum256(b)
+	id := fmt.Sprintf("%s:%s", typeArea, hex.EncodeToString(sum[:8]))
+	h.writeAreaSummary(w, r, id, area, nil)
+}
+
+func (h *Handlers) writeAreaSummary(w http.ResponseWriter, r *http.Request, id string, area geo.Area, self *links) {
+	ctx := r.Context()
+	summary, err := h.Area.SummaryIn(ctx, area)
+	if errors.Is(err, domain.ErrNoSamples) {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	} else if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("retrieving area weather: %w", err)}, "")
+		return
+	}
+	b := area.Bounds()
+	attrs := &areaAttributes{
+		BBox:           []float64{b.MinLon, b.MinLat, b.MaxLon, b.MaxLat},
+		Samples:        len(summary.Samples),
+		Failed:         summary.Failed,
+		Temperatures:   map[string]int{},
+		Conditions:     summary.States,
+		TemperatureMin: summary.MinDegrees,
+		TemperatureMax: summary.MaxDegrees,
+	}
+	for t, n := range summary.Temperatures {
+		attrs.Temperatures[string(t)] = n
+	}
+	if r.URL.Query().Get("grid") == "true" {
+		for _, s := range summary.Samples {
+			cell := areaCell{
+				Latitude:  preciseFloat32(s.Coords.Latitude),
+				Longitude: preciseFloat32(s.Coords.Longitude),
+			}
+			if s.Err != nil {
+				cell.Error = s.Err.Error()
+			} else {
+				cell.Temperature = string(s.Weather.Temperature)
+				cell.Condition = strings.Join(s.Weather.States, ", ")
+				degrees := s.Weather.Degrees
+				cell.Degrees = &degrees
+			}
+			attrs.Cells = append(attrs.Cells, cell)
+		}
+	}
+	doc := &document{
+		Data: &resource{
+			ID:         id,
+			Type:       typeArea,
+			Attributes: attrs,
+			Links:      self,
+		},
+		Links: self,
+	}
+	writeDocument(ctx, w, http.StatusOK, doc)
+}
@@ -0,0 +1,135 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+)
+
+type uniformDomain struct{}
+
+func (ud *uniformDomain) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	return &domain.Weather{
+		Coords:      domain.Coords{Latitude: lat, Longitude: lon},
+		States:      []string{"Snow"},
+		Temperature: domain.TempCold,
+		Degrees:     20 + lat,
+	}, nil
+}
+
+// freezingDomain reports exactly 0°F everywhere
+type freezingDomain struct{}
+
+func (fd *freezingDomain) CurrentIn(ctx context.Context, lat float32, lon float32) (*domain.Weather, error) {
+	return &domain.Weather{Coords: domain.Coords{Latitude: lat, Longitude: lon}, States: []string{"Snow"}, Temperature: domain.TempCold}, nil
+}
+
+func TestHandlers_GetAreaSummary_zeroDegrees(t *testing.T) {
+	svc := &freezingDomain{}
+	h := server.Handlers{
+		Domain: svc,
+		Area:   &domain.AreaService{Batch: &domain.Batch{Service: svc, Concurrency: 2}, MaxSamples: 1},
+	}
+	w := httptest.NewRecorder()
+	h.GetAreaSummary(w, httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/area?bbox=0,0,1,1&grid=true", nil))
+	if want := `"degrees":0`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected '%v' in '%v'", want, w.Body.String())
+	}
+}
+
+func TestHandlers_GetAreaSummary(t *testing.T) {
+	svc := &uniformDomain{}
+	h := server.Handlers{
+		Domain: svc,
+		Area: &domain.AreaService{
+			Batch:      &domain.Batch{Service: svc, Concurrency: 2},
+			MaxSamples: 4,
+		},
+	}
+	tests := []struct {
+		name    string
+		method  string
+		query   string
+		body    string
+		handler http.HandlerFunc
+		code    int
+		samples int
+		cells   int
+	}{
+		{"bbox", http.MethodGet, "?bbox=0,0,2,2", "", h.GetAreaSummary, http.StatusOK, 4, 0},
+		{"bbox-grid", http.MethodGet, "?bbox=0,0,2,2&grid=true", "", h.GetAreaSummary, http.StatusOK, 4, 4},
+		{"missing-bbox", http.MethodGet, "", "", h.GetAreaSummary, http.StatusBadRequest, 0, 0},
+		{"invalid-bbox", http.MethodGet, "?bbox=0,0,2", "", h.GetAreaSummary, http.StatusBadRequest, 0, 0},
+		{
+			"polygon",
+			http.MethodPost,
+			"",
+			`{"type":"Polygon","coordinates":[[[0,0],[2,0],[0,2],[0,0]]]}`,
+			h.GetAreaSummaryByGeoJSON,
+			http.StatusOK,
+			3,
+			0,
+		},
+		{
+			"points",
+			http.MethodPost,
+			"",
+			`{"type":"Point","coordinates":[0,0]}`,
+			h.GetAreaSummaryByGeoJSON,
+			http.StatusBadRequest,
+			0,
+			0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "http://localhost/v1/weather/area"+test.query, strings.NewReader(test.body))
+			w := httptest.NewRecorder()
+			test.handler(w, req)
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+			doc := struct {
+				Data struct {
+					Type       string `json:"type"`
+					Attributes struct {
+						Samples        int            `json:"samples"`
+						Temperatures   map[string]int `json:"temperatures"`
+						Conditions     []string       `json:"conditions"`
+						TemperatureMin float32        `json:"temperature_min"`
+						TemperatureMax float32        `json:"temperature_max"`
+						Cells          []interface{}  `json:"cells"`
+					} `json:"attributes"`
+				} `json:"data"`
+			}{}
+			if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			attrs := doc.Data.Attributes
+			if doc.Data.Type != "urn:weather:area" {
+				t.Errorf("expected type 'urn:weather:area' got '%v'", doc.Data.Type)
+			}
+			if attrs.Samples != test.samples || attrs.Temperatures["cold"] != test.samples {
+				t.Errorf("expected %d cold samples got %d of %v", test.samples, attrs.Samples, attrs.Temperatures)
+			}
+			if len(attrs.Conditions) != 1 || attrs.Conditions[0] != "Snow" {
+				t.Errorf("expected conditions '[Snow]' got '%v'", attrs.Conditions)
+			}
+			if attrs.TemperatureMin >= attrs.TemperatureMax {
+				t.Errorf("expected a temperature range got %v to %v", attrs.TemperatureMin, attrs.TemperatureMax)
+			}
+			if len(attrs.Cells) != test.cells {
+				t.Errorf("expected %d cells got %d", test.cells, len(attrs.Cells))
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/broganross/weather-exercise/astro"
+)
+
+type astronomyAttributes struct {
+	Latitude  preciseFloat32 `json:"latitude"`
+	Longitude preciseFloat32 `json:"longitude"`
+	// Date is the day by local mean time, the sun's events are for the solar day around its noon
+	Date      string     `json:"date"`
+	SolarNoon time.Time  `json:"solar_noon"`
+	Sunrise   *time.Time `json:"sunrise,omitempty"`
+	Sunset    *time.Time `json:"sunset,omitempty"`
+	// Polar is "day" or "night" when the sun doesn't rise or set
+	Polar string `json:"polar,omitempty"`
+	// DayLength in seconds
+	DayLength     int64              `json:"day_length"`
+	NoonElevation float64            `json:"noon_elevation"`
+	Civil         twilightAttributes `json:"civil_twilight"`
+	Nautical      twilightAttributes `json:"nautical_twilight"`
+	Astronomical  twilightAttributes `json:"astronomical_twilight"`
+	// At is when SolarElevation and Moon are for
+	At             time.Time      `json:"at"`
+	SolarElevation float64        `json:"solar_elevation"`
+	Moon           moonAttributes `json:"moon"`
+}
+
+type twilightAttributes struct {
+	Dawn *time.Time `json:"dawn,omitempty"`
+	Dusk *time.Time `json:"dusk,omitempty"`
+}
+
+type moonAttributes struct {
+	Phase        string  `json:"phase"`
+	Illumination float64 `json:"illumination"`
+	// Age in days since the new moon
+	Age float64 `json:"age"`
+}
+
+// GetAstronomy responds with the sun's events and the moon's phase at the coordinates, worked out locally.
+// The day is the date query parameter, or the one holding the at query parameter, and otherwise today.
+func (h *Handlers) GetAstronomy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lat, lon, errs := coordsFromRequest(r)
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "required query parameters")
+		return
+	}
+	at, err := astronomyTime(r, lon)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	day := astro.SunDay(lat, lon, at)
+	moon := astro.MoonAt(at)
+	// the date is by local mean time, 4 minutes from UTC for each degree of longitude
+	date := day.SolarNoon.Add(time.Duration(lon * 4 * float64(time.Minute))).Format(time.DateOnly)
+	coords := formatCoord(lat) + "," + formatCoord(lon)
+	res := resource{
+		ID:   fmt.Sprintf("%s:%s:%s", typeAstronomy, coords, date),
+		Type: typeAstronomy,
+		Attributes: &astronomyAttributes{
+			Latitude:       preciseFloat32(lat),
+			Longitude:      preciseFloat32(lon),
+			Date:           date,
+			SolarNoon:      day.SolarNoon,
+			Sunrise:        timeOrNil(day.Sunrise),
+			Sunset:         timeOrNil(day.Sunset),
+			Polar:          string(day.Polar),
+			DayLength:      int64(day.DayLength.Seconds()),
+			NoonElevation:  roundTo(day.NoonElevation, 2),
+			Civil:          newTwilightAttributes(day.Civil),
+			Nautical:       newTwilightAttributes(day.Nautical),
+			Astronomical:   newTwilightAttributes(day.Astronomical),
+			At:             at.UTC().Truncate(time.Second),
+			SolarElevation: roundTo(astro.SolarElevation(lat, lon, at), 2),
+			Moon: moonAttributes{
+				Phase:        string(moon.Phase),
+				Illumination: roundTo(moon.Illumination, 3),
+				Age:          roundTo(moon.Age.Hours()/24, 2),
+			},
+		},
+	}
+	writeDocument(ctx, w, http.StatusOK, &document{
+		Data:  &res,
+		Links: &links{Self: fmt.Sprintf("/v1/astronomy?latitude=%s&longitude=%s&date=%s", formatCoord(lat), formatCoord(lon), date)},
+	})
+}
+
+// astronomyTime reads the date, or at, query parameter.  A date stands for its noon by local mean time.
+func astronomyTime(r *http.Request, lon float64) (time.Time, error) {
+	q := r.URL.Query()
+	date, at := q.Get("date"), q.Get("at")
+	switch {
+	case date != "" && at != "":
+		return time.Time{}, fmt.Errorf("%w: expected one of date or at, not both", ErrInvalidTime)
+	case date != "":
+		d, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: date: %w", ErrInvalidTime, err)
+		}
+		return d.Add(12*time.Hour - time.Duration(lon*4*float64(time.Minute))), nil
+	case at != "":
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: at: %w", ErrInvalidTime, err)
+		}
+		return t, nil
+	}
+	return time.Now(), nil
+}
+
+func newTwilightAttributes(t astro.Twilight) twilightAttributes {
+	return twilightAttributes{Dawn: timeOrNil(t.Dawn), Dusk: timeOrNil(t.Dusk)}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// roundTo rounds to a number of decimal places, past which the formulas aren't accurate anyway
+func roundTo(f float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(f*p) / p
+}
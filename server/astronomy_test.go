@@ -0,0 +1,84 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/server"
+)
+
+type astronomyDocument struct {
+	Data struct {
+		ID         string `json:"id"`
+		Attributes struct {
+			Date      string     `json:"date"`
+			Sunrise   *time.Time `json:"sunrise"`
+			Sunset    *time.Time `json:"sunset"`
+			Polar     string     `json:"polar"`
+			DayLength int64      `json:"day_length"`
+			Moon      struct {
+				Phase string `json:"phase"`
+			} `json:"moon"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+func TestHandlers_GetAstronomy(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		code    int
+		date    string
+		sunrise string
+		polar   string
+	}{
+		{"greenwich", "http://localhost/v1/astronomy?latitude=51.4769&longitude=0&date=2024-06-21", http.StatusOK, "2024-06-21", "2024-06-21T03:42", ""},
+		{"at", "http://localhost/v1/astronomy?latitude=51.4769&longitude=0&at=2024-06-21T09:00:00Z", http.StatusOK, "2024-06-21", "2024-06-21T03:42", ""},
+		{"polar-night", "http://localhost/v1/astronomy?latitude=69.6492&longitude=18.9553&date=2024-12-21", http.StatusOK, "2024-12-21", "", "night"},
+		{"both", "http://localhost/v1/astronomy?latitude=51.4769&longitude=0&date=2024-06-21&at=2024-06-21T09:00:00Z", http.StatusBadRequest, "", "", ""},
+		{"bad-date", "http://localhost/v1/astronomy?latitude=51.4769&longitude=0&date=21/06/2024", http.StatusBadRequest, "", "", ""},
+		{"missing-longitude", "http://localhost/v1/astronomy?latitude=51.4769", http.StatusBadRequest, "", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := server.Handlers{}
+			w := httptest.NewRecorder()
+			h.GetAstronomy(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+			if w.Code != test.code {
+				t.Fatalf("expected '%v' got '%v'", test.code, w.Code)
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+			doc := astronomyDocument{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			attrs := doc.Data.Attributes
+			if attrs.Date != test.date {
+				t.Errorf("expected '%v' got '%v'", test.date, attrs.Date)
+			}
+			if attrs.Polar != test.polar {
+				t.Errorf("expected '%v' got '%v'", test.polar, attrs.Polar)
+			}
+			if test.sunrise == "" {
+				if attrs.Sunrise != nil || attrs.Sunset != nil {
+					t.Errorf("expected no sunrise or sunset got '%v', '%v'", attrs.Sunrise, attrs.Sunset)
+				}
+				return
+			}
+			if attrs.Sunrise == nil {
+				t.Fatalf("expected sunrise got none")
+			}
+			if got := attrs.Sunrise.Format("2006-01-02T15:04"); got != test.sunrise {
+				t.Errorf("expected '%v' got '%v'", test.sunrise, got)
+			}
+			if attrs.Moon.Phase == "" {
+				t.Errorf("expected a moon phase")
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+	"github.com/broganross/weather-exercise/i18n"
+)
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+var ErrInvalidBody = errors.New("invalid request body")
+
+type batchRequest struct {
+	Coordinates []batchCoordinate `json:"coordinates"`
+}
+
+// batchCoordinate is either a latitude and longitude, or a GeoJSON Point
+type batchCoordinate struct {
+	Latitude    *float64   `json:"latitude"`
+	Longitude   *float64   `json:"longitude"`
+	Type        string     `json:"type"`
+	Coordinates *geo.Point `json:"coordinates"`
+}
+
+func (bc *batchCoordinate) coords() (domain.Coords, error) {
+	if bc.Type != "" {
+		if bc.Type != geo.TypePoint || bc.Coordinates == nil {
+			return domain.Coords{}, errors.New("expected a GeoJSON Point")
+		}
+		return domain.Coords{Latitude: float32(bc.Coordinates.Lat), Longitude: float32(bc.Coordinates.Lon)}, nil
+	}
+	if bc.Latitude == nil || bc.Longitude == nil {
+		return domain.Coords{}, errors.New("expected latitude and longitude")
+	}
+	return domain.Coords{Latitude: float32(*bc.Latitude), Longitude: float32(*bc.Longitude)}, nil
+}
+
+type batchErrorAttributes struct {
+	Latitude  preciseFloat32 `json:"latitude"`
+	Longitude preciseFloat32 `json:"longitude"`
+	Status    int            `json:"status"`
+	Error     string         `json:"error"`
+}
+
+type batchMeta struct {
+	Requested int `json:"requested"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// GetCurrentBatch responds with the current weather for each of the posted coordinates.
+// The body is either a list of coordinates, or a GeoJSON object of points.
+// Lookups that fail are returned as error resources in place, rather than failing the whole batch.
+func (h *Handlers) GetCurrentBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	include, err := parseInclude(r, includeLocation)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	var coords []domain.Coords
+	errs := []error{}
+	if isGeoJSON(body) {
+		coords, err = geoJSONCoords(body)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		coords, errs = batchCoords(body)
+	}
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "")
+		return
+	}
+	h.writeBatch(w, r, coords, include)
+}
+
+// batchCoords reads the coordinates list form of a batch request
+func batchCoords(body []byte) ([]domain.Coords, []error) {
+	req := batchRequest{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, []error{fmt.Errorf("%w: %w", ErrInvalidBody, err)}
+	}
+	if len(req.Coordinates) == 0 {
+		return nil, []error{fmt.Errorf("%w: no coordinates", ErrInvalidBody)}
+	}
+	coords := make([]domain.Coords, len(req.Coordinates))
+	errs := []error{}
+	for i, bc := range req.Coordinates {
+		c, err := bc.coords()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: coordinates[%d]: %w", ErrInvalidBody, i, err))
+		}
+		coords[i] = c
+	}
+	return coords, errs
+}
+
+// writeBatch looks up the current weather for all the coordinates and writes the collection
+func (h *Handlers) writeBatch(w http.ResponseWriter, r *http.Request, coords []domain.Coords, include map[string]bool) {
+	ctx := r.Context()
+	results, err := h.Batch.CurrentIn(ctx, coords)
+	if errors.Is(err, domain.ErrBatchTooLarge) {
+		encodeError(ctx, w, http.StatusRequestEntityTooLarge, []error{err}, "")
+		return
+	} else if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("retrieving batch weather: %w", err)}, "")
+		return
+	}
+	writeDocument(ctx, w, http.StatusOK, batchDocument(results, include, i18n.For(i18n.FromContext(ctx))))
+}
+
+// readBody reads a request body, up to maxBodyBytes
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBody, err)
+	}
+	return b, nil
+}
+
+// batchDocument builds a collection of current weather, or error, resources in the order they were requested
+func batchDocument(results []domain.BatchResult, include map[string]bool, catalog *i18n.Catalog) *document {
+	data := make([]resource, len(results))
+	meta := &batchMeta{Requested: len(results)}
+	included := map[string]bool{}
+	doc := &document{Meta: meta}
+	for i, result := range results {
+		lat := float64(result.Coords.Latitude)
+		lon := float64(result.Coords.Longitude)
+		if result.Err != nil {
+			meta.Failed++
+			data[i] = resource{
+				ID:   fmt.Sprintf("%s:%d", typeError, i),
+				Type: typeError,
+				Attributes: &batchErrorAttributes{
+					Latitude:  preciseFloat32(lat),
+					Longitude: preciseFloat32(lon),
+					Status:    http.StatusInternalServerError,
+					Error:     fmt.Sprintf("retrieving current weather: %s", result.Err),
+				},
+			}
+			continue
+		}
+		meta.Succeeded++
+		current, loc := currentWeatherResource(lat, lon, result.Weather, catalog)
+		current.Meta = newSourceMeta(result.Weather.Source)
+		data[i] = current
+		if include[includeLocation] && !included[loc.ID] {
+			included[loc.ID] = true
+			doc.Included = append(doc.Included, loc)
+		}
+	}
+	doc.Data = data
+	return doc
+}
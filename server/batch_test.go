@@ -0,0 +1,105 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+)
+
+func TestHandlers_GetCurrentBatch(t *testing.T) {
+	mock := &mockWeatherDomain{
+		responses: map[string]mockWeatherDomainResponse{
+			"1.20:2.30": {
+				weather: domain.Weather{
+					Coords:      domain.Coords{Latitude: 1.2, Longitude: 2.3},
+					States:      []string{"rain"},
+					Temperature: domain.TempCold,
+				},
+			},
+		},
+	}
+	h := server.Handlers{
+		Domain: mock,
+		Batch: &domain.Batch{
+			Service:     mock,
+			MaxItems:    3,
+			Concurrency: 2,
+		},
+	}
+	tests := []struct {
+		name  string
+		body  string
+		code  int
+		types []string
+		meta  map[string]float64
+	}{
+		{
+			"mixed-results",
+			`{"coordinates":[{"latitude":1.2,"longitude":2.3},{"latitude":9,"longitude":9},{"type":"Point","coordinates":[2.3,1.2]}]}`,
+			http.StatusOK,
+			[]string{"urn:weather:current", "urn:weather:error", "urn:weather:current"},
+			map[string]float64{"requested": 3, "succeeded": 2, "failed": 1},
+		},
+		{
+			"invalid-item",
+			`{"coordinates":[{"latitude":1.2}]}`,
+			http.StatusBadRequest,
+			nil,
+			nil,
+		},
+		{
+			"empty",
+			`{"coordinates":[]}`,
+			http.StatusBadRequest,
+			nil,
+			nil,
+		},
+		{
+			"too-large",
+			`{"coordinates":[{"latitude":1,"longitude":1},{"latitude":2,"longitude":2},{"latitude":3,"longitude":3},{"latitude":4,"longitude":4}]}`,
+			http.StatusRequestEntityTooLarge,
+			nil,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/v1/weather/current:batch", strings.NewReader(test.body))
+			w := httptest.NewRecorder()
+			h.GetCurrentBatch(w, req)
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.types == nil {
+				return
+			}
+			doc := struct {
+				Data []struct {
+					Type string `json:"type"`
+				} `json:"data"`
+				Meta map[string]float64 `json:"meta"`
+			}{}
+			if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if len(doc.Data) != len(test.types) {
+				t.Fatalf("expected %d resources got %d", len(test.types), len(doc.Data))
+			}
+			for i, want := range test.types {
+				if doc.Data[i].Type != want {
+					t.Errorf("expected resource %d type '%v' got '%v'", i, want, doc.Data[i].Type)
+				}
+			}
+			for k, v := range test.meta {
+				if doc.Meta[k] != v {
+					t.Errorf("expected meta %s '%v' got '%v'", k, v, doc.Meta[k])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,466 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/broganross/weather-exercise/capxml"
+	"github.com/broganross/weather-exercise/geo"
+	"github.com/rs/zerolog/log"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// encoder writes response values in a media type.
+// Response types only describe their JSON shape, the other formats are transcoded from it.
+type encoder struct {
+	mediaType string
+	// short name for the format query parameter override
+	format string
+	encode func(v any) ([]byte, error)
+	// optionally limits the encoder to some responses
+	supports func(v any) bool
+}
+
+func (e *encoder) canEncode(v any) bool {
+	return e.supports == nil || e.supports(v)
+}
+
+// encoders in order of preference when the client accepts anything
+var encoders = []*encoder{
+	{mediaType: jsonAPIMediaType, format: "jsonapi", encode: encodeJSON},
+	{mediaType: "application/json", format: "json", encode: encodeJSON},
+	{mediaType: geo.MediaType, format: "geojson", encode: encodeGeoJSON, supports: isGeoJSONer},
+	{mediaType: capxml.MediaType, format: "cap", encode: encodeCAP, supports: isCAPer},
+	{mediaType: "application/xml", format: "xml", encode: encodeXML},
+	{mediaType: "text/xml", encode: encodeXML},
+	{mediaType: "text/csv", format: "csv", encode: encodeCSV},
+	{mediaType: "application/msgpack", format: "msgpack", encode: encodeMsgpack},
+	{mediaType: "application/vnd.msgpack", encode: encodeMsgpack},
+	{mediaType: "application/x-msgpack", encode: encodeMsgpack},
+}
+
+func encoderFor(mediaType string) *encoder {
+	for _, e := range encoders {
+		if e.mediaType == mediaType {
+			return e
+		}
+	}
+	return nil
+}
+
+// mediaRange is one entry of an Accept header
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// matches reports whether the range accepts the media type
+func (mr mediaRange) matches(mediaType string) bool {
+	if mr.mediaType == "*/*" || mr.mediaType == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(mr.mediaType, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// acceptable is what the client will accept, most preferred first
+type acceptable []mediaRange
+
+// parseAccept reads the format query parameter, falling back to the Accept header
+func parseAccept(r *http.Request) (acceptable, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		for _, e := range encoders {
+			if e.format == format {
+				return acceptable{{mediaType: e.mediaType, q: 1}}, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: format %s", ErrNotAcceptable, format)
+	}
+	header := r.Header.Values("Accept")
+	if len(header) == 0 {
+		return acceptable{{mediaType: "*/*", q: 1}}, nil
+	}
+	accept := acceptable{}
+	for _, h := range header {
+		for _, part := range strings.Split(h, ",") {
+			params := strings.Split(part, ";")
+			mr := mediaRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+			if mr.mediaType == "" {
+				continue
+			}
+			for _, p := range params[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+				if strings.EqualFold(k, "q") {
+					if q, err := strconv.ParseFloat(v, 64); err == nil {
+						mr.q = q
+					}
+				}
+			}
+			if mr.q > 0 {
+				accept = append(accept, mr)
+			}
+		}
+	}
+	sort.SliceStable(accept, func(i, j int) bool {
+		return accept[i].q > accept[j].q
+	})
+	return accept, nil
+}
+
+// choose picks the encoder for a response, preferring the response's own media type when the client allows it
+func (a acceptable) choose(defaultType string, v any) *encoder {
+	for _, mr := range a {
+		if mr.matches(defaultType) {
+			return encoderFor(defaultType)
+		}
+		for _, e := range encoders {
+			if mr.matches(e.mediaType) && e.canEncode(v) {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// acceptsAny reports whether any of the encoders are acceptable, for some response
+func (a acceptable) acceptsAny() bool {
+	for _, mr := range a {
+		for _, e := range encoders {
+			if mr.matches(e.mediaType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type acceptKey struct{}
+
+// NegotiateMiddleware works out the response media type from the Accept header, or format query parameter,
+// responding with 406 Not Acceptable if none of the encoders are acceptable
+func NegotiateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept, err := parseAccept(r)
+		if err == nil && !accept.acceptsAny() {
+			err = fmt.Errorf("%w: %s", ErrNotAcceptable, r.Header.Get("Accept"))
+		}
+		if err != nil {
+			encodeError(r.Context(), w, http.StatusNotAcceptable, []error{err}, "")
+			return
+		}
+		ctx := context.WithValue(r.Context(), acceptKey{}, accept)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// writeResponse encodes the value in the negotiated media type, which is defaultType unless the client asked otherwise
+func writeResponse(ctx context.Context, w http.ResponseWriter, statusCode int, defaultType string, v any) {
+	enc := encoderFor(defaultType)
+	if accept, ok := ctx.Value(acceptKey{}).(acceptable); ok {
+		e := accept.choose(defaultType, v)
+		_, isError := v.(*errorResponse)
+		switch {
+		case e != nil:
+			enc = e
+		case !isError:
+			// acceptable for other responses, but not this one
+			encodeError(ctx, w, http.StatusNotAcceptable, []error{ErrNotAcceptable}, "")
+			return
+		}
+	}
+	body, err := enc.encode(v)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Str("media-type", enc.mediaType).Msg("encoding response")
+		enc = encoderFor("application/json")
+		statusCode = http.StatusInternalServerError
+		body = []byte(fmt.Sprintf(`{"errors":[{"error":%q}],"status":500}`+"\n", "encoding response: "+err.Error()))
+	}
+	w.Header().Set("Content-Type", enc.mediaType)
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("writing response")
+	}
+}
+
+func isCAPer(v any) bool {
+	_, ok := v.(capper)
+	return ok
+}
+
+func encodeCAP(v any) ([]byte, error) {
+	c, ok := v.(capper)
+	if !ok {
+		return nil, fmt.Errorf("%T can't be written as cap", v)
+	}
+	return capxml.Marshal(c.capAlert())
+}
+
+func isGeoJSONer(v any) bool {
+	_, ok := v.(geoJSONer)
+	return ok
+}
+
+func encodeGeoJSON(v any) ([]byte, error) {
+	g, ok := v.(geoJSONer)
+	if !ok {
+		return nil, fmt.Errorf("%T can't be written as geojson", v)
+	}
+	return encodeJSON(g.geoJSON())
+}
+
+func encodeJSON(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// field is a member of an object, keeping the order they were encoded in
+type field struct {
+	key   string
+	value any
+}
+
+// object is a JSON object that keeps its field order
+type object []field
+
+// toTree converts a value to its JSON representation, as objects, []any, json.Number, string, bool and nil
+func toTree(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return decodeTree(dec)
+}
+
+func decodeTree(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := object{}
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeTree(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, field{key: keyTok.(string), value: value})
+			}
+			_, err := dec.Token()
+			return obj, err
+		case '[':
+			arr := []any{}
+			for dec.More() {
+				value, err := decodeTree(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, value)
+			}
+			_, err := dec.Token()
+			return arr, err
+		}
+		return nil, fmt.Errorf("unexpected delimiter %v", t)
+	}
+	return tok, nil
+}
+
+// encodeXML writes the value under a <response> element.
+// Object fields become elements, and array items repeat the element of the field holding them.
+func encodeXML(v any) ([]byte, error) {
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	if err := writeXML(enc, "response", tree); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+func writeXML(enc *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	switch t := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		for _, item := range t {
+			if err := writeXML(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case object:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, f := range t {
+			if err := writeXML(enc, f.key, f.value); err != nil {
+				return err
+			}
+		}
+		return enc.EncodeToken(start.End())
+	}
+	return enc.EncodeElement(scalarString(value), start)
+}
+
+func scalarString(value any) string {
+	switch t := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	return fmt.Sprint(value)
+}
+
+// encodeCSV writes one row per item of the top level "data" or "errors" array, or a single row otherwise.
+// Nested fields are flattened into dot separated columns.
+func encodeCSV(v any) ([]byte, error) {
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+	rows := []any{tree}
+	if obj, ok := tree.(object); ok {
+		for _, f := range obj {
+			if arr, ok := f.value.([]any); ok && (f.key == "data" || f.key == "errors") {
+				rows = arr
+				break
+			}
+		}
+	}
+	columns := []string{}
+	seen := map[string]bool{}
+	records := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		records[i] = map[string]string{}
+		flatten("", row, func(key string, value string) {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+			records[i][key] = value
+		})
+	}
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		line := make([]string, len(columns))
+		for i, c := range columns {
+			line[i] = rec[c]
+		}
+		if err := w.Write(line); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func flatten(prefix string, value any, emit func(key string, value string)) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch t := value.(type) {
+	case object:
+		for _, f := range t {
+			flatten(join(f.key), f.value, emit)
+		}
+	case []any:
+		for i, item := range t {
+			flatten(join(strconv.Itoa(i)), item, emit)
+		}
+	default:
+		if prefix == "" {
+			prefix = "value"
+		}
+		emit(prefix, scalarString(value))
+	}
+}
+
+func encodeMsgpack(v any) ([]byte, error) {
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := writeMsgpack(msgpack.NewEncoder(buf), tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeMsgpack(enc *msgpack.Encoder, value any) error {
+	switch t := value.(type) {
+	case object:
+		if err := enc.EncodeMapLen(len(t)); err != nil {
+			return err
+		}
+		for _, f := range t {
+			if err := enc.EncodeString(f.key); err != nil {
+				return err
+			}
+			if err := writeMsgpack(enc, f.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := enc.EncodeArrayLen(len(t)); err != nil {
+			return err
+		}
+		for _, item := range t {
+			if err := writeMsgpack(enc, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return enc.EncodeInt(i)
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return err
+		}
+		return enc.EncodeFloat64(f)
+	}
+	return enc.Encode(value)
+}
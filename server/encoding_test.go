@@ -0,0 +1,133 @@
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiateMiddleware(t *testing.T) {
+	h := server.Handlers{
+		Domain: &mockWeatherDomain{
+			responses: map[string]mockWeatherDomainResponse{
+				"1.20:2.30": {
+					weather: domain.Weather{
+						States:      []string{"rain"},
+						Temperature: domain.TempCold,
+					},
+				},
+			},
+		},
+	}
+	legacy := server.NegotiateMiddleware(http.HandlerFunc(h.GetCurrentByCoordsLegacy))
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		code        int
+		contentType string
+		body        string
+	}{
+		{
+			"default",
+			"?latitude=1.2&longitude=2.3",
+			"",
+			http.StatusOK,
+			"application/json",
+			`{"id":"urn:weather:current:id","type":"urn:weather:current","attributes":{"latitude":1.200000,"longitude":2.300000,"temperature":"cold","condition":"rain"}}` + "\n",
+		},
+		{
+			"xml",
+			"?latitude=1.2&longitude=2.3",
+			"text/html;q=0.9, application/xml",
+			http.StatusOK,
+			"application/xml",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<response><id>urn:weather:current:id</id><type>urn:weather:current</type><attributes><latitude>1.200000</latitude><longitude>2.300000</longitude><temperature>cold</temperature><condition>rain</condition></attributes></response>` + "\n",
+		},
+		{
+			"csv-format-override",
+			"?latitude=1.2&longitude=2.3&format=csv",
+			"application/xml",
+			http.StatusOK,
+			"text/csv",
+			"id,type,attributes.latitude,attributes.longitude,attributes.temperature,attributes.condition\n" +
+				"urn:weather:current:id,urn:weather:current,1.200000,2.300000,cold,rain\n",
+		},
+		{
+			"csv-errors",
+			"",
+			"text/csv",
+			http.StatusBadRequest,
+			"text/csv",
+			"error,message\n" +
+				"missing query parameter: latitude,required query parameters\n" +
+				"missing query parameter: longitude,required query parameters\n",
+		},
+		{
+			"wildcard-subtype",
+			"?latitude=1.2&longitude=2.3",
+			"text/*",
+			http.StatusOK,
+			"text/xml",
+			"",
+		},
+		{
+			"not-acceptable",
+			"?latitude=1.2&longitude=2.3",
+			"image/png",
+			http.StatusNotAcceptable,
+			"application/json",
+			`{"errors":[{"error":"no acceptable media type: image/png"}],"status":406}` + "\n",
+		},
+		{
+			"unknown-format",
+			"?latitude=1.2&longitude=2.3&format=yaml",
+			"",
+			http.StatusNotAcceptable,
+			"application/json",
+			`{"errors":[{"error":"no acceptable media type: format yaml"}],"status":406}` + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/"+test.query, nil)
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			w := httptest.NewRecorder()
+			legacy.ServeHTTP(w, req)
+			resp := w.Result()
+			if resp.StatusCode != test.code {
+				t.Errorf("expected code '%v' got '%v'", test.code, resp.StatusCode)
+			}
+			if h := resp.Header.Get("Content-Type"); h != test.contentType {
+				t.Errorf("expected Content-Type header '%v' got '%v'", test.contentType, h)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if test.body != "" && string(body) != test.body {
+				t.Errorf("expected body '%v' got '%v'", test.body, string(body))
+			}
+		})
+	}
+
+	t.Run("msgpack", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/?latitude=1.2&longitude=2.3", nil)
+		req.Header.Set("Accept", "application/msgpack")
+		w := httptest.NewRecorder()
+		legacy.ServeHTTP(w, req)
+		got := map[string]interface{}{}
+		if err := msgpack.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decoding msgpack: %v", err)
+		}
+		attrs, _ := got["attributes"].(map[string]interface{})
+		if got["type"] != "urn:weather:current" || attrs["temperature"] != "cold" || attrs["latitude"] != 1.2 {
+			t.Errorf("unexpected msgpack body '%v'", got)
+		}
+	})
+}
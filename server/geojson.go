@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+	"github.com/broganross/weather-exercise/i18n"
+)
+
+var ErrTooManyPoints = errors.New("too many points")
+
+// geoJSONer is implemented by responses that can be written as GeoJSON
+type geoJSONer interface {
+	geoJSON() any
+}
+
+// located is implemented by attributes that have a position
+type located interface {
+	point() geo.Point
+}
+
+func (ca *currentAttributes) point() geo.Point {
+	return geo.Point{Lon: float64(ca.Longitude), Lat: float64(ca.Latitude)}
+}
+
+func (la *locationAttributes) point() geo.Point {
+	return geo.Point{Lon: float64(la.Longitude), Lat: float64(la.Latitude)}
+}
+
+func (ba *batchErrorAttributes) point() geo.Point {
+	return geo.Point{Lon: float64(ba.Longitude), Lat: float64(ba.Latitude)}
+}
+
+// geoJSON converts the document's primary data into a feature collection,
+// with each resource's attributes as the properties of a feature at its position
+func (d *document) geoJSON() any {
+	var resources []resource
+	switch data := d.Data.(type) {
+	case *resource:
+		resources = []resource{*data}
+	case []resource:
+		resources = data
+	}
+	fc := &geo.FeatureCollection{
+		Type:     geo.TypeFeatureCollection,
+		Features: make([]geo.Feature, len(resources)),
+		Meta:     d.Meta,
+	}
+	for i, res := range resources {
+		f := geo.Feature{
+			Type:       geo.TypeFeature,
+			ID:         res.ID,
+			Properties: res.Attributes,
+		}
+		if l, ok := res.Attributes.(located); ok {
+			f.Geometry = geo.NewPoint(l.point())
+		}
+		fc.Features[i] = f
+	}
+	return fc
+}
+
+// isGeoJSON reports whether a JSON body is a GeoJSON object, rather than one of our own request shapes
+func isGeoJSON(body []byte) bool {
+	probe := struct {
+		Type string `json:"type"`
+	}{}
+	return json.Unmarshal(body, &probe) == nil && probe.Type != ""
+}
+
+// geoJSONCoords reads the points of a GeoJSON body as coordinates
+func geoJSONCoords(body []byte) ([]domain.Coords, error) {
+	o, err := geo.Decode(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBody, err)
+	}
+	points, err := o.Points()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBody, err)
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("%w: no points", ErrInvalidBody)
+	}
+	coords := make([]domain.Coords, len(points))
+	for i, p := range points {
+		coords[i] = domain.Coords{Latitude: float32(p.Lat), Longitude: float32(p.Lon)}
+	}
+	return coords, nil
+}
+
+// GetCurrentByGeoJSON responds with the current weather for the points in a GeoJSON body.
+// A single point gets a single resource, like GetCurrentByCoords, while several are looked up as a batch.
+func (h *Handlers) GetCurrentByGeoJSON(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	include, err := parseInclude(r, h.currentIncludes()...)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	coords, err := geoJSONCoords(body)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	if len(coords) > 1 {
+		if h.Batch == nil {
+			encodeError(ctx, w, http.StatusBadRequest, []error{fmt.Errorf("%w: expected a single point", ErrTooManyPoints)}, "")
+			return
+		}
+		h.writeBatch(w, r, coords, include)
+		return
+	}
+	lat := float64(coords[0].Latitude)
+	lon := float64(coords[0].Longitude)
+	alerts := h.alertsAt(ctx, lat, lon)
+	airQuality := h.airQualityAt(ctx, lat, lon, include)
+	weather, ok := h.currentAt(w, r, lat, lon)
+	if !ok {
+		return
+	}
+	doc := withAlerts(currentWeatherDocument(lat, lon, weather, include, i18n.For(i18n.FromContext(ctx))), alerts())
+	writeDocument(ctx, w, http.StatusOK, withAirQuality(doc, airQuality()))
+}
@@ -0,0 +1,139 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+)
+
+type featureCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		ID       string `json:"id"`
+		Geometry struct {
+			Type        string    `json:"type"`
+			Coordinates []float64 `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"features"`
+}
+
+func TestGeoJSON(t *testing.T) {
+	mock := &mockWeatherDomain{
+		responses: map[string]mockWeatherDomainResponse{
+			"1.20:2.30": {
+				weather: domain.Weather{
+					States:      []string{"rain"},
+					Temperature: domain.TempCold,
+				},
+			},
+			"-3.40:5.60": {
+				weather: domain.Weather{
+					States:      []string{"clear"},
+					Temperature: domain.TempHot,
+				},
+			},
+		},
+	}
+	h := server.Handlers{
+		Domain: mock,
+		Batch:  &domain.Batch{Service: mock, Concurrency: 2},
+	}
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		handler http.HandlerFunc
+		code    int
+		// longitude, latitude and temperature of each feature
+		features [][3]interface{}
+	}{
+		{
+			"get-as-geojson",
+			http.MethodGet,
+			"/v1/weather/current?latitude=1.2&longitude=2.3",
+			"",
+			h.GetCurrentByCoords,
+			http.StatusOK,
+			[][3]interface{}{{2.3, 1.2, "cold"}},
+		},
+		{
+			"post-point",
+			http.MethodPost,
+			"/v1/weather/current",
+			`{"type":"Point","coordinates":[2.3,1.2]}`,
+			h.GetCurrentByGeoJSON,
+			http.StatusOK,
+			[][3]interface{}{{2.3, 1.2, "cold"}},
+		},
+		{
+			"post-multipoint",
+			http.MethodPost,
+			"/v1/weather/current",
+			`{"type":"MultiPoint","coordinates":[[2.3,1.2],[5.6,-3.4]]}`,
+			h.GetCurrentByGeoJSON,
+			http.StatusOK,
+			[][3]interface{}{{2.3, 1.2, "cold"}, {5.6, -3.4, "hot"}},
+		},
+		{
+			"batch-feature-collection",
+			http.MethodPost,
+			"/v1/weather/current:batch",
+			`{"type":"FeatureCollection","features":[
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[5.6,-3.4]},"properties":{}},
+				{"type":"Feature","geometry":{"type":"Point","coordinates":[2.3,1.2]},"properties":{}}
+			]}`,
+			h.GetCurrentBatch,
+			http.StatusOK,
+			[][3]interface{}{{5.6, -3.4, "hot"}, {2.3, 1.2, "cold"}},
+		},
+		{
+			"post-polygon",
+			http.MethodPost,
+			"/v1/weather/current",
+			`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+			h.GetCurrentByGeoJSON,
+			http.StatusBadRequest,
+			nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "http://localhost"+test.path, strings.NewReader(test.body))
+			req.Header.Set("Accept", "application/geo+json")
+			w := httptest.NewRecorder()
+			server.NegotiateMiddleware(test.handler).ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.features == nil {
+				return
+			}
+			if h := w.Result().Header.Get("Content-Type"); h != "application/geo+json" {
+				t.Errorf("expected Content-Type header 'application/geo+json' got '%v'", h)
+			}
+			fc := featureCollection{}
+			if err := json.NewDecoder(w.Body).Decode(&fc); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if fc.Type != "FeatureCollection" || len(fc.Features) != len(test.features) {
+				t.Fatalf("expected a collection of %d features got '%v'", len(test.features), fc)
+			}
+			for i, want := range test.features {
+				f := fc.Features[i]
+				if f.Geometry.Type != "Point" || f.Geometry.Coordinates[0] != want[0] || f.Geometry.Coordinates[1] != want[1] {
+					t.Errorf("expected feature %d at [%v, %v] got '%v'", i, want[0], want[1], f.Geometry)
+				}
+				if f.Properties["temperature"] != want[2] {
+					t.Errorf("expected feature %d temperature '%v' got '%v'", i, want[2], f.Properties["temperature"])
+				}
+			}
+		})
+	}
+}
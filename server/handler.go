@@ -2,127 +2,165 @@ package server
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"github.com/broganross/weather-exercise/config"
 	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+	"github.com/broganross/weather-exercise/requestid"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
 )
 
 var (
-	ErrMissingParam = errors.New("missing query parameter")
-	ErrInvalidFloat = errors.New("invalid float")
+	ErrMissingParam       = errors.New("missing query parameter")
+	ErrInvalidFloat       = errors.New("invalid float")
+	ErrUnsupportedInclude = errors.New("unsupported include")
+	ErrNotAcceptable      = errors.New("no acceptable media type")
+	ErrInvalidTime        = errors.New("invalid time")
 )
 
-// SetupRoutes constructs the router, adding middleware, routes, handlers, etc
-func SetupRoutes(h *Handlers, r *mux.Router, c *config.Config) {
-	r.Use(LogContextMiddleware)
-	am := Auth{
-		BaseURL: c.AuthService.URL,
-	}
-	r.Use(am.Middleware)
-	r.HandleFunc("/", h.GetCurrentByCoords).Methods(http.MethodGet)
-}
-
 // Our handlers for whatever routes we need
 type Handlers struct {
 	Domain domain.Service
+	Alerts domain.Alerter
+	// AirQuality can be included in current weather, and has its own routes
+	AirQuality domain.AirQualityReporter
+	History    domain.Historian
+	// CAPSender is the sender of alerts written as CAP messages
+	CAPSender string
+	Batch     *domain.Batch
+	Area      *domain.AreaService
+	Route     *domain.RouteService
+	Streams   *Streams
+	Webhooks  domain.WebhookStore
+	Health    *Health
 }
 
+// GetCurrentByCoords responds with the current weather as a JSON:API document, with a summary of any alerts.
+// The location, and air quality when there's an air quality service, can be included as a compound document
+// with include=location,air_quality.
 func (h *Handlers) GetCurrentByCoords(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
 	ctx := r.Context()
-	q := r.URL.Query()
-	// get the query parameters
-	var lat float64
-	var lon float64
-	errs := []error{}
-	if latString := q.Get("latitude"); latString != "" {
-		l, err := strconv.ParseFloat(latString, 32)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("%w: latitude", ErrInvalidFloat))
-		}
-		lat = l
-	} else {
-		errs = append(errs, fmt.Errorf("%w: latitude", ErrMissingParam))
-	}
-	if lonString := q.Get("longitude"); lonString != "" {
-		l, err := strconv.ParseFloat(lonString, 32)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("%w: longitude", ErrInvalidFloat))
-		}
-		lon = l
-	} else {
-		errs = append(errs, fmt.Errorf("%w: longitude", ErrMissingParam))
+	include, err := parseInclude(r, h.currentIncludes()...)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
 	}
+	lat, lon, errs := coordsFromRequest(r)
 	if len(errs) > 0 {
 		encodeError(ctx, w, http.StatusBadRequest, errs, "required query parameters")
 		return
 	}
+	alerts := h.alertsAt(ctx, lat, lon)
+	airQuality := h.airQualityAt(ctx, lat, lon, include)
+	weather, ok := h.currentAt(w, r, lat, lon)
+	if !ok {
+		return
+	}
+	doc := withAlerts(currentWeatherDocument(lat, lon, weather, include, i18n.For(i18n.FromContext(ctx))), alerts())
+	writeDocument(ctx, w, http.StatusOK, withAirQuality(doc, airQuality()))
+}
 
-	// business logic
-	weather, err := h.Domain.CurrentIn(ctx, float32(lat), float32(lon))
-	if err != nil {
-		encodeError(
-			ctx,
-			w,
-			http.StatusInternalServerError,
-			[]error{fmt.Errorf("retrieving current weather: %w", err)},
-			"",
-		)
+// GetCurrentByCoordsLegacy responds with the current weather in the original, single resource, shape.
+// Only the deprecated root route uses it.
+func (h *Handlers) GetCurrentByCoordsLegacy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lat, lon, weather, ok := h.currentWeather(w, r)
+	if !ok {
 		return
 	}
 	// remap structure to API
 	cond := strings.Join(weather.States, ", ")
 	resp := getCurrentByCoordsResponse{
 		ID:   "urn:weather:current:id",
-		Type: "urn:weather:current",
-		Attribtues: currentAttributes{
+		Type: typeCurrentWeather,
+		Attributes: currentAttributes{
 			Temperature: string(weather.Temperature),
 			Condition:   cond,
 			Latitude:    preciseFloat32(lat),
 			Longitude:   preciseFloat32(lon),
 		},
 	}
-	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+	writeResponse(ctx, w, http.StatusOK, "application/json", &resp)
+}
+
+// currentWeather gets the current weather for the requested coordinates.
+// If it fails, the error has already been written to the response.
+func (h *Handlers) currentWeather(w http.ResponseWriter, r *http.Request) (float64, float64, *domain.Weather, bool) {
+	lat, lon, errs := coordsFromRequest(r)
+	if len(errs) > 0 {
+		encodeError(r.Context(), w, http.StatusBadRequest, errs, "required query parameters")
+		return 0, 0, nil, false
+	}
+	weather, ok := h.currentAt(w, r, lat, lon)
+	return lat, lon, weather, ok
+}
+
+// currentAt gets the current weather at the coordinates.
+// If it fails, the error has already been written to the response.
+func (h *Handlers) currentAt(w http.ResponseWriter, r *http.Request, lat float64, lon float64) (*domain.Weather, bool) {
+	ctx := r.Context()
+	// business logic
+	weather, err := h.Domain.CurrentIn(ctx, float32(lat), float32(lon))
+	if err != nil {
 		encodeError(
 			ctx,
 			w,
 			http.StatusInternalServerError,
-			[]error{fmt.Errorf("encoding current weather response: %w", err)},
+			[]error{fmt.Errorf("retrieving current weather: %w", err)},
 			"",
 		)
-		return
+		return nil, false
 	}
+	return weather, true
+}
+
+// coordsFromRequest reads the latitude and longitude from the route variables, or the query parameters
+func coordsFromRequest(r *http.Request) (float64, float64, []error) {
+	vars := mux.Vars(r)
+	q := r.URL.Query()
+	errs := []error{}
+	parse := func(name string) float64 {
+		s, ok := vars[name]
+		if !ok {
+			s = q.Get(name)
+		}
+		if s == "" {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrMissingParam, name))
+			return 0
+		}
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidFloat, name))
+		}
+		return f
+	}
+	lat := parse("latitude")
+	lon := parse("longitude")
+	return lat, lon, errs
 }
 
 // Creates and writes an error
 func encodeError(ctx context.Context, w http.ResponseWriter, statusCode int, errs []error, message string) {
 	l := log.Ctx(ctx)
-	resp := errorResponse{Status: statusCode}
+	resp := errorResponse{
+		Status:    statusCode,
+		RequestID: requestid.FromContext(ctx),
+	}
+	catalog := i18n.For(i18n.FromContext(ctx))
 	event := l.Error()
 	for _, e := range errs {
 		item := errorItem{
-			Error:   e.Error(),
-			Message: message,
+			Error:   localizeError(catalog, e),
+			Message: catalog.Error(message),
 		}
 		resp.Errors = append(resp.Errors, item)
 		event.Err(e)
 	}
 	event.Int("status_code", statusCode).Send()
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	if err := json.NewEncoder(w).Encode(&resp); err != nil {
-		b := []byte(fmt.Sprintf(`{"status":500,"error":"error encoding error response: %s}`, err))
-		if _, err := w.Write(b); err != nil {
-			l.Error().Err(err).Msg("writing default error to response writer")
-			return
-		}
-	}
+	writeResponse(ctx, w, statusCode, "application/json", &resp)
 }
@@ -9,8 +9,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/repo"
 	"github.com/broganross/weather-exercise/server"
 )
 
@@ -80,7 +82,7 @@ func TestWeatherSource_GetCurrentIn(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, "http://localhost/"+test.path, nil)
 			w := httptest.NewRecorder()
-			handler.GetCurrentByCoords(w, req)
+			handler.GetCurrentByCoordsLegacy(w, req)
 			resp := w.Result()
 			if resp.StatusCode != test.code {
 				t.Errorf("expected code '%v' got '%v'", test.code, resp.StatusCode)
@@ -103,3 +105,188 @@ func TestWeatherSource_GetCurrentIn(t *testing.T) {
 		})
 	}
 }
+
+// The legacy route classified Open Weather's Kelvin temperatures as Fahrenheit, so everything was hot,
+// until the domain converted them
+func TestWeatherSource_GetCurrentIn_units(t *testing.T) {
+	upstream := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if units := r.URL.Query().Get("units"); units != "" {
+				t.Errorf("expected no units got '%v'", units)
+			}
+			w.Write([]byte(`{"coord":{"lat":1.2,"lon":2.3},"weather":[{"id":600,"main":"Snow"}],"main":{"temp":274.8},"dt":1709294400}`))
+		}))
+	defer upstream.Close()
+	handler := server.Handlers{
+		Domain: &domain.WeatherService{
+			Source: &repo.OpenWeather{BaseURL: upstream.URL, Client: upstream.Client(), Timeout: 5 * time.Second},
+		},
+	}
+	w := httptest.NewRecorder()
+	handler.GetCurrentByCoordsLegacy(w, httptest.NewRequest(http.MethodGet, "http://localhost/?latitude=1.2&longitude=2.3", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected '%v' got '%v': '%v'", http.StatusOK, w.Code, w.Body.String())
+	}
+	if want := `"temperature":"cold"`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected '%v' in '%v'", want, w.Body.String())
+	}
+}
+
+func TestHandlers_GetCurrentByCoords(t *testing.T) {
+	lagos, err := time.LoadLocation("Africa/Lagos")
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	handler := server.Handlers{
+		Domain: &mockWeatherDomain{
+			responses: map[string]mockWeatherDomainResponse{
+				"1.20:2.30": {
+					weather: domain.Weather{
+						Coords: domain.Coords{
+							Latitude:  1.25,
+							Longitude: 2.25,
+						},
+						Place: domain.Place{
+							Name:    "Nowhere",
+							Country: "IT",
+						},
+						States:      []string{"rain", "mist"},
+						Temperature: domain.TempCold,
+						ObservedAt:  time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+						Sunrise:     time.Date(2024, time.March, 1, 5, 45, 0, 0, time.UTC),
+						Daytime:     true,
+						Location:    lagos,
+						Source: domain.Source{
+							Provider:  "test",
+							FetchedAt: time.Date(2024, time.March, 1, 12, 5, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+		},
+	}
+	data := `"data":{"id":"urn:weather:current:1.200000,2.300000:1709294400","type":"urn:weather:current",` +
+		`"attributes":{"latitude":1.200000,"longitude":2.300000,"temperature":"cold","condition":"rain, mist","temperature_label":"cold","observed_at":"2024-03-01T12:00:00Z",` +
+		`"observed_at_local":"2024-03-01T13:00:00+01:00","sunrise":"2024-03-01T06:45:00+01:00","timezone":"Africa/Lagos","utc_offset":3600,"period":"day"},` +
+		`"relationships":{"location":{"data":{"id":"urn:weather:location:1.200000,2.300000","type":"urn:weather:location"}}},` +
+		`"links":{"self":"/v1/locations/1.200000,2.300000/weather"}}`
+	rest := `"links":{"self":"/v1/locations/1.200000,2.300000/weather"},` +
+		`"meta":{"provider":"test","fetched_at":"2024-03-01T12:05:00Z"}}` + "\n"
+	included := `"included":[{"id":"urn:weather:location:1.200000,2.300000","type":"urn:weather:location",` +
+		`"attributes":{"latitude":1.250000,"longitude":2.250000,"name":"Nowhere","country":"IT"}}],`
+	tests := []struct {
+		name        string
+		path        string
+		code        int
+		contentType string
+		body        string
+	}{
+		{
+			"document",
+			"?latitude=1.2&longitude=2.3",
+			http.StatusOK,
+			"application/vnd.api+json",
+			"{" + data + "," + rest,
+		},
+		{
+			"include-location",
+			"?latitude=1.2&longitude=2.3&include=location",
+			http.StatusOK,
+			"application/vnd.api+json",
+			"{" + data + "," + included + rest,
+		},
+		{
+			"unsupported-include",
+			"?latitude=1.2&longitude=2.3&include=forecast",
+			http.StatusBadRequest,
+			"application/json",
+			`{"errors":[{"error":"unsupported include: forecast"}],"status":400}` + "\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current"+test.path, nil)
+			w := httptest.NewRecorder()
+			handler.GetCurrentByCoords(w, req)
+			resp := w.Result()
+			if resp.StatusCode != test.code {
+				t.Errorf("expected code '%v' got '%v'", test.code, resp.StatusCode)
+			}
+			if h := resp.Header.Get("Content-Type"); h != test.contentType {
+				t.Errorf("expected Content-Type header '%v' got '%v'", test.contentType, h)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Errorf("reading body: %v", err)
+			}
+			if string(body) != test.body {
+				t.Errorf("expected body '%v' got '%v'", test.body, string(body))
+			}
+		})
+	}
+}
+
+func TestHandlers_GetCurrentByCoords_UV(t *testing.T) {
+	uv := domain.ClassifyUV(7)
+	handler := server.Handlers{
+		Domain: &mockWeatherDomain{
+			responses: map[string]mockWeatherDomainResponse{
+				"1.20:2.30": {weather: domain.Weather{Temperature: domain.TempHot, UV: &uv}},
+			},
+		},
+	}
+	w := httptest.NewRecorder()
+	handler.GetCurrentByCoords(w, httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current?latitude=1.2&longitude=2.3", nil))
+	want := `"uv":{"index":7,"category":"high","protection":"` + uv.Protection + `"}`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected '%v' in '%v'", want, w.Body.String())
+	}
+}
+
+func TestHandlers_GetCurrentByCoords_Derived(t *testing.T) {
+	dewPoint, heatIndex := float32(82.66), float32(121.04)
+	derived := domain.Derived{
+		DewPoint:            &dewPoint,
+		Comfort:             domain.ComfortHumid,
+		HeatIndex:           &heatIndex,
+		Beaufort:            3,
+		BeaufortDescription: "Gentle breeze",
+		WindDirection:       "SSW",
+		Visibility:          domain.VisibilityGood,
+		Precipitation:       domain.PrecipitationNone,
+	}
+	handler := server.Handlers{
+		Domain: &mockWeatherDomain{
+			responses: map[string]mockWeatherDomainResponse{
+				"1.20:2.30": {weather: domain.Weather{Temperature: domain.TempHot, Derived: &derived}},
+			},
+		},
+	}
+	w := httptest.NewRecorder()
+	handler.GetCurrentByCoords(w, httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current?latitude=1.2&longitude=2.3", nil))
+	want := `"derived":{"dew_point":82.7,"comfort":"humid","heat_index":121,"beaufort":{"number":3,"description":"Gentle breeze"},"wind_direction":"SSW","visibility":"good","precipitation":"none"}`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected '%v' in '%v'", want, w.Body.String())
+	}
+}
+
+func TestHandlers_GetCurrentByCoords_Conditions(t *testing.T) {
+	mist, _ := domain.LookupPhenomenon("mist")
+	showers, _ := domain.LookupPhenomenon("heavy_rain_showers")
+	handler := server.Handlers{
+		Domain: &mockWeatherDomain{
+			responses: map[string]mockWeatherDomainResponse{
+				"1.20:2.30": {weather: domain.Weather{Temperature: domain.TempMod, Phenomena: []domain.Phenomenon{mist, showers}}},
+			},
+		},
+	}
+	w := httptest.NewRecorder()
+	handler.GetCurrentByCoords(w, httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current?latitude=1.2&longitude=2.3", nil))
+	want := `"primary_condition":{"code":"heavy_rain_showers","category":"rain","intensity":"heavy","severity":5,"description":"heavy rain showers","icon":"showers-night","icons":{"day":"showers-day","night":"showers-night"}}`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected '%v' in '%v'", want, w.Body.String())
+	}
+	if want := `"conditions":[{"code":"mist",`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected '%v' in '%v'", want, w.Body.String())
+	}
+}
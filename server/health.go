@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Checker is a dependency that can report whether it's reachable
+type Checker interface {
+	Ping(ctx context.Context) error
+}
+
+// Health serves liveness and readiness probes.
+// Configuration is loaded before the server starts, so being able to answer implies it loaded.
+type Health struct {
+	// Dependencies that must be reachable for the service to be ready, by name
+	Checks map[string]Checker
+	// Budget for each dependency check
+	Timeout  time.Duration
+	draining atomic.Bool
+}
+
+// Drain marks the service as shutting down, so readiness fails and load balancers stop sending traffic
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Live reports that the process is up and able to serve requests
+func (h *Health) Live(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, http.StatusOK, healthResponse{Status: healthOK})
+}
+
+// Ready reports whether the service is able to handle traffic
+func (h *Health) Ready(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeHealth(w, r, http.StatusServiceUnavailable, healthResponse{Status: healthDraining})
+		return
+	}
+	resp := healthResponse{
+		Status: healthOK,
+		Checks: make(map[string]string, len(h.Checks)),
+	}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for name, check := range h.Checks {
+		wg.Add(1)
+		go func(name string, check Checker) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+			defer cancel()
+			result := healthOK
+			if err := check.Ping(ctx); err != nil {
+				log.Ctx(r.Context()).Warn().Err(err).Str("check", name).Msg("readiness check failed")
+				result = healthFail
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Checks[name] = result
+			if result != healthOK {
+				resp.Status = healthFailing
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	status := http.StatusOK
+	if resp.Status != healthOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, r, status, resp)
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, statusCode int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("encoding health response")
+	}
+}
+
+// URLCheck checks that a service answers HTTP requests without a server error
+type URLCheck struct {
+	URL    string
+	Client *http.Client
+}
+
+func (uc *URLCheck) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, uc.URL, nil)
+	if err != nil {
+		return fmt.Errorf("creating health check request: %w", err)
+	}
+	client := uc.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("executing health check request: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("health check: %s", http.StatusText(resp.StatusCode))
+	}
+	return nil
+}
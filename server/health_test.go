@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/server"
+)
+
+type mockChecker struct {
+	err error
+}
+
+func (mc *mockChecker) Ping(ctx context.Context) error {
+	return mc.err
+}
+
+func TestHealth_Ready(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks map[string]server.Checker
+		drain  bool
+		code   int
+		body   map[string]interface{}
+	}{
+		{
+			"ready",
+			map[string]server.Checker{"upstream": &mockChecker{}},
+			false,
+			http.StatusOK,
+			map[string]interface{}{"status": "ok", "checks": map[string]interface{}{"upstream": "ok"}},
+		},
+		{
+			"dependency-down",
+			map[string]server.Checker{"upstream": &mockChecker{}, "auth": &mockChecker{err: errors.New("dial tcp 10.0.0.7:443: connection refused")}},
+			false,
+			http.StatusServiceUnavailable,
+			map[string]interface{}{"status": "failing", "checks": map[string]interface{}{"upstream": "ok", "auth": "fail"}},
+		},
+		{
+			"draining",
+			map[string]server.Checker{"upstream": &mockChecker{}},
+			true,
+			http.StatusServiceUnavailable,
+			map[string]interface{}{"status": "draining"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := server.Health{Checks: test.checks, Timeout: time.Second}
+			if test.drain {
+				h.Drain()
+			}
+			w := httptest.NewRecorder()
+			h.Ready(w, httptest.NewRequest(http.MethodGet, "http://localhost/readyz", nil))
+			if w.Code != test.code {
+				t.Errorf("expected code '%v' got '%v'", test.code, w.Code)
+			}
+			got := map[string]interface{}{}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if !reflect.DeepEqual(test.body, got) {
+				t.Errorf("expected body '%v' got '%v'", test.body, got)
+			}
+		})
+	}
+}
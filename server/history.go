@@ -0,0 +1,81 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+)
+
+// GetHistory responds with the weather at the coordinates at the time in the at query parameter, from the observation
+// archive or the provider.  Archived observations are marked as cache hits.  The location can be included with include=location.
+func (h *Handlers) GetHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	include, err := parseInclude(r, includeLocation)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	lat, lon, errs := coordsFromRequest(r)
+	at, err := historyTime(r)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "required query parameters")
+		return
+	}
+	weather, err := h.History.HistoryAt(ctx, float32(lat), float32(lon), at)
+	switch {
+	case errors.Is(err, domain.ErrFutureHistory):
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	case errors.Is(err, domain.ErrNoHistory):
+		encodeError(ctx, w, http.StatusNotFound, []error{err}, "")
+		return
+	case err != nil:
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("retrieving weather history: %w", err)}, "")
+		return
+	}
+	writeDocument(ctx, w, http.StatusOK, historyDocument(lat, lon, weather, include, i18n.For(i18n.FromContext(ctx))))
+}
+
+// historyTime reads the at query parameter, which is required
+func historyTime(r *http.Request) (time.Time, error) {
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		return time.Time{}, fmt.Errorf("%w: at", ErrMissingParam)
+	}
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: at: %w", ErrInvalidTime, err)
+	}
+	return t, nil
+}
+
+// historyDocument is the current weather document, as it was at the observation.
+// The ID is unique per location and observation, like current weather's.
+func historyDocument(lat float64, lon float64, weather *domain.Weather, include map[string]bool, catalog *i18n.Catalog) *document {
+	doc := currentWeatherDocument(lat, lon, weather, include, catalog)
+	res := doc.Data.(*resource)
+	coords := formatCoord(lat) + "," + formatCoord(lon)
+	res.ID = fmt.Sprintf("%s:%s:%d", typeHistory, coords, weather.ObservedAt.Unix())
+	res.Type = typeHistory
+	q := url.Values{}
+	q.Set("latitude", formatCoord(lat))
+	q.Set("longitude", formatCoord(lon))
+	q.Set("at", weather.ObservedAt.UTC().Format(time.RFC3339))
+	res.Links = &links{Self: "/v1/weather/history?" + q.Encode()}
+	doc.Links = res.Links
+	// history is the only place the archive can answer instead of the provider
+	meta := doc.Meta.(*sourceMeta)
+	meta.Cache = "miss"
+	if weather.Source.Cached {
+		meta.Cache = "hit"
+	}
+	return doc
+}
@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+)
+
+type mockHistorian struct {
+	err error
+	// provided is history from the provider rather than the archive
+	provided bool
+}
+
+func (m *mockHistorian) HistoryAt(ctx context.Context, lat float32, lon float32, at time.Time) (*domain.Weather, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &domain.Weather{
+		Coords:      domain.Coords{Latitude: lat, Longitude: lon},
+		States:      []string{"rain"},
+		Temperature: domain.TempMod,
+		ObservedAt:  time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC),
+		Source:      domain.Source{Provider: "openweather", Cached: !m.provided},
+	}, nil
+}
+
+type historyDocument struct {
+	Data struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		Attributes struct {
+			Condition  string    `json:"condition"`
+			ObservedAt time.Time `json:"observed_at"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Links struct {
+		Self string `json:"self"`
+	} `json:"links"`
+	Meta struct {
+		Cache string `json:"cache"`
+	} `json:"meta"`
+}
+
+func TestHandlers_GetHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		history *mockHistorian
+		code    int
+	}{
+		{"history", "http://localhost/v1/weather/history?latitude=35.4676&longitude=-97.5164&at=2024-03-05T14:10:00Z", &mockHistorian{}, http.StatusOK},
+		{"provided", "http://localhost/v1/weather/history?latitude=35.4676&longitude=-97.5164&at=2024-03-05T14:10:00Z", &mockHistorian{provided: true}, http.StatusOK},
+		{"missing-at", "http://localhost/v1/weather/history?latitude=35.4676&longitude=-97.5164", &mockHistorian{}, http.StatusBadRequest},
+		{"invalid-at", "http://localhost/v1/weather/history?latitude=35.4676&longitude=-97.5164&at=last-tuesday", &mockHistorian{}, http.StatusBadRequest},
+		{"future", "http://localhost/v1/weather/history?latitude=35.4676&longitude=-97.5164&at=2099-03-05T14:10:00Z", &mockHistorian{err: domain.ErrFutureHistory}, http.StatusBadRequest},
+		{"not-found", "http://localhost/v1/weather/history?latitude=35.4676&longitude=-97.5164&at=2024-03-05T14:10:00Z", &mockHistorian{err: domain.ErrNoHistory}, http.StatusNotFound},
+		{"failing", "http://localhost/v1/weather/history?latitude=35.4676&longitude=-97.5164&at=2024-03-05T14:10:00Z", &mockHistorian{err: errors.New("boom")}, http.StatusInternalServerError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := server.Handlers{History: test.history}
+			w := httptest.NewRecorder()
+			h.GetHistory(w, httptest.NewRequest(http.MethodGet, test.url, nil))
+			if w.Code != test.code {
+				t.Fatalf("expected '%v' got '%v'", test.code, w.Code)
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+			doc := historyDocument{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if expected := "urn:weather:history:35.467602,-97.516403:1709647200"; doc.Data.ID != expected {
+				t.Errorf("expected '%v' got '%v'", expected, doc.Data.ID)
+			}
+			if doc.Data.Type != "urn:weather:history" || doc.Data.Attributes.Condition != "rain" {
+				t.Errorf("expected the historical rain got '%v'", doc.Data)
+			}
+			if expected := "/v1/weather/history?at=2024-03-05T14%3A00%3A00Z&latitude=35.467602&longitude=-97.516403"; doc.Links.Self != expected {
+				t.Errorf("expected '%v' got '%v'", expected, doc.Links.Self)
+			}
+			expected := "hit"
+			if test.history.provided {
+				expected = "miss"
+			}
+			if doc.Meta.Cache != expected {
+				t.Errorf("expected '%v' got '%v'", expected, doc.Meta.Cache)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+)
+
+// A small subset of JSON:API (https://jsonapi.org/format/) documents
+
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// Resource types
+const (
+	typeCurrentWeather = "urn:weather:current"
+	typeLocation       = "urn:weather:location"
+	typeError          = "urn:weather:error"
+	typeArea           = "urn:weather:area"
+	typeRoute          = "urn:weather:route"
+	typeSubscription   = "urn:weather:subscription"
+	typeDelivery       = "urn:weather:delivery"
+	typeAlert          = "urn:weather:alert"
+	typeAirQuality     = "urn:weather:air-quality"
+	typeAstronomy      = "urn:weather:astronomy"
+	typeHistory        = "urn:weather:history"
+)
+
+// Relationships that can be requested with the include query parameter
+const (
+	includeLocation   = "location"
+	includeAirQuality = "air_quality"
+)
+
+type document struct {
+	// a single *resource, or a []resource collection
+	Data     any        `json:"data"`
+	Included []resource `json:"included,omitempty"`
+	Links    *links     `json:"links,omitempty"`
+	Meta     any        `json:"meta,omitempty"`
+}
+
+type resource struct {
+	ID            string                  `json:"id"`
+	Type          string                  `json:"type"`
+	Attributes    any                     `json:"attributes,omitempty"`
+	Relationships map[string]relationship `json:"relationships,omitempty"`
+	Links         *links                  `json:"links,omitempty"`
+	Meta          any                     `json:"meta,omitempty"`
+}
+
+type resourceIdentifier struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type relationship struct {
+	Data *resourceIdentifier `json:"data"`
+}
+
+type links struct {
+	Self string `json:"self,omitempty"`
+}
+
+// parseInclude reads the requested relationships from the include query parameter,
+// failing if any aren't in allowed
+func parseInclude(r *http.Request, allowed ...string) (map[string]bool, error) {
+	include := map[string]bool{}
+	q := r.URL.Query().Get("include")
+	if q == "" {
+		return include, nil
+	}
+	for _, name := range strings.Split(q, ",") {
+		ok := false
+		for _, a := range allowed {
+			ok = ok || name == a
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedInclude, name)
+		}
+		include[name] = true
+	}
+	return include, nil
+}
+
+// formatCoord formats a coordinate for use in IDs and links, at the same precision as responses
+func formatCoord(f float64) string {
+	return strconv.FormatFloat(f, 'f', 6, 32)
+}
+
+// currentWeatherDocument builds the document for the current weather at the requested coordinates
+func currentWeatherDocument(lat float64, lon float64, weather *domain.Weather, include map[string]bool, catalog *i18n.Catalog) *document {
+	current, loc := currentWeatherResource(lat, lon, weather, catalog)
+	doc := &document{
+		Data:  &current,
+		Links: current.Links,
+		Meta:  newSourceMeta(weather.Source),
+	}
+	if include[includeLocation] {
+		doc.Included = append(doc.Included, loc)
+	}
+	return doc
+}
+
+// currentWeatherResource builds the resource for the current weather at the requested coordinates,
+// along with the location resource it's related to.
+// The ID is unique per location and observation.
+func currentWeatherResource(lat float64, lon float64, weather *domain.Weather, catalog *i18n.Catalog) (resource, resource) {
+	coords := formatCoord(lat) + "," + formatCoord(lon)
+	loc := resource{
+		ID:   fmt.Sprintf("%s:%s", typeLocation, coords),
+		Type: typeLocation,
+		Attributes: &locationAttributes{
+			Latitude:  preciseFloat32(weather.Coords.Latitude),
+			Longitude: preciseFloat32(weather.Coords.Longitude),
+			Name:      weather.Place.Name,
+			Country:   weather.Place.Country,
+		},
+	}
+	zone := weather.Location
+	if zone == nil {
+		zone = time.UTC
+	}
+	observedLocal := weather.ObservedAt.In(zone)
+	_, offset := observedLocal.Zone()
+	current := resource{
+		ID:   fmt.Sprintf("%s:%s:%d", typeCurrentWeather, coords, weather.ObservedAt.Unix()),
+		Type: typeCurrentWeather,
+		Attributes: &currentWeatherAttributes{
+			currentAttributes: currentAttributes{
+				Latitude:    preciseFloat32(lat),
+				Longitude:   preciseFloat32(lon),
+				Temperature: string(weather.Temperature),
+				Condition:   strings.Join(weather.States, ", "),
+			},
+			TemperatureLabel: catalog.Temperature(string(weather.Temperature)),
+			ObservedAt:       weather.ObservedAt,
+			ObservedAtLocal:  observedLocal,
+			Sunrise:          localOrNil(weather.Sunrise, zone),
+			Sunset:           localOrNil(weather.Sunset, zone),
+			Timezone:         zone.String(),
+			UTCOffset:        offset,
+			Period:           period(weather.Daytime),
+			Conditions:       newConditionAttributes(weather.Phenomena, weather.Daytime, catalog),
+			PrimaryCondition: newPrimaryCondition(weather.Phenomena, weather.Daytime, catalog),
+			UV:               newUVAttributes(weather.UV),
+			Derived:          newDerivedAttributes(weather.Derived),
+		},
+		Relationships: map[string]relationship{
+			includeLocation: {Data: &resourceIdentifier{ID: loc.ID, Type: loc.Type}},
+		},
+		Links: &links{Self: fmt.Sprintf("/v1/locations/%s/weather", coords)},
+	}
+	return current, loc
+}
+
+func newSourceMeta(s domain.Source) *sourceMeta {
+	return &sourceMeta{
+		Provider:  s.Provider,
+		FetchedAt: s.FetchedAt.Truncate(time.Second),
+	}
+}
+
+// localOrNil is t in the zone, or nil when it's unknown
+func localOrNil(t time.Time, zone *time.Location) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	local := t.In(zone)
+	return &local
+}
+
+func period(daytime bool) string {
+	if daytime {
+		return "day"
+	}
+	return "night"
+}
+
+func newUVAttributes(uv *domain.UV) *uvAttributes {
+	if uv == nil {
+		return nil
+	}
+	return &uvAttributes{
+		Index:      uv.Index,
+		Category:   string(uv.Category),
+		Protection: uv.Protection,
+		Night:      uv.Night,
+	}
+}
+
+func newConditionAttributes(ps []domain.Phenomenon, daytime bool, catalog *i18n.Catalog) []conditionAttributes {
+	if len(ps) == 0 {
+		return nil
+	}
+	conditions := make([]conditionAttributes, len(ps))
+	for i, p := range ps {
+		conditions[i] = newCondition(p, daytime, catalog)
+	}
+	return conditions
+}
+
+func newPrimaryCondition(ps []domain.Phenomenon, daytime bool, catalog *i18n.Catalog) *conditionAttributes {
+	p, ok := domain.PrimaryPhenomenon(ps)
+	if !ok {
+		return nil
+	}
+	condition := newCondition(p, daytime, catalog)
+	return &condition
+}
+
+func newCondition(p domain.Phenomenon, daytime bool, catalog *i18n.Catalog) conditionAttributes {
+	return conditionAttributes{
+		Code:        p.Code,
+		Category:    string(p.Category),
+		Intensity:   string(p.Intensity),
+		Severity:    p.Severity(),
+		Description: catalog.Condition(p.Code, p.Description),
+		Icon:        p.Icon(daytime),
+		Icons:       iconAttributes{Day: p.DayIcon, Night: p.NightIcon},
+	}
+}
+
+func newDerivedAttributes(d *domain.Derived) *derivedAttributes {
+	if d == nil {
+		return nil
+	}
+	return &derivedAttributes{
+		DewPoint:  tenthOrNil(d.DewPoint),
+		Comfort:   string(d.Comfort),
+		HeatIndex: tenthOrNil(d.HeatIndex),
+		WindChill: tenthOrNil(d.WindChill),
+		Beaufort: beaufortAttributes{
+			Number:      d.Beaufort,
+			Description: d.BeaufortDescription,
+		},
+		WindDirection: d.WindDirection,
+		Visibility:    string(d.Visibility),
+		Precipitation: string(d.Precipitation),
+	}
+}
+
+// tenthOrNil rounds to a tenth of a degree, which is as precise as the provider's temperatures
+func tenthOrNil(f *float32) *float64 {
+	if f == nil {
+		return nil
+	}
+	rounded := roundTo(float64(*f), 1)
+	return &rounded
+}
+
+// writeDocument writes a JSON:API document, in the negotiated media type
+func writeDocument(ctx context.Context, w http.ResponseWriter, statusCode int, doc *document) {
+	writeResponse(ctx, w, statusCode, jsonAPIMediaType, doc)
+}
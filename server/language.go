@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+)
+
+// LanguageMiddleware picks the language for the response from the lang query parameter, or the Accept-Language
+// header, and echoes it in Content-Language.  A language there's no catalog for falls back to the next preference,
+// then English, rather than failing the request.
+func LanguageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language := i18n.Match(r.URL.Query().Get("lang"), r.Header.Values("Accept-Language"))
+		w.Header().Set("Content-Language", language)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.NewContext(r.Context(), language)))
+	})
+}
+
+// translatableErrors are the errors clients are told about whose text is translated.  Whatever detail is wrapped
+// around them, like the parameter name or a parse error, stays as it is.
+var translatableErrors = []error{
+	ErrMissingParam,
+	ErrInvalidFloat,
+	ErrUnsupportedInclude,
+	ErrNotAcceptable,
+	ErrInvalidTime,
+	ErrInvalidBody,
+	ErrTooManyPoints,
+	ErrInvalidCallback,
+	ErrSubscriptionLimit,
+	ErrNotSubscribed,
+	ErrSubscribed,
+	ErrUnknownMessage,
+	domain.ErrNoSamples,
+	domain.ErrBatchTooLarge,
+	domain.ErrNoHistory,
+	domain.ErrFutureHistory,
+	domain.ErrInvalidRoute,
+	domain.ErrBeyondForecast,
+	domain.ErrWebhookNotFound,
+	domain.ErrInvalidWebhook,
+}
+
+// localizeError is the error's text with the translatable errors in it translated
+func localizeError(catalog *i18n.Catalog, err error) string {
+	text := err.Error()
+	for _, t := range translatableErrors {
+		if errors.Is(err, t) {
+			text = strings.Replace(text, t.Error(), catalog.Error(t.Error()), 1)
+		}
+	}
+	return text
+}
@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+	"github.com/broganross/weather-exercise/server"
+)
+
+func TestLanguageMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		acceptLanguage string
+		expected       string
+	}{
+		{"default", "/", "", "en"},
+		{"param", "/?lang=de", "fr", "de"},
+		{"header", "/", "pt-BR,pt;q=0.9", "pt"},
+		{"unsupported", "/?lang=tlh", "ko", "en"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+			handler := server.LanguageMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = i18n.FromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "http://localhost"+test.path, nil)
+			if test.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", test.acceptLanguage)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if got != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+			if h := w.Header().Get("Content-Language"); h != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, h)
+			}
+			if h := w.Header().Get("Vary"); h != "Accept-Language" {
+				t.Errorf("expected 'Accept-Language' got '%v'", h)
+			}
+		})
+	}
+}
+
+func TestLanguageMiddleware_errors(t *testing.T) {
+	handler := server.LanguageMiddleware(http.HandlerFunc((&server.Handlers{}).GetCurrentByCoords))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current?lang=de", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected '%v' got '%v'", http.StatusBadRequest, w.Code)
+	}
+	body := struct {
+		Errors []struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(body.Errors) == 0 {
+		t.Fatalf("expected errors in '%v'", w.Body.String())
+	}
+	if expected := "fehlender Abfrageparameter: latitude"; body.Errors[0].Error != expected {
+		t.Errorf("expected '%v' got '%v'", expected, body.Errors[0].Error)
+	}
+	if expected := "erforderliche Abfrageparameter"; body.Errors[0].Message != expected {
+		t.Errorf("expected '%v' got '%v'", expected, body.Errors[0].Message)
+	}
+}
+
+func TestLanguageMiddleware_currentWeather(t *testing.T) {
+	showers, _ := domain.LookupPhenomenon("heavy_rain_showers")
+	handler := server.LanguageMiddleware(http.HandlerFunc((&server.Handlers{
+		Domain: &mockWeatherDomain{
+			responses: map[string]mockWeatherDomainResponse{
+				"1.20:2.30": {weather: domain.Weather{Temperature: domain.TempHot, Phenomena: []domain.Phenomenon{showers}}},
+			},
+		},
+	}).GetCurrentByCoords))
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/v1/weather/current?latitude=1.2&longitude=2.3", nil)
+	req.Header.Set("Accept-Language", "de-DE")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	for _, want := range []string{
+		`"temperature":"hot"`,
+		`"temperature_label":"heiß"`,
+		`"code":"heavy_rain_showers"`,
+		`"description":"starke Regenschauer"`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("expected '%v' in '%v'", want, w.Body.String())
+		}
+	}
+}
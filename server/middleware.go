@@ -1,27 +1,48 @@
 package server
 
 import (
+	"bufio"
+	"context"
 	"errors"
+	"net"
 	"net/http"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/broganross/weather-exercise/internal/sample"
+	"github.com/broganross/weather-exercise/metrics"
+	"github.com/broganross/weather-exercise/requestid"
+	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/broganross/weather-exercise/server")
+
 // LogContextMiddleware injects a logger into the context and adds a request id.
-// TODO: extend to log outgoing request statuses
+// Client supplied request IDs are only used if they're valid, otherwise a new one is generated.
+// The ID is echoed back in the response headers.
 func LogContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestID := r.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = uuid.NewString()
+		requestID := r.Header.Get(requestid.Header)
+		rejected := requestID != "" && !requestid.Valid(requestID)
+		if requestID == "" || rejected {
+			requestID = requestid.New()
 		}
 		l := log.With().Logger()
 		l.UpdateContext(func(c zerolog.Context) zerolog.Context {
 			return c.Str("request-id", requestID)
 		})
-		r = r.WithContext(l.WithContext(r.Context()))
+		ctx := requestid.NewContext(r.Context(), requestID)
+		r = r.WithContext(l.WithContext(ctx))
+		w.Header().Set(requestid.Header, requestID)
+		if rejected {
+			l.Warn().Msg("replaced invalid client request id")
+		}
 		l.Debug().
 			Str("method", r.Method).
 			Stringer("URL", r.URL).
@@ -31,6 +52,89 @@ func LogContextMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// TracingMiddleware starts a server span for each request, continuing any W3C trace context sent by the client.
+// The trace and span IDs are added to the request's logger.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeTemplate(r)
+		ctx, span := tracer.Start(
+			ctx,
+			r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+		if sc := span.SpanContext(); sc.IsValid() {
+			l := log.Ctx(ctx).With().
+				Str("trace-id", sc.TraceID().String()).
+				Str("span-id", sc.SpanID().String()).
+				Logger()
+			ctx = l.WithContext(ctx)
+		}
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		status := rec.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// AccessLog logs a summary of every request once it has been handled.
+// Failed requests are always logged, successful ones are sampled at SampleRate (0 to 1).
+type AccessLog struct {
+	SampleRate float64
+}
+
+func (al *AccessLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		entry := &accessEntry{}
+		rec := &responseRecorder{ResponseWriter: w}
+		r = r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry))
+		next.ServeHTTP(rec, r)
+
+		status := rec.Status()
+		if status < http.StatusBadRequest && !sample.Keep(al.SampleRate) {
+			return
+		}
+		l := log.Ctx(r.Context())
+		var event *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = l.Error()
+		case status >= http.StatusBadRequest:
+			event = l.Warn()
+		default:
+			event = l.Info()
+		}
+		event.
+			Str("method", r.Method).
+			Str("route", routeTemplate(r)).
+			Int("status", status).
+			Int("bytes", rec.bytes).
+			Dur("latency", time.Since(start)).
+			Str("principal", entry.principal).
+			Msg("request completed")
+	})
+}
+
+// MetricsMiddleware records request counts and latencies by route template and status
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		metrics.ObserveRequest(routeTemplate(r), r.Method, rec.Status(), time.Since(start))
+	})
+}
+
 // AuthMiddleware an example auth external service
 type Auth struct {
 	BaseURL string
@@ -40,6 +144,7 @@ func (am *Auth) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authenticated := true
 		authorized := true
+		principal := "anonymous"
 		// pull an auth token from the header
 		// check its validity in some repository
 		// check that the user can access the resource
@@ -59,6 +164,84 @@ func (am *Auth) Middleware(next http.Handler) http.Handler {
 			)
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withPrincipal(r, principal))
 	})
 }
+
+type principalKey struct{}
+
+// PrincipalFrom returns the authenticated principal for the request context, if there is one
+func PrincipalFrom(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(string)
+	return p
+}
+
+// withPrincipal stores the principal in the request context, and on the access log entry
+func withPrincipal(r *http.Request, principal string) *http.Request {
+	if entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry); ok {
+		entry.principal = principal
+	}
+	return r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+}
+
+type accessEntryKey struct{}
+
+// accessEntry collects details about a request from inner handlers for the access log
+type accessEntry struct {
+	principal string
+}
+
+// responseRecorder keeps track of the status code and body size written through a ResponseWriter
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rr *responseRecorder) WriteHeader(statusCode int) {
+	if rr.status == 0 {
+		rr.status = statusCode
+	}
+	rr.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
+
+// Hijack hands the connection over to the handler, for WebSockets, which check for it directly
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(rr.ResponseWriter).Hijack()
+	if err == nil && rr.status == 0 {
+		rr.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Status returns the written status code, which is 200 if the handler never wrote one
+func (rr *responseRecorder) Status() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
+
+// routeTemplate returns the path template of the matched route, falling back to the raw path
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
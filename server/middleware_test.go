@@ -0,0 +1,109 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/broganross/weather-exercise/requestid"
+	"github.com/broganross/weather-exercise/server"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+func TestAccessLog_Middleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate float64
+		status     int
+		logged     bool
+	}{
+		{"success-logged", 1, http.StatusOK, true},
+		{"success-sampled-out", 0, http.StatusOK, false},
+		{"failure-always-logged", 0, http.StatusBadGateway, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := zerolog.New(buf)
+			router := mux.NewRouter()
+			al := server.AccessLog{SampleRate: test.sampleRate}
+			am := server.Auth{}
+			router.Use(al.Middleware, am.Middleware)
+			router.HandleFunc("/things/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+				w.Write([]byte("hello"))
+			})
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/things/12", nil)
+			req = req.WithContext(logger.WithContext(req.Context()))
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if !test.logged {
+				if buf.Len() != 0 {
+					t.Errorf("expected no log got '%v'", buf.String())
+				}
+				return
+			}
+			entry := map[string]interface{}{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decoding log entry: %v", err)
+			}
+			want := map[string]interface{}{
+				"route":     "/things/{id}",
+				"status":    float64(test.status),
+				"bytes":     float64(5),
+				"principal": "anonymous",
+				"method":    http.MethodGet,
+			}
+			for k, v := range want {
+				if entry[k] != v {
+					t.Errorf("expected %s '%v' got '%v'", k, v, entry[k])
+				}
+			}
+			if _, ok := entry["latency"]; !ok {
+				t.Errorf("expected latency in log entry")
+			}
+		})
+	}
+}
+
+func TestLogContextMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"client-supplied", "abc-123", true},
+		{"invalid-replaced", "abc\r\nfake: log", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var seen string
+			handler := server.LogContextMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestid.FromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+			if test.incoming != "" {
+				req.Header.Set(requestid.Header, test.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			got := w.Result().Header.Get(requestid.Header)
+			if got != seen {
+				t.Errorf("expected response header '%v' to match context '%v'", got, seen)
+			}
+			if !requestid.Valid(got) {
+				t.Errorf("expected a valid request id got '%v'", got)
+			}
+			if test.keep && got != test.incoming {
+				t.Errorf("expected '%v' got '%v'", test.incoming, got)
+			}
+			if !test.keep && got == test.incoming {
+				t.Errorf("expected '%v' to be replaced", test.incoming)
+			}
+		})
+	}
+}
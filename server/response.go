@@ -1,10 +1,14 @@
 package server
 
-import "strconv"
+import (
+	"strconv"
+	"time"
+)
 
 type errorResponse struct {
-	Errors []errorItem `json:"errors"`
-	Status int         `json:"status"`
+	Errors    []errorItem `json:"errors"`
+	Status    int         `json:"status"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 type errorItem struct {
@@ -22,10 +26,7 @@ func (pf *preciseFloat32) MarshalJSON() ([]byte, error) {
 type getCurrentByCoordsResponse struct {
 	ID         string            `json:"id"`
 	Type       string            `json:"type"`
-	Attribtues currentAttributes `json:"attributes"`
-	// Links      apiLinks
-	// Relationships apiRelationships
-	// meta apiMeta
+	Attributes currentAttributes `json:"attributes"`
 }
 
 type currentAttributes struct {
@@ -34,3 +35,99 @@ type currentAttributes struct {
 	Temperature string         `json:"temperature"`
 	Condition   string         `json:"condition"`
 }
+
+type currentWeatherAttributes struct {
+	currentAttributes
+	// TemperatureLabel is the temperature class in the negotiated language
+	TemperatureLabel string    `json:"temperature_label"`
+	ObservedAt       time.Time `json:"observed_at"`
+	// ObservedAtLocal, Sunrise and Sunset are in Timezone
+	ObservedAtLocal time.Time  `json:"observed_at_local"`
+	Sunrise         *time.Time `json:"sunrise,omitempty"`
+	Sunset          *time.Time `json:"sunset,omitempty"`
+	// Timezone is the IANA name of the time zone at the location
+	Timezone string `json:"timezone"`
+	// UTCOffset in seconds at ObservedAt
+	UTCOffset int `json:"utc_offset"`
+	// Period is "day" or "night", by whether the sun was up
+	Period string `json:"period"`
+	// Conditions are the conditions in the provider independent taxonomy, and PrimaryCondition the most
+	// severe of them.  Both are left out when none of the provider's conditions are known.
+	Conditions       []conditionAttributes `json:"conditions,omitempty"`
+	PrimaryCondition *conditionAttributes  `json:"primary_condition,omitempty"`
+	// UV is left out when there's no UV source, or the lookup failed
+	UV *uvAttributes `json:"uv,omitempty"`
+	// Derived is left out when the provider doesn't give the conditions it's worked out from
+	Derived *derivedAttributes `json:"derived,omitempty"`
+	// Alerts summarizes the active alerts, when there's an alert service
+	Alerts *alertSummary `json:"alerts,omitempty"`
+}
+
+type uvAttributes struct {
+	Index      float32 `json:"index"`
+	Category   string  `json:"category"`
+	Protection string  `json:"protection"`
+	// Night is set when the index is zero because the sun is down
+	Night bool `json:"night,omitempty"`
+}
+
+type conditionAttributes struct {
+	Code      string `json:"code"`
+	Category  string `json:"category"`
+	Intensity string `json:"intensity"`
+	Severity  int    `json:"severity"`
+	// Description is the name of the condition in the negotiated language
+	Description string `json:"description"`
+	// Icon is the icon key for the period, Icons both of them
+	Icon  string         `json:"icon"`
+	Icons iconAttributes `json:"icons"`
+}
+
+type iconAttributes struct {
+	Day   string `json:"day"`
+	Night string `json:"night"`
+}
+
+// derivedAttributes are the metrics worked out from the conditions.  Temperatures are in Fahrenheit.
+type derivedAttributes struct {
+	DewPoint      *float64           `json:"dew_point,omitempty"`
+	Comfort       string             `json:"comfort"`
+	HeatIndex     *float64           `json:"heat_index,omitempty"`
+	WindChill     *float64           `json:"wind_chill,omitempty"`
+	Beaufort      beaufortAttributes `json:"beaufort"`
+	WindDirection string             `json:"wind_direction,omitempty"`
+	Visibility    string             `json:"visibility,omitempty"`
+	Precipitation string             `json:"precipitation"`
+}
+
+type beaufortAttributes struct {
+	Number      int    `json:"number"`
+	Description string `json:"description"`
+}
+
+type locationAttributes struct {
+	Latitude  preciseFloat32 `json:"latitude"`
+	Longitude preciseFloat32 `json:"longitude"`
+	Name      string         `json:"name,omitempty"`
+	Country   string         `json:"country,omitempty"`
+}
+
+type sourceMeta struct {
+	Provider  string    `json:"provider"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Cache is only set where there's a cache, history from the observation archive
+	Cache string `json:"cache,omitempty"`
+}
+
+const (
+	healthOK       = "ok"
+	healthFailing  = "failing"
+	healthDraining = "draining"
+	// a failed check only reports that it failed, the reason is logged as it can include internal addresses
+	healthFail = "fail"
+)
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
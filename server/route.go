@@ -0,0 +1,193 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+	"github.com/broganross/weather-exercise/i18n"
+)
+
+// routeRequest is a journey along either an encoded polyline, or a GeoJSON LineString
+type routeRequest struct {
+	Polyline  string          `json:"polyline"`
+	Precision int             `json:"precision"`
+	Geometry  json.RawMessage `json:"geometry"`
+	// Defaults to now
+	Departure *time.Time `json:"departure"`
+	SpeedKPH  float64    `json:"speed_kph"`
+	SpacingKM float64    `json:"spacing_km"`
+}
+
+type routePoint struct {
+	Latitude  preciseFloat32 `json:"latitude"`
+	Longitude preciseFloat32 `json:"longitude"`
+}
+
+type routeAttributes struct {
+	LengthKM  float64        `json:"length_km"`
+	Departure time.Time      `json:"departure"`
+	Arrival   time.Time      `json:"arrival"`
+	Worst     *routeWorst    `json:"worst"`
+	Segments  []routeSegment `json:"segments"`
+}
+
+type routeWorst struct {
+	Segment   int    `json:"segment"`
+	Condition string `json:"condition"`
+	Severity  int    `json:"severity"`
+}
+
+type routeSegment struct {
+	Start    routePoint `json:"start"`
+	End      routePoint `json:"end"`
+	Sample   routePoint `json:"sample"`
+	FromKM   float64    `json:"from_km"`
+	LengthKM float64    `json:"length_km"`
+	ETA      time.Time  `json:"eta"`
+	// "current" or "forecast"
+	Source      string `json:"source"`
+	Temperature string `json:"temperature,omitempty"`
+	// TemperatureLabel is the temperature class in the negotiated language
+	TemperatureLabel string `json:"temperature_label,omitempty"`
+	Condition        string `json:"condition,omitempty"`
+	// Degrees is nil when the lookup failed, so 0°F isn't mistaken for no reading
+	Degrees  *float32 `json:"degrees,omitempty"`
+	Severity int      `json:"severity"`
+	// PrimaryCondition is the most severe of the conditions in the provider independent taxonomy
+	PrimaryCondition *conditionAttributes `json:"primary_condition,omitempty"`
+	UV               *uvAttributes        `json:"uv,omitempty"`
+	Error            string               `json:"error,omitempty"`
+}
+
+// GetRouteWeather responds with the weather along a posted route, at the time each part of it is reached
+func (h *Handlers) GetRouteWeather(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	body, err := readBody(w, r)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	query, err := routeQuery(body)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{fmt.Errorf("%w: %w", ErrInvalidBody, err)}, "")
+		return
+	}
+	route, err := h.Route.WeatherAlong(ctx, query)
+	if errors.Is(err, domain.ErrInvalidRoute) {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	} else if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("retrieving route weather: %w", err)}, "")
+		return
+	}
+	// routes don't have a natural ID, so use a digest of the journey
+	b, _ := json.Marshal(struct {
+		Line      geo.LineString
+		Departure time.Time
+		Speed     float64
+	}{query.Line, query.Departure, query.SpeedKPH})
+	sum := sha256.Sum256(b)
+	doc := &document{
+		Data: &resource{
+			ID:         fmt.Sprintf("%s:%s", typeRoute, hex.EncodeToString(sum[:8])),
+			Type:       typeRoute,
+			Attributes: newRouteAttributes(route, i18n.For(i18n.FromContext(ctx))),
+		},
+	}
+	writeDocument(ctx, w, http.StatusOK, doc)
+}
+
+// routeQuery reads the journey from a route request body
+func routeQuery(body []byte) (domain.RouteQuery, error) {
+	req := routeRequest{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return domain.RouteQuery{}, err
+	}
+	q := domain.RouteQuery{
+		Departure: time.Now().UTC(),
+		SpeedKPH:  req.SpeedKPH,
+		Spacing:   req.SpacingKM,
+	}
+	if req.Departure != nil {
+		q.Departure = *req.Departure
+	}
+	var err error
+	switch {
+	case req.Polyline != "" && len(req.Geometry) > 0:
+		return q, errors.New("expected one of polyline or geometry, not both")
+	case req.Polyline != "":
+		q.Line, err = geo.DecodePolyline(req.Polyline, req.Precision)
+	case len(req.Geometry) > 0:
+		var o *geo.Object
+		if o, err = geo.Decode(req.Geometry); err == nil {
+			q.Line, err = o.LineString()
+		}
+	default:
+		err = errors.New("expected a polyline or geometry")
+	}
+	return q, err
+}
+
+func newRouteAttributes(route *domain.RouteWeather, catalog *i18n.Catalog) *routeAttributes {
+	attrs := &routeAttributes{
+		LengthKM:  roundKM(route.LengthKM),
+		Departure: route.Departure.Truncate(time.Second),
+		Arrival:   route.Arrival.Truncate(time.Second),
+		Segments:  make([]routeSegment, len(route.Segments)),
+	}
+	for i, seg := range route.Segments {
+		s := routeSegment{
+			Start:    newRoutePoint(seg.Start),
+			End:      newRoutePoint(seg.End),
+			Sample:   newRoutePoint(seg.Sample),
+			FromKM:   roundKM(seg.FromKM),
+			LengthKM: roundKM(seg.LengthKM),
+			ETA:      seg.ETA.Truncate(time.Second),
+			Source:   "current",
+		}
+		if seg.Forecast {
+			s.Source = "forecast"
+		}
+		if seg.Err != nil {
+			s.Error = seg.Err.Error()
+		} else {
+			s.Temperature = string(seg.Weather.Temperature)
+			s.TemperatureLabel = catalog.Temperature(s.Temperature)
+			s.Condition = strings.Join(seg.Weather.States, ", ")
+			degrees := seg.Weather.Degrees
+			s.Degrees = &degrees
+			primary, _ := domain.PrimaryPhenomenon(seg.Weather.Phenomena)
+			s.Severity = primary.Severity()
+			s.PrimaryCondition = newPrimaryCondition(seg.Weather.Phenomena, seg.Weather.Daytime, catalog)
+			s.UV = newUVAttributes(seg.Weather.UV)
+		}
+		attrs.Segments[i] = s
+	}
+	if route.Worst >= 0 {
+		worst := attrs.Segments[route.Worst]
+		attrs.Worst = &routeWorst{
+			Segment:   route.Worst,
+			Condition: worst.Condition,
+			Severity:  worst.Severity,
+		}
+	}
+	return attrs
+}
+
+func newRoutePoint(p geo.Point) routePoint {
+	return routePoint{Latitude: preciseFloat32(p.Lat), Longitude: preciseFloat32(p.Lon)}
+}
+
+// roundKM rounds a distance to the metre
+func roundKM(km float64) float64 {
+	return math.Round(km*1000) / 1000
+}
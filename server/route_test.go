@@ -0,0 +1,108 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+)
+
+// stormForecast forecasts thunderstorms from now on
+type stormForecast struct{}
+
+func (sf *stormForecast) ForecastIn(ctx context.Context, lat float32, lon float32) ([]domain.Weather, error) {
+	now := time.Now()
+	storm, _ := domain.LookupPhenomenon("thunderstorm")
+	return []domain.Weather{
+		{States: []string{"Thunderstorm"}, Phenomena: []domain.Phenomenon{storm}, Temperature: domain.TempMod, ObservedAt: now},
+		{States: []string{"Thunderstorm"}, Phenomena: []domain.Phenomenon{storm}, Temperature: domain.TempMod, ObservedAt: now.Add(3 * time.Hour)},
+	}, nil
+}
+
+func TestHandlers_GetRouteWeather(t *testing.T) {
+	h := server.Handlers{
+		Route: &domain.RouteService{
+			Current:       &uniformDomain{},
+			Forecast:      &stormForecast{},
+			Spacing:       100,
+			MaxSamples:    10,
+			CurrentWindow: time.Hour,
+		},
+	}
+	tests := []struct {
+		name     string
+		body     string
+		code     int
+		segments int
+		worst    int
+	}{
+		{"polyline", `{"polyline":"_p~iF~ps|U_ulLnnqC","speed_kph":80}`, http.StatusOK, 3, 1},
+		{
+			"geometry",
+			`{"geometry":{"type":"LineString","coordinates":[[0,0],[0,1]]},"speed_kph":50,"spacing_km":40}`,
+			http.StatusOK,
+			3,
+			1,
+		},
+		{"missing-line", `{"speed_kph":50}`, http.StatusBadRequest, 0, 0},
+		{"missing-speed", `{"polyline":"_p~iF~ps|U_ulLnnqC"}`, http.StatusBadRequest, 0, 0},
+		{"invalid-polyline", `{"polyline":"_p~iF","speed_kph":50}`, http.StatusBadRequest, 0, 0},
+		{"point", `{"geometry":{"type":"Point","coordinates":[0,0]},"speed_kph":50}`, http.StatusBadRequest, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/v1/weather/route", strings.NewReader(test.body))
+			w := httptest.NewRecorder()
+			h.GetRouteWeather(w, req)
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.code != http.StatusOK {
+				return
+			}
+			doc := struct {
+				Data struct {
+					Type       string `json:"type"`
+					Attributes struct {
+						Worst *struct {
+							Segment   int    `json:"segment"`
+							Condition string `json:"condition"`
+							Severity  int    `json:"severity"`
+						} `json:"worst"`
+						Segments []struct {
+							Source    string   `json:"source"`
+							Condition string   `json:"condition"`
+							Degrees   *float32 `json:"degrees"`
+						} `json:"segments"`
+					} `json:"attributes"`
+				} `json:"data"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			attrs := doc.Data.Attributes
+			if doc.Data.Type != "urn:weather:route" {
+				t.Errorf("expected 'urn:weather:route' got '%v'", doc.Data.Type)
+			}
+			if len(attrs.Segments) != test.segments {
+				t.Fatalf("expected '%v' segments got '%v'", test.segments, len(attrs.Segments))
+			}
+			if attrs.Segments[0].Source != "current" || attrs.Segments[0].Condition != "Snow" {
+				t.Errorf("expected current snow at the start got '%v'", attrs.Segments[0])
+			}
+			// the forecast is exactly 0°F
+			if last := attrs.Segments[len(attrs.Segments)-1]; last.Degrees == nil || *last.Degrees != 0 {
+				t.Errorf("expected '0' degrees got '%v'", last.Degrees)
+			}
+			if attrs.Worst == nil || attrs.Worst.Segment != test.worst || attrs.Worst.Condition != "Thunderstorm" || attrs.Worst.Severity != 8 {
+				t.Errorf("expected thunderstorms at segment '%v' got '%v'", test.worst, attrs.Worst)
+			}
+		})
+	}
+}
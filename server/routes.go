@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/broganross/weather-exercise/config"
+	"github.com/broganross/weather-exercise/metrics"
+	"github.com/gorilla/mux"
+)
+
+// rootDeprecatedAt is when the unversioned root route was superseded by /v1
+var rootDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// apiVersion registers the routes of one version of the API under its path prefix.
+// Each version owns its handlers, so a version with a different response shape can be served alongside the older ones.
+// Streaming routes are registered separately, as they negotiate their own protocol.
+type apiVersion struct {
+	prefix   string
+	register func(h *Handlers, r *mux.Router)
+	streams  func(h *Handlers, r *mux.Router)
+}
+
+var apiVersions = []apiVersion{
+	{prefix: "/v1", register: registerV1, streams: registerV1Streams},
+}
+
+// SetupRoutes constructs the router, adding middleware, routes, handlers, etc
+func SetupRoutes(h *Handlers, r *mux.Router, c *config.Config) {
+	r.Use(LogContextMiddleware)
+	r.Use(TracingMiddleware)
+	al := AccessLog{
+		SampleRate: c.AccessLog.SampleRate,
+	}
+	r.Use(al.Middleware)
+	r.Use(MetricsMiddleware)
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+	if h.Health != nil {
+		r.HandleFunc("/healthz", h.Health.Live).Methods(http.MethodGet)
+		r.HandleFunc("/readyz", h.Health.Ready).Methods(http.MethodGet)
+	}
+
+	// everything else requires authentication
+	am := Auth{
+		BaseURL: c.AuthService.URL,
+	}
+	streams := r.NewRoute().Subrouter()
+	streams.Use(LanguageMiddleware)
+	streams.Use(am.Middleware)
+	for _, v := range apiVersions {
+		if v.streams != nil {
+			v.streams(h, streams.PathPrefix(v.prefix).Subrouter())
+		}
+	}
+	api := r.NewRoute().Subrouter()
+	api.Use(LanguageMiddleware)
+	api.Use(NegotiateMiddleware)
+	api.Use(am.Middleware)
+	for _, v := range apiVersions {
+		v.register(h, api.PathPrefix(v.prefix).Subrouter())
+	}
+
+	// the original unversioned route, kept until its sunset
+	root := api.Path("/").Subrouter()
+	root.Use(deprecated(rootDeprecatedAt, c.RootSunset, "/v1/weather/current"))
+	root.Methods(http.MethodGet).HandlerFunc(h.GetCurrentByCoordsLegacy)
+}
+
+func registerV1(h *Handlers, r *mux.Router) {
+	r.HandleFunc("/weather/current", h.GetCurrentByCoords).Methods(http.MethodGet)
+	r.HandleFunc("/weather/current", h.GetCurrentByGeoJSON).Methods(http.MethodPost)
+	r.HandleFunc("/locations/{latitude:[^/,]+},{longitude:[^/,]+}/weather", h.GetCurrentByCoords).Methods(http.MethodGet)
+	r.HandleFunc("/astronomy", h.GetAstronomy).Methods(http.MethodGet)
+	if h.Batch != nil {
+		r.HandleFunc("/weather/current:batch", h.GetCurrentBatch).Methods(http.MethodPost)
+	}
+	if h.History != nil {
+		r.HandleFunc("/weather/history", h.GetHistory).Methods(http.MethodGet)
+	}
+	if h.Area != nil {
+		r.HandleFunc("/weather/area", h.GetAreaSummary).Methods(http.MethodGet)
+		r.HandleFunc("/weather/area", h.GetAreaSummaryByGeoJSON).Methods(http.MethodPost)
+	}
+	if h.Alerts != nil {
+		r.HandleFunc("/alerts", h.GetAlerts).Methods(http.MethodGet)
+	}
+	if h.AirQuality != nil {
+		r.HandleFunc("/air-quality", h.GetAirQuality).Methods(http.MethodGet)
+		r.HandleFunc("/air-quality/forecast", h.GetAirQualityForecast).Methods(http.MethodGet)
+	}
+	if h.Route != nil {
+		r.HandleFunc("/weather/route", h.GetRouteWeather).Methods(http.MethodPost)
+	}
+	if h.Webhooks != nil {
+		r.HandleFunc("/subscriptions", h.CreateSubscription).Methods(http.MethodPost)
+		r.HandleFunc("/subscriptions", h.ListSubscriptions).Methods(http.MethodGet)
+		r.HandleFunc("/subscriptions/{id}", h.GetSubscription).Methods(http.MethodGet)
+		r.HandleFunc("/subscriptions/{id}", h.DeleteSubscription).Methods(http.MethodDelete)
+		r.HandleFunc("/subscriptions/{id}/deliveries", h.GetSubscriptionDeliveries).Methods(http.MethodGet)
+	}
+}
+
+func registerV1Streams(h *Handlers, r *mux.Router) {
+	if h.Streams != nil {
+		r.HandleFunc("/weather/current/stream", h.GetCurrentStream).Methods(http.MethodGet)
+		r.HandleFunc("/weather/current/ws", h.GetCurrentWebSocket).Methods(http.MethodGet)
+	}
+}
+
+// deprecated marks responses as coming from a deprecated route (RFC 9745 and RFC 8594),
+// pointing clients at its replacement
+func deprecated(at time.Time, sunset time.Time, successor string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", at.Unix()))
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/config"
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+	"github.com/gorilla/mux"
+)
+
+func TestSetupRoutes(t *testing.T) {
+	h := server.Handlers{
+		Domain: &mockWeatherDomain{
+			responses: map[string]mockWeatherDomainResponse{
+				"1.20:-2.30": {
+					weather: domain.Weather{
+						Coords:      domain.Coords{Latitude: 1.2, Longitude: -2.3},
+						States:      []string{"rain"},
+						Temperature: domain.TempCold,
+					},
+				},
+			},
+		},
+	}
+	router := mux.NewRouter()
+	sunset := time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)
+	server.SetupRoutes(&h, router, &config.Config{RootSunset: sunset})
+	tests := []struct {
+		name       string
+		path       string
+		code       int
+		deprecated bool
+	}{
+		{"v1-query", "/v1/weather/current?latitude=1.2&longitude=-2.3", http.StatusOK, false},
+		{"v1-location", "/v1/locations/1.2,-2.3/weather", http.StatusOK, false},
+		{"v1-location-invalid", "/v1/locations/north,-2.3/weather", http.StatusBadRequest, false},
+		{"root-alias", "/?latitude=1.2&longitude=-2.3", http.StatusOK, true},
+		{"unknown", "/v1/weather/yesterday", http.StatusNotFound, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost"+test.path, nil))
+			resp := w.Result()
+			if resp.StatusCode != test.code {
+				t.Errorf("expected code '%v' got '%v'", test.code, resp.StatusCode)
+			}
+			dep := resp.Header.Get("Deprecation")
+			if test.deprecated {
+				if dep == "" {
+					t.Errorf("expected Deprecation header")
+				}
+				if got := resp.Header.Get("Sunset"); got != "Mon, 19 Apr 2027 00:00:00 GMT" {
+					t.Errorf("expected Sunset header got '%v'", got)
+				}
+			} else if dep != "" {
+				t.Errorf("expected no Deprecation header got '%v'", dep)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+	"github.com/broganross/weather-exercise/requestid"
+	"github.com/rs/zerolog/log"
+)
+
+// Streams pushes weather updates to long lived connections, from a shared poller
+type Streams struct {
+	Poller *domain.Poller
+	// How often a comment, or WebSocket ping, is sent to keep proxies from closing idle streams
+	Heartbeat time.Duration
+	// Maximum locations one WebSocket can subscribe to
+	MaxSubscriptions int
+	// Messages queued for each WebSocket, clients that fall further behind are disconnected
+	SendBuffer int
+	// How long a WebSocket write can take
+	WriteTimeout time.Duration
+}
+
+// GetCurrentStream streams Server-Sent Events whenever the current weather at the coordinates changes.
+// Each event's data is the same document as GetCurrentByCoords, and its ID can be sent back
+// in Last-Event-ID when reconnecting to only receive what was missed.
+func (h *Handlers) GetCurrentStream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lat, lon, errs := coordsFromRequest(r)
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "required query parameters")
+		return
+	}
+	// an unreadable ID is treated as a new subscription
+	lastID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	sub, err := h.Streams.Poller.Subscribe(domain.Coords{Latitude: float32(lat), Longitude: float32(lon)}, lastID)
+	if err != nil {
+		encodeError(ctx, w, http.StatusServiceUnavailable, []error{err}, "")
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// streams outlive the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Ctx(ctx).Debug().Err(err).Msg("clearing stream write deadline")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("streaming unsupported")
+		return
+	}
+
+	heartbeat := time.NewTicker(h.Streams.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case u, ok := <-sub.Updates():
+			if !ok {
+				// the poller has shut down
+				return
+			}
+			err = writeEvent(w, lat, lon, u, requestid.FromContext(ctx), i18n.For(i18n.FromContext(ctx)))
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			log.Ctx(ctx).Debug().Err(err).Msg("writing stream")
+			return
+		}
+	}
+}
+
+// writeEvent writes an update as a weather event, or an error event if the lookup failed
+func writeEvent(w http.ResponseWriter, lat float64, lon float64, u domain.Update, requestID string, catalog *i18n.Catalog) error {
+	event := "weather"
+	var data any
+	if u.Err != nil {
+		event = "error"
+		data = &errorResponse{
+			Errors:    []errorItem{{Error: fmt.Sprintf("retrieving current weather: %s", u.Err)}},
+			Status:    http.StatusInternalServerError,
+			RequestID: requestID,
+		}
+	} else {
+		data = currentWeatherDocument(lat, lon, u.Weather, nil, catalog)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", u.ID, event, b)
+	return err
+}
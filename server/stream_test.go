@@ -0,0 +1,139 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/config"
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+	"github.com/gorilla/mux"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvents reads events, and comments, from a stream until n have been read
+func readEvents(t *testing.T, s *bufio.Scanner, n int) ([]sseEvent, int) {
+	t.Helper()
+	events := []sseEvent{}
+	comments := 0
+	current := sseEvent{}
+	for len(events) < n && s.Scan() {
+		line := s.Text()
+		switch {
+		case line == "":
+			if current.event != "" {
+				events = append(events, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+			comments++
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if len(events) < n {
+		t.Fatalf("expected '%v' events got '%v': %v", n, len(events), s.Err())
+	}
+	return events, comments
+}
+
+func TestHandlers_GetCurrentStream(t *testing.T) {
+	poller := &domain.Poller{
+		Service:  &uniformDomain{},
+		Interval: time.Hour,
+		GridSize: 1,
+		Buffer:   4,
+		History:  4,
+	}
+	defer poller.Close()
+	h := server.Handlers{
+		Streams: &server.Streams{Poller: poller, Heartbeat: 10 * time.Millisecond},
+	}
+	router := mux.NewRouter()
+	server.SetupRoutes(&h, router, &config.Config{})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	stream := func(query string, lastID string) (*http.Response, context.CancelFunc) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/weather/current/stream"+query, nil)
+		req.Header.Set("Accept", "text/event-stream")
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+		return resp, cancel
+	}
+
+	resp, cancel := stream("?latitude=1.2&longitude=-2.3", "")
+	defer cancel()
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected code '%v' got '%v'", http.StatusOK, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("expected 'text/event-stream' got '%v'", got)
+	}
+	events, _ := readEvents(t, bufio.NewScanner(resp.Body), 1)
+	if events[0].event != "weather" || events[0].id == "" {
+		t.Errorf("expected a weather event with an ID got '%v'", events[0])
+	}
+	doc := struct {
+		Data struct {
+			Attributes struct {
+				Latitude  float32 `json:"latitude"`
+				Condition string  `json:"condition"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal([]byte(events[0].data), &doc); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if doc.Data.Attributes.Latitude != 1.2 || doc.Data.Attributes.Condition != "Snow" {
+		t.Errorf("expected snow at the requested latitude got '%v'", doc.Data.Attributes)
+	}
+
+	// resuming from the latest event only sends heartbeats until something changes
+	resumed, cancelResumed := stream("?latitude=1.4&longitude=-2.1", events[0].id)
+	defer cancelResumed()
+	defer resumed.Body.Close()
+	s := bufio.NewScanner(resumed.Body)
+	comments := 0
+	for comments < 2 && s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "id: ") {
+			t.Fatalf("expected no events got '%v'", line)
+		}
+		if strings.HasPrefix(line, ":") {
+			comments++
+		}
+	}
+	if comments < 2 {
+		t.Errorf("expected heartbeats got '%v'", comments)
+	}
+
+	bad, cancelBad := stream("?latitude=north", "")
+	defer cancelBad()
+	defer bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected code '%v' got '%v'", http.StatusBadRequest, bad.StatusCode)
+	}
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+	"github.com/broganross/weather-exercise/webhook"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var ErrInvalidCallback = errors.New("invalid callback url")
+
+type subscriptionRequest struct {
+	Latitude    *float64         `json:"latitude"`
+	Longitude   *float64         `json:"longitude"`
+	CallbackURL string           `json:"callback_url"`
+	Predicate   predicateRequest `json:"predicate"`
+}
+
+type predicateRequest struct {
+	Temperature       string `json:"temperature,omitempty"`
+	ConditionContains string `json:"condition_contains,omitempty"`
+}
+
+type subscriptionAttributes struct {
+	Latitude    preciseFloat32   `json:"latitude"`
+	Longitude   preciseFloat32   `json:"longitude"`
+	CallbackURL string           `json:"callback_url"`
+	Predicate   predicateRequest `json:"predicate"`
+	// Only returned when the subscription is created
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Matching  bool      `json:"matching"`
+}
+
+type deliveryAttributes struct {
+	EventID    string    `json:"event_id"`
+	Attempt    int       `json:"attempt"`
+	Outcome    string    `json:"outcome"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// CreateSubscription registers a webhook, to be called when the weather at the coordinates starts matching the predicate.
+// The secret payloads are signed with is only returned in this response.
+func (h *Handlers) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	body, err := readBody(w, r)
+	if err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{err}, "")
+		return
+	}
+	req := subscriptionRequest{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		encodeError(ctx, w, http.StatusBadRequest, []error{fmt.Errorf("%w: %w", ErrInvalidBody, err)}, "")
+		return
+	}
+	wh, errs := req.webhook()
+	if len(errs) > 0 {
+		encodeError(ctx, w, http.StatusBadRequest, errs, "")
+		return
+	}
+	wh.ID = uuid.NewString()
+	wh.Owner = PrincipalFrom(ctx)
+	wh.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("generating secret: %w", err)}, "")
+		return
+	}
+	wh.Secret = hex.EncodeToString(secret)
+	if err := h.Webhooks.Create(ctx, wh); err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("creating subscription: %w", err)}, "")
+		return
+	}
+	res := subscriptionResource(wh)
+	res.Attributes.(*subscriptionAttributes).Secret = wh.Secret
+	w.Header().Set("Location", res.Links.Self)
+	writeDocument(ctx, w, http.StatusCreated, &document{Data: &res, Links: res.Links})
+}
+
+// ListSubscriptions responds with the caller's subscriptions
+func (h *Handlers) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	all, err := h.Webhooks.List(ctx)
+	if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("listing subscriptions: %w", err)}, "")
+		return
+	}
+	owner := PrincipalFrom(ctx)
+	data := []resource{}
+	for i := range all {
+		if all[i].Owner == owner {
+			data = append(data, subscriptionResource(&all[i]))
+		}
+	}
+	writeDocument(ctx, w, http.StatusOK, &document{Data: data, Links: &links{Self: "/v1/subscriptions"}})
+}
+
+// GetSubscription responds with one of the caller's subscriptions
+func (h *Handlers) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	wh, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	res := subscriptionResource(wh)
+	writeDocument(r.Context(), w, http.StatusOK, &document{Data: &res, Links: res.Links})
+}
+
+// DeleteSubscription removes one of the caller's subscriptions, and its delivery history
+func (h *Handlers) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	wh, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	err := h.Webhooks.Delete(ctx, wh.ID)
+	if err != nil && !errors.Is(err, domain.ErrWebhookNotFound) {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("deleting subscription: %w", err)}, "")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSubscriptionDeliveries responds with the delivery history of one of the caller's subscriptions, oldest first.
+// Events that ran out of retries have a final attempt with the dead_letter outcome.
+func (h *Handlers) GetSubscriptionDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	wh, ok := h.ownSubscription(w, r)
+	if !ok {
+		return
+	}
+	history, err := h.Webhooks.Deliveries(ctx, wh.ID)
+	if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("listing deliveries: %w", err)}, "")
+		return
+	}
+	data := make([]resource, len(history))
+	for i, d := range history {
+		data[i] = resource{
+			ID:   fmt.Sprintf("%s:%s", typeDelivery, d.ID),
+			Type: typeDelivery,
+			Attributes: &deliveryAttributes{
+				EventID:    d.EventID,
+				Attempt:    d.Attempt,
+				Outcome:    d.Outcome,
+				StatusCode: d.StatusCode,
+				Error:      d.Error,
+				At:         d.At.Truncate(time.Second),
+			},
+		}
+	}
+	self := &links{Self: fmt.Sprintf("/v1/subscriptions/%s/deliveries", wh.ID)}
+	writeDocument(ctx, w, http.StatusOK, &document{Data: data, Links: self})
+}
+
+// ownSubscription gets the subscription in the route, responding 404 if it doesn't exist or belongs to someone else.
+// If it fails, the error has already been written to the response.
+func (h *Handlers) ownSubscription(w http.ResponseWriter, r *http.Request) (*domain.Webhook, bool) {
+	ctx := r.Context()
+	id := mux.Vars(r)["id"]
+	wh, err := h.Webhooks.Get(ctx, id)
+	if errors.Is(err, domain.ErrWebhookNotFound) || (err == nil && wh.Owner != PrincipalFrom(ctx)) {
+		encodeError(ctx, w, http.StatusNotFound, []error{fmt.Errorf("%w: %s", domain.ErrWebhookNotFound, id)}, "")
+		return nil, false
+	} else if err != nil {
+		encodeError(ctx, w, http.StatusInternalServerError, []error{fmt.Errorf("getting subscription: %w", err)}, "")
+		return nil, false
+	}
+	return wh, true
+}
+
+// webhook validates the request, and converts it to a webhook without an identity
+func (req *subscriptionRequest) webhook() (*domain.Webhook, []error) {
+	errs := []error{}
+	if req.Latitude == nil || req.Longitude == nil {
+		errs = append(errs, fmt.Errorf("%w: latitude and longitude", ErrMissingParam))
+	} else if !(geo.Point{Lon: *req.Longitude, Lat: *req.Latitude}).Valid() {
+		errs = append(errs, fmt.Errorf("%w: coordinates out of range", ErrInvalidBody))
+	}
+	u, err := url.Parse(req.CallbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("%w: expected an absolute http or https url", ErrInvalidCallback))
+	} else if err := webhook.CheckCallback(u); err != nil {
+		errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidCallback, err))
+	}
+	p := domain.Predicate{
+		Temperature:       domain.Temperature(req.Predicate.Temperature),
+		ConditionContains: req.Predicate.ConditionContains,
+	}
+	if err := p.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &domain.Webhook{
+		Coords:      domain.Coords{Latitude: float32(*req.Latitude), Longitude: float32(*req.Longitude)},
+		Predicate:   p,
+		CallbackURL: req.CallbackURL,
+	}, nil
+}
+
+func subscriptionResource(wh *domain.Webhook) resource {
+	return resource{
+		ID:   fmt.Sprintf("%s:%s", typeSubscription, wh.ID),
+		Type: typeSubscription,
+		Attributes: &subscriptionAttributes{
+			Latitude:    preciseFloat32(wh.Coords.Latitude),
+			Longitude:   preciseFloat32(wh.Coords.Longitude),
+			CallbackURL: wh.CallbackURL,
+			Predicate: predicateRequest{
+				Temperature:       string(wh.Predicate.Temperature),
+				ConditionContains: wh.Predicate.ConditionContains,
+			},
+			CreatedAt: wh.CreatedAt,
+			Matching:  wh.Matching,
+		},
+		Links: &links{Self: "/v1/subscriptions/" + wh.ID},
+	}
+}
@@ -0,0 +1,162 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+	"github.com/broganross/weather-exercise/store"
+	"github.com/gorilla/mux"
+)
+
+func TestHandlers_CreateSubscription(t *testing.T) {
+	h := server.Handlers{Webhooks: store.NewMemory()}
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{
+			"valid",
+			`{"latitude":45.5,"longitude":-122.6,"callback_url":"https://example.com/hook","predicate":{"condition_contains":"snow"}}`,
+			http.StatusCreated,
+		},
+		{"missing-coords", `{"callback_url":"https://example.com/hook","predicate":{"temperature":"cold"}}`, http.StatusBadRequest},
+		{
+			"out-of-range",
+			`{"latitude":91,"longitude":0,"callback_url":"https://example.com/hook","predicate":{"temperature":"cold"}}`,
+			http.StatusBadRequest,
+		},
+		{
+			"relative-callback",
+			`{"latitude":1,"longitude":1,"callback_url":"/hook","predicate":{"temperature":"cold"}}`,
+			http.StatusBadRequest,
+		},
+		{
+			"other-scheme",
+			`{"latitude":1,"longitude":1,"callback_url":"ftp://example.com/hook","predicate":{"temperature":"cold"}}`,
+			http.StatusBadRequest,
+		},
+		{
+			"loopback-callback",
+			`{"latitude":1,"longitude":1,"callback_url":"http://127.0.0.1:8080/hook","predicate":{"temperature":"cold"}}`,
+			http.StatusBadRequest,
+		},
+		{
+			"localhost-callback",
+			`{"latitude":1,"longitude":1,"callback_url":"http://localhost/hook","predicate":{"temperature":"cold"}}`,
+			http.StatusBadRequest,
+		},
+		{
+			"private-callback",
+			`{"latitude":1,"longitude":1,"callback_url":"https://192.168.1.20/hook","predicate":{"temperature":"cold"}}`,
+			http.StatusBadRequest,
+		},
+		{
+			"metadata-callback",
+			`{"latitude":1,"longitude":1,"callback_url":"http://169.254.169.254/latest/meta-data/","predicate":{"temperature":"cold"}}`,
+			http.StatusBadRequest,
+		},
+		{"empty-predicate", `{"latitude":1,"longitude":1,"callback_url":"https://example.com/hook"}`, http.StatusBadRequest},
+		{"invalid-json", `{"latitude":`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/v1/subscriptions", strings.NewReader(test.body))
+			w := httptest.NewRecorder()
+			h.CreateSubscription(w, req)
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if test.code != http.StatusCreated {
+				return
+			}
+			doc := struct {
+				Data struct {
+					ID         string `json:"id"`
+					Type       string `json:"type"`
+					Attributes struct {
+						Secret string `json:"secret"`
+					} `json:"attributes"`
+				} `json:"data"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+			if doc.Data.Type != "urn:weather:subscription" {
+				t.Errorf("expected '%v' got '%v'", "urn:weather:subscription", doc.Data.Type)
+			}
+			if len(doc.Data.Attributes.Secret) != 64 {
+				t.Errorf("expected a 32 byte hex secret got '%v'", doc.Data.Attributes.Secret)
+			}
+			if loc := w.Header().Get("Location"); !strings.HasPrefix(loc, "/v1/subscriptions/") {
+				t.Errorf("expected a subscription location got '%v'", loc)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetSubscription(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	hooks := []domain.Webhook{
+		{ID: "mine", Predicate: domain.Predicate{Temperature: domain.TempCold}, Secret: "shh", CreatedAt: time.Now()},
+		{ID: "theirs", Owner: "someone-else", Predicate: domain.Predicate{Temperature: domain.TempCold}, CreatedAt: time.Now()},
+	}
+	for _, hook := range hooks {
+		if err := s.Create(ctx, &hook); err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+	}
+	s.AddDelivery(ctx, &domain.WebhookDelivery{ID: "d1", WebhookID: "mine", EventID: "e1", Attempt: 1, Outcome: domain.DeliverySucceeded})
+	h := server.Handlers{Webhooks: s}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		id      string
+		code    int
+	}{
+		{"get", h.GetSubscription, "mine", http.StatusOK},
+		{"get-other-owner", h.GetSubscription, "theirs", http.StatusNotFound},
+		{"get-missing", h.GetSubscription, "missing", http.StatusNotFound},
+		{"deliveries", h.GetSubscriptionDeliveries, "mine", http.StatusOK},
+		{"deliveries-other-owner", h.GetSubscriptionDeliveries, "theirs", http.StatusNotFound},
+		{"delete-other-owner", h.DeleteSubscription, "theirs", http.StatusNotFound},
+		{"delete", h.DeleteSubscription, "mine", http.StatusNoContent},
+		{"get-deleted", h.GetSubscription, "mine", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost/v1/subscriptions/"+test.id, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": test.id})
+			w := httptest.NewRecorder()
+			test.handler(w, req)
+			if w.Code != test.code {
+				t.Fatalf("expected code '%v' got '%v': %s", test.code, w.Code, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "shh") {
+				t.Errorf("expected the secret to only be returned on create got '%s'", w.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/v1/subscriptions", nil)
+	w := httptest.NewRecorder()
+	h.ListSubscriptions(w, req)
+	doc := struct {
+		Data []json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(doc.Data) != 0 {
+		t.Errorf("expected only the caller's subscriptions got '%s'", w.Body.String())
+	}
+}
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/i18n"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// maxMessageBytes limits the size of messages read from WebSocket clients
+const maxMessageBytes = 4096
+
+var (
+	ErrSubscriptionLimit = errors.New("subscription limit reached")
+	ErrNotSubscribed     = errors.New("not subscribed")
+	ErrSubscribed        = errors.New("already subscribed")
+	ErrUnknownMessage    = errors.New("unknown message type")
+)
+
+// WebSocket message types
+const (
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsPing         = "ping"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsUpdate       = "update"
+	wsError        = "error"
+	wsPong         = "pong"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// wsMessage is a message from the client.
+// The ID is optional, and echoed back in replies so clients can match them up.
+type wsMessage struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id,omitempty"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// wsReply is a message to the client
+type wsReply struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"`
+	Latitude  *preciseFloat32 `json:"latitude,omitempty"`
+	Longitude *preciseFloat32 `json:"longitude,omitempty"`
+	EventID   uint64          `json:"event_id,omitempty"`
+	Data      any             `json:"data,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// wsConn is one client connection, with its subscriptions.
+// The handler's goroutine reads, and a single writer goroutine owns writes to the connection.
+type wsConn struct {
+	conn    *websocket.Conn
+	streams *Streams
+	// catalog is the language negotiated when the connection was opened
+	catalog *i18n.Catalog
+	// replies waiting to be written, a client that lets it fill up is disconnected
+	send chan wsReply
+	done chan struct{}
+	once sync.Once
+	// why the connection is being closed, set before done is closed
+	closeCode   int
+	closeReason string
+
+	mu   sync.Mutex
+	subs map[string]*domain.Subscription
+}
+
+// GetCurrentWebSocket upgrades to a WebSocket that clients subscribe to the current weather at several locations on.
+// Every subscription is backed by the shared poller, so updates are multiplexed with other subscribers to the same locations.
+func (h *Handlers) GetCurrentWebSocket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already responded
+		log.Ctx(ctx).Debug().Err(err).Msg("upgrading to websocket")
+		return
+	}
+	c := &wsConn{
+		conn:    conn,
+		streams: h.Streams,
+		catalog: i18n.For(i18n.FromContext(ctx)),
+		send:    make(chan wsReply, max(h.Streams.SendBuffer, 1)),
+		done:    make(chan struct{}),
+		subs:    map[string]*domain.Subscription{},
+	}
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.write()
+	}()
+	err = c.read()
+	c.stop(websocket.CloseNormalClosure, "")
+	<-written
+	c.unsubscribeAll()
+	if err != nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+		log.Ctx(ctx).Debug().Err(err).Msg("reading websocket")
+	}
+	if c.closeCode == websocket.ClosePolicyViolation {
+		log.Ctx(ctx).Warn().Str("reason", c.closeReason).Msg("closed websocket")
+	}
+}
+
+// read handles client messages until the connection fails or is closed
+func (c *wsConn) read() error {
+	pongWait := 2 * c.streams.Heartbeat
+	c.conn.SetReadLimit(maxMessageBytes)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, b, err := c.conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		msg := wsMessage{}
+		if err := json.Unmarshal(b, &msg); err != nil {
+			c.enqueue(wsReply{Type: wsError, Error: localizeError(c.catalog, fmt.Errorf("%w: %w", ErrInvalidBody, err))})
+			continue
+		}
+		if err := c.handle(msg); err != nil {
+			reply := wsReply{Type: wsError, ID: msg.ID, Error: localizeError(c.catalog, err)}
+			if msg.Latitude != nil && msg.Longitude != nil {
+				reply.Latitude, reply.Longitude = replyCoords(*msg.Latitude, *msg.Longitude)
+			}
+			c.enqueue(reply)
+		}
+	}
+}
+
+func (c *wsConn) handle(msg wsMessage) error {
+	switch msg.Type {
+	case wsPing:
+		c.enqueue(wsReply{Type: wsPong, ID: msg.ID})
+		return nil
+	case wsSubscribe, wsUnsubscribe:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownMessage, msg.Type)
+	}
+	if msg.Latitude == nil || msg.Longitude == nil {
+		return fmt.Errorf("%w: latitude and longitude", ErrMissingParam)
+	}
+	lat, lon := *msg.Latitude, *msg.Longitude
+	key := formatCoord(lat) + "," + formatCoord(lon)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub, ok := c.subs[key]
+	if msg.Type == wsUnsubscribe {
+		if !ok {
+			return ErrNotSubscribed
+		}
+		delete(c.subs, key)
+		sub.Close()
+		replyLat, replyLon := replyCoords(lat, lon)
+		c.enqueue(wsReply{Type: wsUnsubscribed, ID: msg.ID, Latitude: replyLat, Longitude: replyLon})
+		return nil
+	}
+	switch {
+	case ok:
+		return ErrSubscribed
+	case len(c.subs) >= c.streams.MaxSubscriptions:
+		return fmt.Errorf("%w: %d", ErrSubscriptionLimit, c.streams.MaxSubscriptions)
+	}
+	sub, err := c.streams.Poller.Subscribe(domain.Coords{Latitude: float32(lat), Longitude: float32(lon)}, 0)
+	if err != nil {
+		return err
+	}
+	c.subs[key] = sub
+	replyLat, replyLon := replyCoords(lat, lon)
+	// acknowledge before the subscription's first update can be sent
+	c.enqueue(wsReply{Type: wsSubscribed, ID: msg.ID, Latitude: replyLat, Longitude: replyLon})
+	go c.forward(key, lat, lon, sub)
+	return nil
+}
+
+// forward sends a subscription's updates to the client, until it's unsubscribed
+func (c *wsConn) forward(key string, lat float64, lon float64, sub *domain.Subscription) {
+	replyLat, replyLon := replyCoords(lat, lon)
+	for u := range sub.Updates() {
+		reply := wsReply{
+			Type:      wsUpdate,
+			Latitude:  replyLat,
+			Longitude: replyLon,
+			EventID:   u.ID,
+		}
+		if u.Err != nil {
+			reply.Error = fmt.Sprintf("retrieving current weather: %s", u.Err)
+		} else {
+			reply.Data = currentWeatherDocument(lat, lon, u.Weather, nil, c.catalog)
+		}
+		if !c.enqueue(reply) {
+			return
+		}
+	}
+	c.mu.Lock()
+	current := c.subs[key] == sub
+	c.mu.Unlock()
+	if current {
+		// closed by the poller, rather than unsubscribed
+		c.stop(websocket.CloseGoingAway, "shutting down")
+	}
+}
+
+// enqueue queues a reply to be written, disconnecting clients that aren't keeping up
+func (c *wsConn) enqueue(reply wsReply) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.send <- reply:
+		return true
+	default:
+		c.stop(websocket.ClosePolicyViolation, "reading too slowly")
+		return false
+	}
+}
+
+// write sends queued replies, and pings, until the connection is stopped
+func (c *wsConn) write() {
+	defer c.conn.Close()
+	ping := time.NewTicker(c.streams.Heartbeat)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-c.done:
+			// there's no point saying goodbye on a broken connection
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				msg := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+				c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.streams.WriteTimeout))
+			}
+			return
+		case <-ping.C:
+			err = c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.streams.WriteTimeout))
+		case reply := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.streams.WriteTimeout))
+			err = c.conn.WriteJSON(reply)
+		}
+		if err != nil {
+			c.stop(websocket.CloseAbnormalClosure, "")
+		}
+	}
+}
+
+// stop closes the connection, the first reason given is the one sent to the client
+func (c *wsConn) stop(code int, reason string) {
+	c.once.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+func (c *wsConn) unsubscribeAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, sub := range c.subs {
+		delete(c.subs, key)
+		sub.Close()
+	}
+}
+
+func replyCoords(lat float64, lon float64) (*preciseFloat32, *preciseFloat32) {
+	rLat, rLon := preciseFloat32(lat), preciseFloat32(lon)
+	return &rLat, &rLon
+}
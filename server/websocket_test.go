@@ -0,0 +1,186 @@
+package server_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/config"
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/server"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+type wsReply struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id"`
+	Latitude  *float32 `json:"latitude"`
+	Longitude *float32 `json:"longitude"`
+	EventID   uint64   `json:"event_id"`
+	Data      *struct {
+		Data struct {
+			Attributes struct {
+				Condition string `json:"condition"`
+			} `json:"attributes"`
+		} `json:"data"`
+	} `json:"data"`
+	Error string `json:"error"`
+}
+
+func TestHandlers_GetCurrentWebSocket(t *testing.T) {
+	poller := &domain.Poller{
+		Service:  &uniformDomain{},
+		Interval: time.Hour,
+		Buffer:   4,
+		History:  4,
+	}
+	defer poller.Close()
+	h := server.Handlers{
+		Streams: &server.Streams{
+			Poller:           poller,
+			Heartbeat:        time.Minute,
+			MaxSubscriptions: 2,
+			SendBuffer:       8,
+			WriteTimeout:     time.Second,
+		},
+	}
+	router := mux.NewRouter()
+	server.SetupRoutes(&h, router, &config.Config{})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/weather/current/ws", nil)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	exchange := func(msg string, replies int) []wsReply {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+		got := make([]wsReply, replies)
+		for i := range got {
+			if err := conn.ReadJSON(&got[i]); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+		}
+		return got
+	}
+
+	got := exchange(`{"type":"ping","id":"p1"}`, 1)
+	if got[0].Type != "pong" || got[0].ID != "p1" {
+		t.Errorf("expected pong 'p1' got '%v'", got[0])
+	}
+
+	got = exchange(`{"type":"subscribe","id":"s1","latitude":1.5,"longitude":2.5}`, 2)
+	if got[0].Type != "subscribed" || got[0].ID != "s1" {
+		t.Errorf("expected subscribed 's1' got '%v'", got[0])
+	}
+	if got[1].Type != "update" || got[1].EventID == 0 || got[1].Data == nil || got[1].Data.Data.Attributes.Condition != "Snow" {
+		t.Errorf("expected an update got '%v'", got[1])
+	}
+	if got[1].Latitude == nil || *got[1].Latitude != 1.5 {
+		t.Errorf("expected the update for the subscribed latitude got '%v'", got[1].Latitude)
+	}
+
+	tests := []struct {
+		name  string
+		msg   string
+		reply string
+		err   string
+	}{
+		{"duplicate", `{"type":"subscribe","latitude":1.5,"longitude":2.5}`, "error", "already subscribed"},
+		{"missing-coords", `{"type":"subscribe","latitude":1.5}`, "error", "missing"},
+		{"unknown-type", `{"type":"shout"}`, "error", "unknown message type"},
+		{"invalid-json", `{"type":`, "error", "invalid request body"},
+		{"not-subscribed", `{"type":"unsubscribe","latitude":9,"longitude":9}`, "error", "not subscribed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := exchange(test.msg, 1)
+			if got[0].Type != test.reply || !strings.Contains(got[0].Error, test.err) {
+				t.Errorf("expected '%v: %v' got '%v: %v'", test.reply, test.err, got[0].Type, got[0].Error)
+			}
+		})
+	}
+
+	exchange(`{"type":"subscribe","latitude":3,"longitude":4}`, 2)
+	got = exchange(`{"type":"subscribe","latitude":5,"longitude":6}`, 1)
+	if got[0].Type != "error" || !strings.Contains(got[0].Error, "subscription limit") {
+		t.Errorf("expected the subscription limit got '%v'", got[0])
+	}
+	got = exchange(`{"type":"unsubscribe","id":"u1","latitude":3,"longitude":4}`, 1)
+	if got[0].Type != "unsubscribed" || got[0].ID != "u1" {
+		t.Errorf("expected unsubscribed 'u1' got '%v'", got[0])
+	}
+	got = exchange(`{"type":"subscribe","latitude":5,"longitude":6}`, 1)
+	if got[0].Type != "subscribed" {
+		t.Errorf("expected room for another subscription got '%v'", got[0])
+	}
+
+	// shutting down the poller closes the connection
+	poller.Close()
+	for {
+		if err := conn.ReadJSON(&wsReply{}); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+				t.Errorf("expected going away got '%v'", err)
+			}
+			break
+		}
+	}
+}
+
+func TestHandlers_GetCurrentWebSocket_language(t *testing.T) {
+	poller := &domain.Poller{Service: &uniformDomain{}, Interval: time.Hour, Buffer: 4, History: 4}
+	defer poller.Close()
+	h := server.Handlers{
+		Streams: &server.Streams{
+			Poller:           poller,
+			Heartbeat:        time.Minute,
+			MaxSubscriptions: 1,
+			SendBuffer:       8,
+			WriteTimeout:     time.Second,
+		},
+	}
+	router := mux.NewRouter()
+	server.SetupRoutes(&h, router, &config.Config{})
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/weather/current/ws?lang=de", nil)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	tests := []struct {
+		name    string
+		msg     string
+		replies int
+		err     string
+	}{
+		{"unknown-type", `{"type":"shout"}`, 1, `unbekannter Nachrichtentyp: "shout"`},
+		{"not-subscribed", `{"type":"unsubscribe","latitude":9,"longitude":9}`, 1, "nicht abonniert"},
+		{"subscribe", `{"type":"subscribe","latitude":1.5,"longitude":2.5}`, 2, ""},
+		{"duplicate", `{"type":"subscribe","latitude":1.5,"longitude":2.5}`, 1, "bereits abonniert"},
+		{"limit", `{"type":"subscribe","latitude":3,"longitude":4}`, 1, "Abonnementlimit erreicht: 1"},
+	}
+	for _, test := range tests {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(test.msg)); err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+		got := wsReply{}
+		for i := 0; i < test.replies; i++ {
+			if err := conn.ReadJSON(&got); err != nil {
+				t.Fatalf("got unexpected error: '%v'", err)
+			}
+		}
+		if test.err != "" && got.Error != test.err {
+			t.Errorf("%v: expected '%v' got '%v'", test.name, test.err, got.Error)
+		}
+	}
+}
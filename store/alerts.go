@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/geo"
+)
+
+// Alerts keeps ingested alerts in memory, feeds are reloaded on restart anyway
+type Alerts struct {
+	mu     sync.RWMutex
+	alerts map[string]domain.Alert
+}
+
+func NewAlerts() *Alerts {
+	return &Alerts{alerts: map[string]domain.Alert{}}
+}
+
+// Put adds alerts, replacing any with the same ID, and drops any that have expired
+func (s *Alerts) Put(ctx context.Context, alerts ...domain.Alert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range alerts {
+		s.alerts[a.ID] = a
+	}
+	now := time.Now()
+	for id, a := range s.alerts {
+		if !a.Active(now) {
+			delete(s.alerts, id)
+		}
+	}
+	return nil
+}
+
+func (s *Alerts) Remove(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.alerts, id)
+		for stored := range s.alerts {
+			if strings.HasPrefix(stored, id+"#") {
+				delete(s.alerts, stored)
+			}
+		}
+	}
+	return nil
+}
+
+// GetAlertsByCoords returns the active alerts with an area holding the coordinates, ordered by ID.
+// Alerts without any shapes, like those only giving geocodes, never match.
+func (s *Alerts) GetAlertsByCoords(ctx context.Context, latitude float32, longitude float32) ([]domain.Alert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p := geo.Point{Lon: float64(longitude), Lat: float64(latitude)}
+	now := time.Now()
+	found := []domain.Alert{}
+	for _, a := range s.alerts {
+		if !a.Active(now) {
+			continue
+		}
+		for _, area := range a.Areas {
+			if area.Bounds().Contains(p) && area.Contains(p) {
+				found = append(found, a)
+				break
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID < found[j].ID })
+	return found, nil
+}
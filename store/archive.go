@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+const archiveSchema = `
+CREATE TABLE IF NOT EXISTS observations (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	latitude REAL NOT NULL,
+	longitude REAL NOT NULL,
+	observed_at INTEGER NOT NULL,
+	provider TEXT NOT NULL,
+	provider_latitude REAL NOT NULL,
+	provider_longitude REAL NOT NULL,
+	place TEXT NOT NULL,
+	country TEXT NOT NULL,
+	states TEXT NOT NULL,
+	temperature REAL NOT NULL,
+	sunrise INTEGER NOT NULL,
+	sunset INTEGER NOT NULL,
+	utc_offset INTEGER,
+	conditions TEXT,
+	phenomena TEXT,
+	fetched_at INTEGER NOT NULL,
+	UNIQUE (latitude, longitude, observed_at, provider)
+);
+CREATE INDEX IF NOT EXISTS observations_place ON observations (latitude, longitude, observed_at);
+`
+
+// Archive keeps observations in a SQLite database, which is created if it doesn't exist
+type Archive struct {
+	db *sql.DB
+}
+
+// OpenArchive opens the archive at path, creating the table it needs
+func OpenArchive(ctx context.Context, path string) (*Archive, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, archiveSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating archive schema: %w", err)
+	}
+	return &Archive{db: db}, nil
+}
+
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// Record keeps an observation.  The provider only updates its observations every few minutes,
+// so the same one fetched again is ignored.
+func (a *Archive) Record(ctx context.Context, latitude float32, longitude float32, rw *domain.RepoWeather) error {
+	states, err := json.Marshal(rw.States)
+	if err != nil {
+		return fmt.Errorf("encoding states: %w", err)
+	}
+	var offset *int64
+	if rw.UTCOffset != nil {
+		seconds := int64(*rw.UTCOffset / time.Second)
+		offset = &seconds
+	}
+	var conditions *string
+	if rw.Conditions != nil {
+		encoded, err := json.Marshal(rw.Conditions)
+		if err != nil {
+			return fmt.Errorf("encoding conditions: %w", err)
+		}
+		c := string(encoded)
+		conditions = &c
+	}
+	// phenomena are kept by code, they're looked up again when read
+	codes := make([]string, len(rw.Phenomena))
+	for i, p := range rw.Phenomena {
+		codes[i] = p.Code
+	}
+	phenomena, err := json.Marshal(codes)
+	if err != nil {
+		return fmt.Errorf("encoding phenomena: %w", err)
+	}
+	_, err = a.db.ExecContext(
+		ctx,
+		`INSERT OR IGNORE INTO observations (latitude, longitude, observed_at, provider, provider_latitude, provider_longitude,
+		place, country, states, temperature, sunrise, sunset, utc_offset, conditions, phenomena, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		latitude, longitude, rw.ObservedAt.UnixNano(), rw.Source.Provider, rw.Coords.Latitude, rw.Coords.Longitude,
+		rw.Place.Name, rw.Place.Country, string(states), rw.Temperature, nanosOrZero(rw.Sunrise), nanosOrZero(rw.Sunset),
+		offset, conditions, string(phenomena), rw.Source.FetchedAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting observation: %w", err)
+	}
+	return nil
+}
+
+// Nearest finds the observation closest to at, asked for within gridSize degrees of the coordinates and within window of at
+func (a *Archive) Nearest(ctx context.Context, latitude float32, longitude float32, gridSize float32, at time.Time, window time.Duration) (*domain.RepoWeather, error) {
+	row := a.db.QueryRowContext(
+		ctx,
+		`SELECT observed_at, provider, provider_latitude, provider_longitude, place, country, states, temperature,
+		sunrise, sunset, utc_offset, conditions, phenomena, fetched_at
+		FROM observations
+		WHERE latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ? AND observed_at BETWEEN ? AND ?
+		ORDER BY ABS(observed_at - ?), seq DESC LIMIT 1`,
+		latitude-gridSize, latitude+gridSize, longitude-gridSize, longitude+gridSize,
+		at.Add(-window).UnixNano(), at.Add(window).UnixNano(), at.UnixNano(),
+	)
+	rw := &domain.RepoWeather{}
+	var observed, sunrise, sunset, fetched int64
+	var offset sql.NullInt64
+	var states string
+	var conditions, phenomena sql.NullString
+	err := row.Scan(
+		&observed, &rw.Source.Provider, &rw.Coords.Latitude, &rw.Coords.Longitude, &rw.Place.Name, &rw.Place.Country,
+		&states, &rw.Temperature, &sunrise, &sunset, &offset, &conditions, &phenomena, &fetched,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNoHistory
+	} else if err != nil {
+		return nil, fmt.Errorf("selecting observation: %w", err)
+	}
+	if err := json.Unmarshal([]byte(states), &rw.States); err != nil {
+		return nil, fmt.Errorf("decoding states: %w", err)
+	}
+	if conditions.Valid {
+		rw.Conditions = &domain.Conditions{}
+		if err := json.Unmarshal([]byte(conditions.String), rw.Conditions); err != nil {
+			return nil, fmt.Errorf("decoding conditions: %w", err)
+		}
+	}
+	if phenomena.Valid {
+		var codes []string
+		if err := json.Unmarshal([]byte(phenomena.String), &codes); err != nil {
+			return nil, fmt.Errorf("decoding phenomena: %w", err)
+		}
+		// codes that have since been dropped from the taxonomy are left out
+		for _, code := range codes {
+			if p, ok := domain.LookupPhenomenon(code); ok {
+				rw.Phenomena = append(rw.Phenomena, p)
+			}
+		}
+	}
+	rw.ObservedAt = time.Unix(0, observed).UTC()
+	rw.Sunrise = timeOrZero(sunrise)
+	rw.Sunset = timeOrZero(sunset)
+	rw.Source.FetchedAt = time.Unix(0, fetched).UTC()
+	if offset.Valid {
+		d := time.Duration(offset.Int64) * time.Second
+		rw.UTCOffset = &d
+	}
+	return rw, nil
+}
+
+// nanosOrZero keeps the zero time, which is unknown, as zero
+func nanosOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func timeOrZero(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos).UTC()
+}
@@ -0,0 +1,85 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/store"
+)
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	archive, err := store.OpenArchive(ctx, filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	defer archive.Close()
+	observed := time.Date(2024, 3, 5, 14, 0, 0, 0, time.UTC)
+	offset := -6 * time.Hour
+	visibility := float32(8000)
+	first := domain.RepoWeather{
+		Coords:      domain.Coords{Latitude: 35.47, Longitude: -97.52},
+		Place:       domain.Place{Name: "Oklahoma City", Country: "US"},
+		States:      []string{"Rain", "Mist"},
+		Phenomena:   []domain.Phenomenon{lookupPhenomenon(t, "light_rain"), lookupPhenomenon(t, "mist")},
+		Temperature: 51.5,
+		ObservedAt:  observed,
+		Sunrise:     time.Date(2024, 3, 5, 12, 50, 0, 0, time.UTC),
+		Sunset:      time.Date(2024, 3, 6, 0, 25, 0, 0, time.UTC),
+		UTCOffset:   &offset,
+		Conditions:  &domain.Conditions{Humidity: 93, WindSpeed: 14.2, WindDegrees: 170, Visibility: &visibility, Precipitation: 2.5},
+		Source:      domain.Source{Provider: "openweather", FetchedAt: observed.Add(time.Minute)},
+	}
+	second := first
+	second.States = []string{"Clear"}
+	second.Phenomena = nil
+	second.ObservedAt = observed.Add(time.Hour)
+	second.Sunrise = time.Time{}
+	second.UTCOffset = nil
+	second.Conditions = nil
+	for _, rw := range []domain.RepoWeather{first, second, first} {
+		if err := archive.Record(ctx, 35.4676, -97.5164, &rw); err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		lat      float32
+		lon      float32
+		at       time.Time
+		expected *domain.RepoWeather
+		err      error
+	}{
+		{"exact", 35.4676, -97.5164, observed, &first, nil},
+		{"nearest", 35.4676, -97.5164, observed.Add(40 * time.Minute), &second, nil},
+		{"nearby", 35.47, -97.51, observed.Add(10 * time.Minute), &first, nil},
+		{"too-far", 35.6, -97.5164, observed, nil, domain.ErrNoHistory},
+		{"too-long-ago", 35.4676, -97.5164, observed.Add(-2 * time.Hour), nil, domain.ErrNoHistory},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := archive.Nearest(ctx, test.lat, test.lon, 0.01, test.at, time.Hour)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected '%v' got '%v'", test.err, err)
+			}
+			if test.expected != nil && !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
+
+func lookupPhenomenon(t *testing.T, code string) domain.Phenomenon {
+	t.Helper()
+	p, ok := domain.LookupPhenomenon(code)
+	if !ok {
+		t.Fatalf("expected phenomenon '%v'", code)
+	}
+	return p
+}
@@ -0,0 +1,102 @@
+// Package store persists webhook subscriptions, in memory for development and tests, or in SQLite,
+// and keeps alerts ingested from CAP feeds in memory.
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+// Memory keeps webhooks in memory, they're lost on restart
+type Memory struct {
+	mu         sync.RWMutex
+	webhooks   map[string]domain.Webhook
+	deliveries map[string][]domain.WebhookDelivery
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		webhooks:   map[string]domain.Webhook{},
+		deliveries: map[string][]domain.WebhookDelivery{},
+	}
+}
+
+func (m *Memory) Create(ctx context.Context, w *domain.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.webhooks[w.ID] = *w
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, id string) (*domain.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	w, ok := m.webhooks[id]
+	if !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return &w, nil
+}
+
+// List returns every webhook, oldest first
+func (m *Memory) List(ctx context.Context) ([]domain.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	all := make([]domain.Webhook, 0, len(m.webhooks))
+	for _, w := range m.webhooks {
+		all = append(all, w)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].ID < all[j].ID
+		}
+		return all[i].CreatedAt.Before(all[j].CreatedAt)
+	})
+	return all, nil
+}
+
+// Delete removes a webhook along with its delivery history
+func (m *Memory) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[id]; !ok {
+		return domain.ErrWebhookNotFound
+	}
+	delete(m.webhooks, id)
+	delete(m.deliveries, id)
+	return nil
+}
+
+func (m *Memory) SetMatching(ctx context.Context, id string, matching bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, ok := m.webhooks[id]
+	if !ok {
+		return domain.ErrWebhookNotFound
+	}
+	w.Matching = matching
+	m.webhooks[id] = w
+	return nil
+}
+
+func (m *Memory) AddDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[d.WebhookID]; !ok {
+		return domain.ErrWebhookNotFound
+	}
+	m.deliveries[d.WebhookID] = append(m.deliveries[d.WebhookID], *d)
+	return nil
+}
+
+func (m *Memory) Deliveries(ctx context.Context, webhookID string) ([]domain.WebhookDelivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.webhooks[webhookID]; !ok {
+		return nil, domain.ErrWebhookNotFound
+	}
+	return append([]domain.WebhookDelivery{}, m.deliveries[webhookID]...), nil
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	// registers the pure Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS webhooks (
+	id TEXT PRIMARY KEY,
+	owner TEXT NOT NULL,
+	latitude REAL NOT NULL,
+	longitude REAL NOT NULL,
+	temperature TEXT NOT NULL,
+	condition_contains TEXT NOT NULL,
+	callback_url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	matching INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+	event_id TEXT NOT NULL,
+	attempt INTEGER NOT NULL,
+	outcome TEXT NOT NULL,
+	status_code INTEGER NOT NULL,
+	error TEXT NOT NULL,
+	at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook ON webhook_deliveries (webhook_id, seq);
+`
+
+// SQLite keeps webhooks in a SQLite database, which is created if it doesn't exist
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens the database at path, creating the tables it needs
+func OpenSQLite(ctx context.Context, path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite: %w", err)
+	}
+	// writes are serialized by SQLite anyway, and one connection keeps in memory databases consistent
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating sqlite schema: %w", err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLite) Create(ctx context.Context, w *domain.Webhook) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO webhooks (id, owner, latitude, longitude, temperature, condition_contains, callback_url, secret, created_at, matching)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		w.ID, w.Owner, w.Coords.Latitude, w.Coords.Longitude, string(w.Predicate.Temperature), w.Predicate.ConditionContains,
+		w.CallbackURL, w.Secret, w.CreatedAt.UnixNano(), w.Matching,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting webhook: %w", err)
+	}
+	return nil
+}
+
+const selectWebhooks = `SELECT id, owner, latitude, longitude, temperature, condition_contains, callback_url, secret, created_at, matching FROM webhooks`
+
+func (s *SQLite) Get(ctx context.Context, id string) (*domain.Webhook, error) {
+	w, err := scanWebhook(s.db.QueryRowContext(ctx, selectWebhooks+` WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrWebhookNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("selecting webhook: %w", err)
+	}
+	return w, nil
+}
+
+// List returns every webhook, oldest first
+func (s *SQLite) List(ctx context.Context) ([]domain.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, selectWebhooks+` ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("selecting webhooks: %w", err)
+	}
+	defer rows.Close()
+	all := []domain.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning webhook: %w", err)
+		}
+		all = append(all, *w)
+	}
+	return all, rows.Err()
+}
+
+// Delete removes a webhook along with its delivery history
+func (s *SQLite) Delete(ctx context.Context, id string) error {
+	return s.exec(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+}
+
+func (s *SQLite) SetMatching(ctx context.Context, id string, matching bool) error {
+	return s.exec(ctx, `UPDATE webhooks SET matching = ? WHERE id = ?`, matching, id)
+}
+
+func (s *SQLite) AddDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	// only inserted if the webhook exists
+	return s.exec(
+		ctx,
+		`INSERT INTO webhook_deliveries (id, webhook_id, event_id, attempt, outcome, status_code, error, at)
+		SELECT ?, id, ?, ?, ?, ?, ?, ? FROM webhooks WHERE id = ?`,
+		d.ID, d.EventID, d.Attempt, d.Outcome, d.StatusCode, d.Error, d.At.UnixNano(), d.WebhookID,
+	)
+}
+
+func (s *SQLite) Deliveries(ctx context.Context, webhookID string) ([]domain.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, webhook_id, event_id, attempt, outcome, status_code, error, at
+		FROM webhook_deliveries WHERE webhook_id = ? ORDER BY seq`,
+		webhookID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("selecting webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	all := []domain.WebhookDelivery{}
+	for rows.Next() {
+		d := domain.WebhookDelivery{}
+		var at int64
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Attempt, &d.Outcome, &d.StatusCode, &d.Error, &at); err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		d.At = time.Unix(0, at).UTC()
+		all = append(all, d)
+	}
+	return all, rows.Err()
+}
+
+// exec runs a statement that affects a single webhook, which has to exist
+func (s *SQLite) exec(ctx context.Context, query string, args ...any) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("executing webhook statement: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("executing webhook statement: %w", err)
+	}
+	if n == 0 {
+		return domain.ErrWebhookNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row scanner) (*domain.Webhook, error) {
+	w := &domain.Webhook{}
+	var temperature string
+	var created int64
+	err := row.Scan(
+		&w.ID, &w.Owner, &w.Coords.Latitude, &w.Coords.Longitude, &temperature, &w.Predicate.ConditionContains,
+		&w.CallbackURL, &w.Secret, &created, &w.Matching,
+	)
+	if err != nil {
+		return nil, err
+	}
+	w.Predicate.Temperature = domain.Temperature(temperature)
+	w.CreatedAt = time.Unix(0, created).UTC()
+	return w, nil
+}
@@ -0,0 +1,110 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/store"
+)
+
+func TestStores(t *testing.T) {
+	sqlite, err := store.OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "weather.db"))
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	defer sqlite.Close()
+	stores := []struct {
+		name  string
+		store domain.WebhookStore
+	}{
+		{"memory", store.NewMemory()},
+		{"sqlite", sqlite},
+	}
+	for _, s := range stores {
+		t.Run(s.name, func(t *testing.T) {
+			testStore(t, s.store)
+		})
+	}
+}
+
+func testStore(t *testing.T, s domain.WebhookStore) {
+	ctx := context.Background()
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := domain.Webhook{
+		ID:          "a",
+		Owner:       "anonymous",
+		Coords:      domain.Coords{Latitude: 1.5, Longitude: -2.5},
+		Predicate:   domain.Predicate{Temperature: domain.TempHot, ConditionContains: "snow"},
+		CallbackURL: "https://example.test/hook",
+		Secret:      "shh",
+		CreatedAt:   created,
+	}
+	second := first
+	second.ID = "b"
+	second.CreatedAt = created.Add(time.Minute)
+	for _, w := range []domain.Webhook{second, first} {
+		if err := s.Create(ctx, &w); err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+	}
+
+	got, err := s.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if !reflect.DeepEqual(*got, first) {
+		t.Errorf("expected '%v' got '%v'", first, *got)
+	}
+	if _, err := s.Get(ctx, "missing"); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrWebhookNotFound, err)
+	}
+
+	if err := s.SetMatching(ctx, "b", true); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	all, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if len(all) != 2 || all[0].ID != "a" || all[1].ID != "b" || !all[1].Matching {
+		t.Errorf("expected both webhooks oldest first, with b matching got '%v'", all)
+	}
+	if err := s.SetMatching(ctx, "missing", true); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrWebhookNotFound, err)
+	}
+
+	deliveries := []domain.WebhookDelivery{
+		{ID: "d1", WebhookID: "a", EventID: "e1", Attempt: 1, Outcome: domain.DeliveryFailed, StatusCode: 500, Error: "boom", At: created},
+		{ID: "d2", WebhookID: "a", EventID: "e1", Attempt: 2, Outcome: domain.DeliverySucceeded, StatusCode: 204, At: created.Add(time.Second)},
+	}
+	for _, d := range deliveries {
+		if err := s.AddDelivery(ctx, &d); err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+	}
+	if err := s.AddDelivery(ctx, &domain.WebhookDelivery{ID: "d3", WebhookID: "missing"}); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrWebhookNotFound, err)
+	}
+	history, err := s.Deliveries(ctx, "a")
+	if err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if !reflect.DeepEqual(history, deliveries) {
+		t.Errorf("expected '%v' got '%v'", deliveries, history)
+	}
+
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatalf("got unexpected error: '%v'", err)
+	}
+	if _, err := s.Deliveries(ctx, "a"); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrWebhookNotFound, err)
+	}
+	if err := s.Delete(ctx, "a"); !errors.Is(err, domain.ErrWebhookNotFound) {
+		t.Errorf("expected '%v' got '%v'", domain.ErrWebhookNotFound, err)
+	}
+}
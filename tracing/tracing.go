@@ -0,0 +1,55 @@
+// Package tracing configures OpenTelemetry tracing for the service.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/broganross/weather-exercise/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Setup installs the W3C trace context propagator, and if tracing is enabled,
+// a global tracer provider exporting over OTLP/HTTP.
+// The returned function flushes any buffered spans and must be called before exiting.
+func Setup(ctx context.Context, c config.Tracing, version string) (func(context.Context) error, error) {
+	// Propagate even when disabled, so incoming trace context is still passed upstream
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if !c.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(c.Endpoint),
+	}
+	if c.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating otlp exporter: %w", err)
+	}
+	tp := NewProvider(sdktrace.NewBatchSpanProcessor(exporter), c, version)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// NewProvider creates a tracer provider sending spans to the processor, sampling root spans at the configured ratio
+func NewProvider(processor sdktrace.SpanProcessor, c config.Tracing, version string) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(
+		semconv.ServiceName(c.ServiceName),
+		semconv.ServiceVersion(version),
+	)
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+}
@@ -0,0 +1,74 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/config"
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/repo"
+	"github.com/broganross/weather-exercise/server"
+	"github.com/broganross/weather-exercise/tracing"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	c := config.Tracing{SampleRatio: 1, ServiceName: "test"}
+	if _, err := tracing.Setup(context.Background(), c, "test"); err != nil {
+		t.Fatalf("setting up tracing: %v", err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewProvider(sdktrace.NewSimpleSpanProcessor(exporter), c, "test")
+	otel.SetTracerProvider(tp)
+	defer tp.Shutdown(context.Background())
+
+	var upstreamParent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamParent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"main":{"temp":50}}`))
+	}))
+	defer upstream.Close()
+	h := server.Handlers{
+		Domain: &domain.WeatherService{
+			Source: &repo.OpenWeather{
+				BaseURL: upstream.URL,
+				Client:  http.DefaultClient,
+				Timeout: 5 * time.Second,
+			},
+		},
+	}
+	router := mux.NewRouter()
+	server.SetupRoutes(&h, router, &config.Config{})
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/?latitude=1&longitude=2", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected code '%v' got '%v'", http.StatusOK, w.Code)
+	}
+
+	spans := exporter.GetSpans()
+	names := map[string]bool{}
+	for _, s := range spans {
+		names[s.Name] = true
+		if got := s.SpanContext.TraceID().String(); got != traceID {
+			t.Errorf("expected span '%v' in trace '%v' got '%v'", s.Name, traceID, got)
+		}
+	}
+	for _, want := range []string{"GET /", "domain.CurrentIn", "openweather.GetByCoords"} {
+		if !names[want] {
+			t.Errorf("expected span '%v' in %v", want, names)
+		}
+	}
+	if len(upstreamParent) < 35 || upstreamParent[3:35] != traceID {
+		t.Errorf("expected upstream traceparent in trace '%v' got '%v'", traceID, upstreamParent)
+	}
+}
@@ -0,0 +1,268 @@
+// Package tz finds the IANA time zone at a place, offline.  A place is in the zone whose boundary holds it, from the
+// embedded boundaries.json, as long as the zone's offset agrees with the one the provider reported.  Outside the
+// boundaries, out at sea or where they don't reach, it's the zone of the nearest principal location from tzdata's
+// zone.tab whose offset agrees.  The zone rules are embedded with time/tzdata, so the host doesn't need them.
+//
+// boundaries.json is a hand made sample rather than a full dataset: coarse outlines of the contiguous US zones and
+// metropolitan France, in the GeoJSON shape of timezone-boundary-builder's releases, a feature per zone with its name
+// in the tzid property.  Everywhere else falls back to zone.tab.
+package tz
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/broganross/weather-exercise/geo"
+)
+
+var (
+	ErrInvalidTable      = errors.New("invalid zone table")
+	ErrInvalidBoundaries = errors.New("invalid zone boundaries")
+)
+
+// MaxDistance is how far, in kilometres, a place can be from a zone's principal location and still be in it.
+// Past that, out at sea, the zone comes from the offset alone.
+const MaxDistance = 2000
+
+//go:embed zone.tab
+var zoneTab []byte
+
+//go:embed boundaries.json
+var boundariesJSON []byte
+
+// zone is a row of zone.tab
+type zone struct {
+	name string
+	at   geo.Point
+}
+
+var (
+	zones     []zone
+	loadZones = sync.OnceValue(func() error {
+		var err error
+		zones, err = parseTable(zoneTab)
+		return err
+	})
+	locations sync.Map
+)
+
+// boundary is the area a zone covers
+type boundary struct {
+	name   string
+	area   geo.MultiPolygon
+	bounds geo.BBox
+}
+
+var loadBoundaries = sync.OnceValues(func() ([]boundary, error) {
+	return parseBoundaries(boundariesJSON)
+})
+
+// Lookup finds the time zone at a place.  offset is the UTC offset the provider reported at t, nil if it didn't,
+// and the zone has to agree with it.  With no zone that does, it's a fixed zone at the offset, or without an offset
+// the nautical zone for the longitude.
+func Lookup(lat float64, lon float64, t time.Time, offset *time.Duration) *time.Location {
+	p := geo.Point{Lat: lat, Lon: lon}
+	// the data is embedded, so it only fails to load from a broken build, which the tests catch
+	if boundaries, err := loadBoundaries(); err == nil {
+		for _, b := range boundaries {
+			if !b.bounds.Contains(p) || !b.area.Contains(p) {
+				continue
+			}
+			loc, err := location(b.name)
+			if err != nil {
+				continue
+			}
+			if offset == nil || offsetAt(loc, t) == *offset {
+				return loc
+			}
+		}
+	}
+	if err := loadZones(); err == nil {
+		for _, z := range nearest(p) {
+			loc, err := location(z.name)
+			if err != nil {
+				continue
+			}
+			if offset == nil || offsetAt(loc, t) == *offset {
+				return loc
+			}
+		}
+	}
+	if offset != nil {
+		return Fixed(*offset)
+	}
+	return Fixed(time.Duration(math.Round(lon/15)) * time.Hour)
+}
+
+// Fixed is a zone at a constant offset.  Whole hours use the Etc/GMT zones, so they have an IANA name.
+func Fixed(offset time.Duration) *time.Location {
+	if offset%time.Hour == 0 && offset.Abs() <= 12*time.Hour {
+		hours := int(offset / time.Hour)
+		name := "Etc/GMT"
+		// the Etc zones' signs are the POSIX way round, west of Greenwich is positive
+		switch {
+		case hours > 0:
+			name += "-" + strconv.Itoa(hours)
+		case hours < 0:
+			name += "+" + strconv.Itoa(-hours)
+		}
+		if loc, err := location(name); err == nil {
+			return loc
+		}
+	}
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+	}
+	minutes := int(offset.Abs() / time.Minute)
+	return time.FixedZone(fmt.Sprintf("UTC%s%02d:%02d", sign, minutes/60, minutes%60), int(offset/time.Second))
+}
+
+// nearest is the zones within MaxDistance of p, closest first
+func nearest(p geo.Point) []zone {
+	type candidate struct {
+		zone
+		distance float64
+	}
+	candidates := []candidate{}
+	for _, z := range zones {
+		if d := geo.Distance(p, z.at); d <= MaxDistance {
+			candidates = append(candidates, candidate{zone: z, distance: d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	near := make([]zone, len(candidates))
+	for i, c := range candidates {
+		near[i] = c.zone
+	}
+	return near
+}
+
+// location loads a zone once
+func location(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func offsetAt(loc *time.Location, t time.Time) time.Duration {
+	_, offset := t.In(loc).Zone()
+	return time.Duration(offset) * time.Second
+}
+
+// parseBoundaries reads a GeoJSON feature collection of zone areas, named by their tzid property
+func parseBoundaries(b []byte) ([]boundary, error) {
+	fc := struct {
+		Features []struct {
+			geo.Object
+			Properties struct {
+				TZID string `json:"tzid"`
+			} `json:"properties"`
+		} `json:"features"`
+	}{}
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBoundaries, err)
+	}
+	boundaries := make([]boundary, 0, len(fc.Features))
+	for i, f := range fc.Features {
+		if f.Properties.TZID == "" {
+			return nil, fmt.Errorf("%w: feature %d: missing tzid", ErrInvalidBoundaries, i)
+		}
+		area, err := f.Object.Area()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidBoundaries, f.Properties.TZID, err)
+		}
+		boundaries = append(boundaries, boundary{name: f.Properties.TZID, area: area, bounds: area.Bounds()})
+	}
+	return boundaries, nil
+}
+
+// parseTable reads zone.tab, tab separated country code, ISO 6709 coordinates and zone name
+func parseTable(b []byte) ([]zone, error) {
+	table := []zone{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%w: line %d: expected country, coordinates and zone", ErrInvalidTable, line)
+		}
+		at, err := parseISO6709(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidTable, line, err)
+		}
+		table = append(table, zone{name: fields[2], at: at})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// parseISO6709 reads coordinates like +DDMM+DDDMM or +DDMMSS+DDDMMSS
+func parseISO6709(s string) (geo.Point, error) {
+	if len(s) < 2 {
+		return geo.Point{}, fmt.Errorf("coordinates %q: too short", s)
+	}
+	split := strings.IndexAny(s[1:], "+-") + 1
+	if split == 0 {
+		return geo.Point{}, fmt.Errorf("coordinates %q: expected a signed longitude", s)
+	}
+	lat, err := parseDMS(s[:split], 2)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("coordinates %q: %w", s, err)
+	}
+	lon, err := parseDMS(s[split:], 3)
+	if err != nil {
+		return geo.Point{}, fmt.Errorf("coordinates %q: %w", s, err)
+	}
+	return geo.Point{Lat: lat, Lon: lon}, nil
+}
+
+// parseDMS reads a signed angle of degreeDigits digits of degrees, then minutes and maybe seconds
+func parseDMS(s string, degreeDigits int) (float64, error) {
+	digits := s[1:]
+	if len(digits) != degreeDigits+2 && len(digits) != degreeDigits+4 {
+		return 0, fmt.Errorf("angle %q: unexpected length", s)
+	}
+	parts := []string{digits[:degreeDigits], digits[degreeDigits : degreeDigits+2]}
+	if len(digits) == degreeDigits+4 {
+		parts = append(parts, digits[degreeDigits+2:])
+	}
+	angle := 0.0
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("angle %q: %w", s, err)
+		}
+		angle += float64(v) / math.Pow(60, float64(i))
+	}
+	if s[0] == '-' {
+		angle = -angle
+	}
+	return angle, nil
+}
@@ -0,0 +1,88 @@
+package tz_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/tz"
+)
+
+func offset(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestLookup(t *testing.T) {
+	summer := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	winter := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		lat      float64
+		lon      float64
+		at       time.Time
+		offset   *time.Duration
+		expected string
+	}{
+		{"london", 51.5074, -0.1278, summer, offset(time.Hour), "Europe/London"},
+		{"london-no-offset", 51.5074, -0.1278, winter, nil, "Europe/London"},
+		{"new-york", 40.7128, -74.006, winter, offset(-5 * time.Hour), "America/New_York"},
+		{"tokyo", 35.6762, 139.6503, summer, offset(9 * time.Hour), "Asia/Tokyo"},
+		{"kathmandu", 27.7172, 85.324, summer, offset(5*time.Hour + 45*time.Minute), "Asia/Kathmandu"},
+		// Phoenix is nearer Hermosillo, but Arizona keeps standard time like it does
+		{"phoenix", 33.4484, -112.074, summer, offset(-7 * time.Hour), "America/Phoenix"},
+		// Elbląg is nearer Kaliningrad, which stays an hour ahead of Poland in winter
+		{"elblag", 54.1561, 19.4045, winter, offset(time.Hour), "Europe/Warsaw"},
+		// neighbours across a border that keep the same time still have their own zones
+		{"san-diego", 32.7157, -117.1611, summer, offset(-7 * time.Hour), "America/Los_Angeles"},
+		{"tijuana", 32.5149, -117.0382, summer, offset(-7 * time.Hour), "America/Tijuana"},
+		{"seattle", 47.6062, -122.3321, winter, offset(-8 * time.Hour), "America/Los_Angeles"},
+		{"vancouver", 49.2827, -123.1207, winter, offset(-8 * time.Hour), "America/Vancouver"},
+		{"lubbock", 33.5779, -101.8552, summer, offset(-5 * time.Hour), "America/Chicago"},
+		{"lubbock-no-offset", 33.5779, -101.8552, winter, nil, "America/Chicago"},
+		{"el-paso", 31.7619, -106.485, summer, offset(-6 * time.Hour), "America/Denver"},
+		{"ciudad-juarez", 31.72, -106.46, summer, offset(-6 * time.Hour), "America/Ciudad_Juarez"},
+		{"strasbourg", 48.5734, 7.7521, summer, offset(2 * time.Hour), "Europe/Paris"},
+		// zones within a country
+		{"boise", 43.615, -116.2023, winter, offset(-7 * time.Hour), "America/Boise"},
+		{"indianapolis", 39.7684, -86.1581, winter, offset(-5 * time.Hour), "America/Indiana/Indianapolis"},
+		{"louisville", 38.2527, -85.7585, winter, offset(-5 * time.Hour), "America/Kentucky/Louisville"},
+		{"detroit", 42.3314, -83.0458, winter, offset(-5 * time.Hour), "America/Detroit"},
+		{"provider-offset", 51.5074, -0.1278, summer, offset(5*time.Hour + 30*time.Minute), "UTC+05:30"},
+		{"provider-whole-hour", 51.5074, -0.1278, summer, offset(-3 * time.Hour), "Etc/GMT+3"},
+		{"south-pacific", -45, -120, summer, nil, "Etc/GMT+8"},
+		{"south-pacific-offset", -45, -120, summer, offset(-7 * time.Hour), "Etc/GMT+7"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := tz.Lookup(test.lat, test.lon, test.at, test.offset)
+			if got.String() != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+			if test.offset != nil {
+				if _, off := test.at.In(got).Zone(); time.Duration(off)*time.Second != *test.offset {
+					t.Errorf("expected '%v' got '%v'", *test.offset, time.Duration(off)*time.Second)
+				}
+			}
+		})
+	}
+}
+
+func TestFixed(t *testing.T) {
+	tests := []struct {
+		offset   time.Duration
+		expected string
+	}{
+		{0, "Etc/GMT"},
+		{2 * time.Hour, "Etc/GMT-2"},
+		{-11 * time.Hour, "Etc/GMT+11"},
+		{-(3*time.Hour + 30*time.Minute), "UTC-03:30"},
+		{14 * time.Hour, "UTC+14:00"},
+	}
+	for _, test := range tests {
+		t.Run(test.expected, func(t *testing.T) {
+			got := tz.Fixed(test.offset)
+			if got.String() != test.expected {
+				t.Errorf("expected '%v' got '%v'", test.expected, got)
+			}
+		})
+	}
+}
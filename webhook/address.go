@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("forbidden callback address")
+
+// sharedAddressSpace is carrier grade NAT (RFC 6598), which is as internal as the private ranges
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Forbidden reports whether callbacks can't be sent to an address: loopback, private, link local (including
+// cloud metadata services at 169.254.169.254), multicast and unspecified addresses are all internal.
+func Forbidden(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr) ||
+		(addr.Is4() && addr.As4()[0] == 0)
+}
+
+// CheckCallback rejects callback URLs whose host is a forbidden address, or localhost.  Other names can
+// still resolve to one, so clients from NewClient check every address they connect to as well.
+func CheckCallback(u *url.URL) error {
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, u.Hostname())
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && Forbidden(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, u.Hostname())
+	}
+	return nil
+}
+
+// NewClient is a client for delivering webhooks that refuses to connect to forbidden addresses.  It's checked
+// when dialing, after names are resolved, so DNS rebinding and redirects can't get around it.  Proxies from the
+// environment aren't used, as the check would apply to the proxy rather than the callback.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+			}
+			if Forbidden(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,89 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/webhook"
+)
+
+func TestForbidden(t *testing.T) {
+	tests := []struct {
+		addr      string
+		forbidden bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::ffff:127.0.0.1", true},
+	}
+	for _, test := range tests {
+		if got := webhook.Forbidden(netip.MustParseAddr(test.addr)); got != test.forbidden {
+			t.Errorf("%v: expected '%v' got '%v'", test.addr, test.forbidden, got)
+		}
+	}
+}
+
+func TestCheckCallback(t *testing.T) {
+	tests := []struct {
+		callback  string
+		forbidden bool
+	}{
+		{"https://example.com/hook", false},
+		{"https://93.184.216.34/hook", false},
+		{"http://localhost:8080/hook", true},
+		{"http://api.localhost/hook", true},
+		{"http://127.0.0.1/hook", true},
+		{"http://[::1]:9000/hook", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://10.0.0.8/hook", true},
+		{"http://192.168.0.10/hook", true},
+	}
+	for _, test := range tests {
+		u, err := url.Parse(test.callback)
+		if err != nil {
+			t.Fatalf("got unexpected error: '%v'", err)
+		}
+		err = webhook.CheckCallback(u)
+		if got := errors.Is(err, webhook.ErrForbiddenAddress); got != test.forbidden {
+			t.Errorf("%v: expected '%v' got '%v'", test.callback, test.forbidden, err)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+	s := &webhook.Sender{Client: webhook.NewClient(time.Second)}
+	_, err := s.Send(context.Background(), &domain.WebhookEvent{
+		ID:      "evt-1",
+		Webhook: &domain.Webhook{ID: "sub-1", CallbackURL: srv.URL, Secret: "shh"},
+		Weather: &domain.Weather{},
+	})
+	if !errors.Is(err, webhook.ErrForbiddenAddress) {
+		t.Errorf("expected '%v' got '%v'", webhook.ErrForbiddenAddress, err)
+	}
+	if called {
+		t.Errorf("expected the callback not to be called")
+	}
+}
@@ -0,0 +1,134 @@
+// Package webhook delivers signed webhook payloads.
+//
+// Each payload is signed with the subscription's secret, as the hex HMAC-SHA256 of the timestamp header,
+// a '.', and the body.  Receivers should recompute it, compare in constant time, and reject old timestamps.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Weather-Event"
+	HeaderTimestamp = "X-Weather-Timestamp"
+	HeaderSignature = "X-Weather-Signature"
+)
+
+// EventType is the type of payload sent when a subscription's predicate starts matching
+const EventType = "weather.condition_matched"
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Payload is the body of a delivery
+type Payload struct {
+	ID           string       `json:"id"`
+	Type         string       `json:"type"`
+	OccurredAt   time.Time    `json:"occurred_at"`
+	Subscription Subscription `json:"subscription"`
+	Weather      Weather      `json:"weather"`
+}
+
+type Subscription struct {
+	ID        string  `json:"id"`
+	Latitude  float32 `json:"latitude"`
+	Longitude float32 `json:"longitude"`
+}
+
+type Weather struct {
+	Temperature string    `json:"temperature"`
+	Condition   string    `json:"condition"`
+	Degrees     float32   `json:"degrees"`
+	ObservedAt  time.Time `json:"observed_at"`
+}
+
+// Sender posts signed payloads to webhook callbacks
+type Sender struct {
+	Client *http.Client
+}
+
+// Send delivers the event, any response outside 2xx is an error
+func (s *Sender) Send(ctx context.Context, e *domain.WebhookEvent) (int, error) {
+	body, err := json.Marshal(NewPayload(e))
+	if err != nil {
+		return 0, fmt.Errorf("encoding webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Webhook.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("creating webhook request: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, e.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(e.Webhook.Secret, timestamp, body))
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("delivering webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("delivering webhook: unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// NewPayload builds the body delivered for an event
+func NewPayload(e *domain.WebhookEvent) *Payload {
+	return &Payload{
+		ID:         e.ID,
+		Type:       EventType,
+		OccurredAt: e.OccurredAt,
+		Subscription: Subscription{
+			ID:        e.Webhook.ID,
+			Latitude:  e.Webhook.Coords.Latitude,
+			Longitude: e.Webhook.Coords.Longitude,
+		},
+		Weather: Weather{
+			Temperature: string(e.Weather.Temperature),
+			Condition:   strings.Join(e.Weather.States, ", "),
+			Degrees:     e.Weather.Degrees,
+			ObservedAt:  e.Weather.ObservedAt,
+		},
+	}
+}
+
+// Sign returns the signature header value for a body
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature, and that its timestamp is within tolerance of now
+func Verify(secret string, timestamp string, body []byte, signature string, tolerance time.Duration) error {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: timestamp %q", ErrInvalidSignature, timestamp)
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
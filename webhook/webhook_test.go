@@ -0,0 +1,84 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/broganross/weather-exercise/domain"
+	"github.com/broganross/weather-exercise/webhook"
+)
+
+func TestSender_Send(t *testing.T) {
+	var verifyErr error
+	var payload webhook.Payload
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = webhook.Verify("shh", r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature), time.Minute)
+		json.Unmarshal(body, &payload)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	e := &domain.WebhookEvent{
+		ID: "evt-1",
+		Webhook: &domain.Webhook{
+			ID:          "sub-1",
+			Coords:      domain.Coords{Latitude: 1.5, Longitude: 2.5},
+			CallbackURL: srv.URL,
+			Secret:      "shh",
+		},
+		Weather: &domain.Weather{States: []string{"Snow"}, Temperature: domain.TempCold, Degrees: 20},
+	}
+	s := &webhook.Sender{Client: srv.Client()}
+	code, err := s.Send(context.Background(), e)
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("expected '%v' got '%v' '%v'", http.StatusNoContent, code, err)
+	}
+	if verifyErr != nil {
+		t.Errorf("got unexpected error: '%v'", verifyErr)
+	}
+	if payload.ID != "evt-1" || payload.Subscription.ID != "sub-1" || payload.Weather.Condition != "Snow" {
+		t.Errorf("expected the event payload got '%v'", payload)
+	}
+
+	status = http.StatusInternalServerError
+	if code, err := s.Send(context.Background(), e); err == nil || code != http.StatusInternalServerError {
+		t.Errorf("expected an error for '%v' got '%v' '%v'", status, code, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		signature string
+		err       error
+	}{
+		{"valid", "shh", now, body, webhook.Sign("shh", now, body), nil},
+		{"wrong-secret", "other", now, body, webhook.Sign("shh", now, body), webhook.ErrInvalidSignature},
+		{"tampered", "shh", now, []byte(`{"id":"evt-2"}`), webhook.Sign("shh", now, body), webhook.ErrInvalidSignature},
+		{"replayed", "shh", old, body, webhook.Sign("shh", old, body), webhook.ErrInvalidSignature},
+		{"bad-timestamp", "shh", "yesterday", body, webhook.Sign("shh", "yesterday", body), webhook.ErrInvalidSignature},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := webhook.Verify(test.secret, test.timestamp, test.body, test.signature, 5*time.Minute)
+			if !errors.Is(err, test.err) {
+				t.Errorf("expected '%v' got '%v'", test.err, err)
+			}
+		})
+	}
+}